@@ -0,0 +1,44 @@
+package etcpwdparse
+
+const (
+	// DefaultUidMin is the lowest user id given to regular accounts by useradd on most distributions
+	DefaultUidMin = 1000
+	// DefaultUidMax is the highest user id given to regular accounts by useradd on most distributions
+	DefaultUidMax = 60000
+)
+
+// AccountRanges holds the UID_MIN and UID_MAX policy used to classify accounts. User ids below
+// UidMin are system accounts and user ids between UidMin and UidMax inclusive are regular
+// accounts. Ids above UidMax, such as nobody at 65534, are neither.
+type AccountRanges struct {
+	UidMin int
+	UidMax int
+}
+
+// DefaultAccountRanges returns the ranges used by shadow-utils when login.defs does not
+// override them.
+func DefaultAccountRanges() AccountRanges {
+	return AccountRanges{UidMin: DefaultUidMin, UidMax: DefaultUidMax}
+}
+
+// IsSystemAccount returns true if the entry has a user id below UidMin
+func (r AccountRanges) IsSystemAccount(entry *EtcPasswdEntry) bool {
+	return entry.uid < r.UidMin
+}
+
+// IsRegularAccount returns true if the entry has a user id between UidMin and UidMax
+func (r AccountRanges) IsRegularAccount(entry *EtcPasswdEntry) bool {
+	return entry.uid >= r.UidMin && entry.uid <= r.UidMax
+}
+
+// IsSystemAccount returns true if the entry is a system account according to the default
+// account ranges. Use AccountRanges to classify with a different policy.
+func (e *EtcPasswdEntry) IsSystemAccount() bool {
+	return DefaultAccountRanges().IsSystemAccount(e)
+}
+
+// IsRegularAccount returns true if the entry is a regular account according to the default
+// account ranges. Use AccountRanges to classify with a different policy.
+func (e *EtcPasswdEntry) IsRegularAccount() bool {
+	return DefaultAccountRanges().IsRegularAccount(e)
+}
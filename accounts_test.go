@@ -0,0 +1,30 @@
+package etcpwdparse
+
+import (
+	"testing"
+)
+
+func TestAccountClassification(t *testing.T) {
+	root := &EtcPasswdEntry{username: "root", uid: 0}
+	daemon := &EtcPasswdEntry{username: "daemon", uid: 999}
+	alice := &EtcPasswdEntry{username: "alice", uid: 1000}
+	nobody := &EtcPasswdEntry{username: "nobody", uid: 65534}
+
+	if !root.IsSystemAccount() || root.IsRegularAccount() {
+		t.Fatalf("root should be a system account")
+	}
+	if !daemon.IsSystemAccount() || daemon.IsRegularAccount() {
+		t.Fatalf("daemon should be a system account")
+	}
+	if alice.IsSystemAccount() || !alice.IsRegularAccount() {
+		t.Fatalf("alice should be a regular account")
+	}
+	if nobody.IsSystemAccount() || nobody.IsRegularAccount() {
+		t.Fatalf("nobody should be neither")
+	}
+
+	legacy := AccountRanges{UidMin: 500, UidMax: 60000}
+	if !legacy.IsRegularAccount(daemon) || legacy.IsSystemAccount(daemon) {
+		t.Fatalf("999 should be a regular account with UID_MIN 500")
+	}
+}
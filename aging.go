@@ -0,0 +1,50 @@
+package etcpwdparse
+
+import (
+	"time"
+)
+
+// neverExpireDays is the maximum password age from which shadow-utils treats a password as
+// never expiring, 99999 being the usual value.
+const neverExpireDays = 10000
+
+// epochDay returns the number of whole days between the epoch and the given time, which is the
+// unit used by the shadow day fields.
+func epochDay(now time.Time) int {
+	return int(now.Unix() / int64(24*time.Hour/time.Second))
+}
+
+// MustChangePassword returns true if the last change field is 0, which forces the user to
+// change their password at the next login.
+func (e *EtcShadowEntry) MustChangePassword() bool {
+	return e.lastchange == 0
+}
+
+// IsExpired returns true if the account expiration date has been reached at the given time.
+// Like shadow-utils an expire field of 0 or less is treated as no expiration date.
+func (e *EtcShadowEntry) IsExpired(now time.Time) bool {
+	return e.expire > 0 && epochDay(now) >= e.expire
+}
+
+// IsPasswordExpired returns true if the password must be changed at the given time, either
+// because MustChangePassword is set or because the maximum password age has passed since the
+// last change. A maximum age of 10000 days or more is treated as no maximum.
+func (e *EtcShadowEntry) IsPasswordExpired(now time.Time) bool {
+	if e.MustChangePassword() {
+		return true
+	}
+	if e.lastchange < 0 || e.max < 0 || e.max >= neverExpireDays {
+		return false
+	}
+	return epochDay(now) >= e.lastchange+e.max
+}
+
+// DaysUntilExpiry returns the number of days from the given time until the account expiration
+// date, which is 0 or negative once the account has expired. The second result is false when
+// the account has no expiration date.
+func (e *EtcShadowEntry) DaysUntilExpiry(now time.Time) (int, bool) {
+	if e.expire <= 0 {
+		return 0, false
+	}
+	return e.expire - epochDay(now), true
+}
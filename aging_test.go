@@ -0,0 +1,55 @@
+package etcpwdparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShadowAging(t *testing.T) {
+	// day 19000 is 2022-01-08
+	now := time.Date(2022, 1, 8, 15, 30, 0, 0, time.UTC)
+	if epochDay(now) != 19000 {
+		t.Fatalf("%d != 19000", epochDay(now))
+	}
+
+	entry, _ := ParseShadowLine("alice:$6$x:18990:0:30:7::19005:")
+	if entry.MustChangePassword() {
+		t.Fatalf("alice should not have to change password")
+	}
+	if entry.IsPasswordExpired(now) {
+		t.Fatalf("alice password should not be expired yet")
+	}
+	if !entry.IsPasswordExpired(now.AddDate(0, 0, 20)) {
+		t.Fatalf("alice password should be expired after 30 days")
+	}
+	if entry.IsExpired(now) {
+		t.Fatalf("alice should not be expired yet")
+	}
+	if !entry.IsExpired(now.AddDate(0, 0, 5)) {
+		t.Fatalf("alice should be expired on the expire day")
+	}
+	if days, ok := entry.DaysUntilExpiry(now); !ok || days != 5 {
+		t.Fatalf("%d %v != 5 true", days, ok)
+	}
+	if days, _ := entry.DaysUntilExpiry(now.AddDate(0, 0, 7)); days != -2 {
+		t.Fatalf("%d != -2", days)
+	}
+
+	entry, _ = ParseShadowLine("bob:$6$x:0:0:99999:7:::")
+	if !entry.MustChangePassword() || !entry.IsPasswordExpired(now) {
+		t.Fatalf("bob should have to change password")
+	}
+
+	entry, _ = ParseShadowLine("carol:$6$x:18000:0:99999:7:::")
+	if entry.IsPasswordExpired(now) || entry.IsExpired(now) {
+		t.Fatalf("carol should never expire")
+	}
+	if _, ok := entry.DaysUntilExpiry(now); ok {
+		t.Fatalf("carol should have no expiration date")
+	}
+
+	entry, _ = ParseShadowLine("daemon:*::::::: ")
+	if entry.MustChangePassword() || entry.IsPasswordExpired(now) || entry.IsExpired(now) {
+		t.Fatalf("daemon has no aging and should not expire")
+	}
+}
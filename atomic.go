@@ -0,0 +1,60 @@
+package etcpwdparse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes the content to a temporary file in the same directory as path and
+// renames it over path so that readers never observe a partially written file. If path already
+// exists its permissions and owner are kept, otherwise defaultPerm is used.
+func writeFileAtomic(path string, content []byte, defaultPerm os.FileMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		info = nil
+	}
+	return writeFileAtomicLike(path, content, defaultPerm, info)
+}
+
+// writeFileAtomicLike is like writeFileAtomic but takes the permissions and owner from the given
+// file info, such as that of the original when writing a backup. A nil info uses defaultPerm and
+// leaves the new file owned by the current user.
+func writeFileAtomicLike(path string, content []byte, defaultPerm os.FileMode, like os.FileInfo) error {
+	perm := defaultPerm
+	if like != nil {
+		perm = like.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	// clean up the temp file if anything fails before the rename
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// the rename would otherwise hand the file to the current user, breaking group readers of
+	// files such as a root:shadow /etc/shadow
+	if like != nil {
+		if uid, gid, ok := fileOwner(like); ok {
+			if err := os.Chown(tmp.Name(), uid, gid); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,24 @@
+package etcpwdparse
+
+// DiffBackup compares the passwd file at the given path against its backup, the same path with
+// BackupSuffix appended as written by shadow-utils tools such as useradd and vipw and by
+// EditTransaction. Since the tools write the backup just before changing the file, the result
+// shows the most recent account change, going from the backup to the current file. Bad lines
+// in either file are skipped so that a damaged file can still be compared.
+func DiffBackup(path string, opts ...Option) (PasswdDiff, error) {
+	backup := NewEtcPasswdCache(true, opts...)
+	if err := backup.LoadFromPath(path + BackupSuffix); err != nil {
+		return PasswdDiff{}, err
+	}
+	current := NewEtcPasswdCache(true, opts...)
+	if err := current.LoadFromPath(path); err != nil {
+		return PasswdDiff{}, err
+	}
+	return Diff(backup, current), nil
+}
+
+// DiffDefaultBackup is DiffBackup for the /etc/passwd file, relative to the root given by
+// WithRoot, so the files of a mounted disk image can be compared.
+func DiffDefaultBackup(opts ...Option) (PasswdDiff, error) {
+	return DiffBackup(applyOptions(opts).defaultPath("/etc/passwd"), opts...)
+}
@@ -0,0 +1,31 @@
+package etcpwdparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffBackup(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "etc"), 0755)
+	path := filepath.Join(root, "etc", "passwd")
+	os.WriteFile(path+BackupSuffix, []byte("root:x:0:0:root:/root:/bin/bash\nbob:x:1000:1000::/home/bob:/bin/sh\n"), 0644)
+	os.WriteFile(path, []byte("root:x:0:0:root:/root:/bin/bash\nbob:x:1000:1000::/home/bob:/bin/bash\nbroken\nmallory:x:0:0::/:/bin/sh\n"), 0644)
+
+	diff, err := DiffDefaultBackup(WithRoot(root))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Username() != "mallory" || len(diff.Removed) != 0 {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+	if len(diff.Modified) != 1 || diff.Modified[0].Changes[0] != (FieldChange{Field: "shell", Old: "/bin/sh", New: "/bin/bash"}) {
+		t.Fatalf("unexpected modifications %+v", diff.Modified)
+	}
+
+	os.Remove(path + BackupSuffix)
+	if _, err := DiffBackup(path); !os.IsNotExist(err) {
+		t.Fatalf("expected a missing backup to fail, got %v", err)
+	}
+}
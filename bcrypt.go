@@ -0,0 +1,158 @@
+package etcpwdparse
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	bcryptAlphabet = "./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	bcryptMinCost  = 4
+	bcryptMaxCost  = 31
+	bcryptSaltLen  = 16
+	bcryptMaxKey   = 72
+)
+
+// bcryptMagic is the text that bcrypt encrypts with the expensive key schedule.
+var bcryptMagic = []byte("OrpheanBeholderScryDoubt")
+
+// blowfish holds the key dependent state of the Blowfish cipher.
+type blowfish struct {
+	p [18]uint32
+	s [4][256]uint32
+}
+
+// f is the Blowfish round function.
+func (b *blowfish) f(x uint32) uint32 {
+	return ((b.s[0][x>>24] + b.s[1][x>>16&0xff]) ^ b.s[2][x>>8&0xff]) + b.s[3][x&0xff]
+}
+
+// encrypt enciphers the two halves of a block.
+func (b *blowfish) encrypt(l, r uint32) (uint32, uint32) {
+	l ^= b.p[0]
+	for i := 1; i < 17; i += 2 {
+		r ^= b.f(l) ^ b.p[i]
+		l ^= b.f(r) ^ b.p[i+1]
+	}
+	return r ^ b.p[17], l
+}
+
+// streamWord returns the next big endian word of the data, wrapping around at the end.
+func streamWord(data []byte, pos *int) uint32 {
+	var w uint32
+	for i := 0; i < 4; i++ {
+		w = w<<8 | uint32(data[*pos])
+		*pos = (*pos + 1) % len(data)
+	}
+	return w
+}
+
+// expandKey mixes the key and, unless it is nil, the salt into the state.
+func (b *blowfish) expandKey(key, salt []byte) {
+	pos := 0
+	for i := range b.p {
+		b.p[i] ^= streamWord(key, &pos)
+	}
+	pos = 0
+	var l, r uint32
+	next := func() {
+		if salt != nil {
+			l ^= streamWord(salt, &pos)
+			r ^= streamWord(salt, &pos)
+		}
+		l, r = b.encrypt(l, r)
+	}
+	for i := 0; i < len(b.p); i += 2 {
+		next()
+		b.p[i], b.p[i+1] = l, r
+	}
+	for i := range b.s {
+		for j := 0; j < len(b.s[i]); j += 2 {
+			next()
+			b.s[i][j], b.s[i][j+1] = l, r
+		}
+	}
+}
+
+// bcryptEncode encodes the bytes in the big endian base64 variant used by bcrypt, without padding.
+func bcryptEncode(data []byte) string {
+	result := make([]byte, 0, (len(data)*8+5)/6)
+	for i := 0; i < len(data); i += 3 {
+		var w uint
+		n := min(len(data)-i, 3)
+		for j := 0; j < 3; j++ {
+			w <<= 8
+			if j < n {
+				w |= uint(data[i+j])
+			}
+		}
+		for j := 0; j <= n; j++ {
+			result = append(result, bcryptAlphabet[w>>(18-6*j)&0x3f])
+		}
+	}
+	return string(result)
+}
+
+// bcryptDecode decodes length bytes from the bcrypt base64 text.
+func bcryptDecode(text string, length int) ([]byte, error) {
+	result := make([]byte, 0, length+2)
+	for i := 0; len(result) < length; i += 4 {
+		var w uint
+		for j := 0; j < 4; j++ {
+			w <<= 6
+			if i+j < len(text) {
+				c := strings.IndexByte(bcryptAlphabet, text[i+j])
+				if c < 0 {
+					return nil, fmt.Errorf("Password hash had badly formatted salt %s", text)
+				}
+				w |= uint(c)
+			}
+		}
+		result = append(result, byte(w>>16), byte(w>>8), byte(w))
+	}
+	return result[:length], nil
+}
+
+// bcryptCrypt computes the bcrypt hash of the plaintext using the variant, cost, and salt of the
+// setting, as introduced by OpenBSD. The "$2a$", "$2b$", and "$2y$" variants only differ for
+// passwords that are too long or contain 8-bit characters on broken implementations, so they are
+// computed in the same way.
+func bcryptCrypt(setting, plaintext string) (string, error) {
+	if len(setting) < 7+22 || setting[3] != '$' || setting[6] != '$' {
+		return "", fmt.Errorf("Password hash had badly formatted bcrypt setting")
+	}
+	cost, err := strconv.Atoi(setting[4:6])
+	if err != nil || cost < bcryptMinCost || cost > bcryptMaxCost {
+		return "", fmt.Errorf("Password hash had bad bcrypt cost %s", setting[4:6])
+	}
+	salt, err := bcryptDecode(setting[7:7+22], bcryptSaltLen)
+	if err != nil {
+		return "", err
+	}
+	key := append([]byte(plaintext), 0)
+	if len(key) > bcryptMaxKey {
+		key = key[:bcryptMaxKey]
+	}
+
+	b := &blowfish{p: blowfishP, s: blowfishS}
+	b.expandKey(key, salt)
+	for i := uint64(0); i < 1<<uint(cost); i++ {
+		b.expandKey(key, nil)
+		b.expandKey(salt, nil)
+	}
+
+	text := make([]byte, len(bcryptMagic))
+	copy(text, bcryptMagic)
+	for i := 0; i < len(text); i += 8 {
+		l, r := binary.BigEndian.Uint32(text[i:]), binary.BigEndian.Uint32(text[i+4:])
+		for j := 0; j < 64; j++ {
+			l, r = b.encrypt(l, r)
+		}
+		binary.BigEndian.PutUint32(text[i:], l)
+		binary.BigEndian.PutUint32(text[i+4:], r)
+	}
+	// the last byte of the digest has never been part of the hash
+	return setting[:7] + bcryptEncode(salt) + bcryptEncode(text[:len(text)-1]), nil
+}
@@ -0,0 +1,26 @@
+package etcpwdparse
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ChownPathForUser looks up the uid and primary gid of the user and makes them the owner of the
+// path. When recursive is set everything below the path is changed too, like `chown -R`, and
+// symbolic links are changed themselves rather than followed.
+func (e *EtcPasswdCache) ChownPathForUser(path, username string, recursive bool) error {
+	entry, ok := e.LookupUserByName(username)
+	if !ok {
+		return sentinelf(ErrUserNotFound, "No such user with username '%s'", username)
+	}
+	if !recursive {
+		return os.Chown(path, entry.uid, entry.gid)
+	}
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(p, entry.uid, entry.gid)
+	})
+}
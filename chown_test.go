@@ -0,0 +1,62 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package etcpwdparse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestChownPathForUser(t *testing.T) {
+	uid, gid := os.Getuid(), os.Getgid()
+	if os.Getuid() == 0 {
+		// root can give the files away to check that the owner really changes
+		uid, gid = 4321, 4322
+	}
+	cache := NewEtcPasswdCache(false)
+	content := fmt.Sprintf("owner:x:%d:%d::/home/owner:/bin/sh\n", uid, gid)
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	tempDir, _ := os.MkdirTemp("", "chown")
+	defer os.RemoveAll(tempDir)
+	nested := filepath.Join(tempDir, "a", "b")
+	os.MkdirAll(nested, 0755)
+	file := filepath.Join(nested, "file")
+	os.WriteFile(file, []byte("data"), 0644)
+	os.Symlink("/nonexistent", filepath.Join(tempDir, "link"))
+
+	owner := func(p string) (int, int) {
+		info, err := os.Lstat(p)
+		if err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		stat := info.Sys().(*syscall.Stat_t)
+		return int(stat.Uid), int(stat.Gid)
+	}
+
+	if err := cache.ChownPathForUser(tempDir, "owner", false); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if u, g := owner(tempDir); u != uid || g != gid {
+		t.Fatalf("%d:%d != %d:%d", u, g, uid, gid)
+	}
+
+	if err := cache.ChownPathForUser(tempDir, "owner", true); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	for _, p := range []string{nested, file, filepath.Join(tempDir, "link")} {
+		if u, g := owner(p); u != uid || g != gid {
+			t.Fatalf("%s: %d:%d != %d:%d", p, u, g, uid, gid)
+		}
+	}
+
+	if err := cache.ChownPathForUser(tempDir, "nosuchuser", true); err == nil {
+		t.Fatalf("Should have failed for an unknown user")
+	}
+}
@@ -0,0 +1,23 @@
+package etcpwdparse
+
+// Clone returns an independent copy of the cache holding copies of its entries, lines, and
+// indexes, so that a worker can take a consistent snapshot and change it without affecting
+// the shared cache or seeing later changes to it. The copy keeps the path and options, so it can
+// be saved, but it is never reloaded by WithTTL or WithStatCheck and has no subscribers.
+func (e *EtcPasswdCache) Clone() *EtcPasswdCache {
+	e.refresh()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	entries := make([]*EtcPasswdEntry, len(e.entries))
+	for i, entry := range e.entries {
+		entries[i] = entry.Clone()
+	}
+	clone := e.newLoadTarget(e.path)
+	clone.opts.ttl, clone.opts.statCheck = 0, false
+	clone.lines = e.lines
+	clone.duplicates = append(clone.duplicates, e.duplicates...)
+	clone.compat = append(clone.compat, e.compat...)
+	clone.rebuildEntries(entries, func(*EtcPasswdEntry) bool { return false }, true)
+	clone.loadedAt, clone.loadedInfo = e.loadedAt, e.loadedInfo
+	return clone
+}
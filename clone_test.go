@@ -0,0 +1,51 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCacheClone(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakeReconcileContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	clone := cache.Clone()
+
+	original, _ := cache.LookupUserByName("alice")
+	copied, ok := clone.LookupUserByName("alice")
+	if !ok || copied == original || !copied.Equal(original) {
+		t.Fatalf("the clone should hold equal copies of the entries")
+	}
+
+	// changes to the clone do not affect the cache
+	if _, err := clone.AddUser(UserSpec{Username: "carol"}); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := clone.DeleteUser("bob"); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, ok := cache.LookupUserByName("carol"); ok {
+		t.Fatalf("carol should only be in the clone")
+	}
+	if _, ok := cache.LookupUserByUid(1001); !ok {
+		t.Fatalf("bob should still be in the cache")
+	}
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	if buf.String() != fakeReconcileContent {
+		t.Fatalf("%q != %q", buf.String(), fakeReconcileContent)
+	}
+
+	// and changes to the cache do not affect the clone
+	cache.ModifyUser("alice", func(b *EtcPasswdEntryBuilder) { b.Shell("/bin/zsh") })
+	if entry, _ := clone.LookupUserByName("alice"); entry.Shell() != "/bin/bash" {
+		t.Fatalf("%s != /bin/bash", entry.Shell())
+	}
+	buf.Reset()
+	clone.WriteTo(buf)
+	if !strings.HasPrefix(buf.String(), "# managed accounts\n") || strings.Contains(buf.String(), "bob") {
+		t.Fatalf("unexpected clone content %q", buf.String())
+	}
+}
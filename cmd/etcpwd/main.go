@@ -0,0 +1,235 @@
+// Command etcpwd is a small command line interface over the etcpwdparse package for scripting
+// on minimal systems that lack getent.
+//
+// Usage:
+//
+//	etcpwd [--file path] lookup <name|uid>
+//	etcpwd [--file path] list [--json]
+//	etcpwd [--file path] homedir <name>
+//	etcpwd validate [--strict] <file>
+//	etcpwd minimal [--user name:uid]... <rootdir>
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/AstromechZA/etcpwdparse"
+)
+
+const usageText = `usage: etcpwd [--file path] <command> [arguments]
+
+commands:
+  lookup <name|uid>   print the passwd entry for a username or uid
+  list [--json]       print every passwd entry
+  homedir <name>      print the home directory of a user
+  validate [--strict] <file>
+                      check a passwd file for parse errors and, with --strict,
+                      consistency problems
+  minimal [--user name:uid]... <rootdir>
+                      write a minimal etc/passwd and etc/group below rootdir
+                      holding root, nobody, and the given users
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("etcpwd", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usageText) }
+	file := fs.String("file", "", "passwd file to read instead of /etc/passwd")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	command, rest := fs.Arg(0), fs.Args()[1:]
+	switch command {
+	case "validate":
+		return runValidate(rest, stdout, stderr)
+	case "minimal":
+		return runMinimal(rest, stdout, stderr)
+	}
+	handlers := map[string]func(*etcpwdparse.EtcPasswdCache, []string, io.Writer, io.Writer) int{
+		"lookup":  runLookup,
+		"list":    runList,
+		"homedir": runHomedir,
+	}
+	handler, ok := handlers[command]
+	if !ok {
+		fmt.Fprintf(stderr, "etcpwd: unknown command '%s'\n", command)
+		fs.Usage()
+		return 2
+	}
+
+	cache, err := loadCache(*file)
+	if err != nil {
+		fmt.Fprintf(stderr, "etcpwd: %s\n", err)
+		return 1
+	}
+	return handler(cache, rest, stdout, stderr)
+}
+
+// loadCache loads the given passwd file or the system default when path is empty.
+func loadCache(path string) (*etcpwdparse.EtcPasswdCache, error) {
+	cache := etcpwdparse.NewEtcPasswdCache(false)
+	if len(path) == 0 {
+		return cache, cache.LoadDefault()
+	}
+	return cache, cache.LoadFromPath(path)
+}
+
+func runLookup(cache *etcpwdparse.EtcPasswdCache, args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: etcpwd lookup <name|uid>")
+		return 2
+	}
+	entry, ok := cache.LookupUserByName(args[0])
+	if !ok {
+		if uid, err := strconv.Atoi(args[0]); err == nil {
+			entry, ok = cache.LookupUserByUid(uid)
+		}
+	}
+	if !ok {
+		fmt.Fprintf(stderr, "etcpwd: no such user '%s'\n", args[0])
+		return 1
+	}
+	fmt.Fprintln(stdout, entry.String())
+	return 0
+}
+
+func runList(cache *etcpwdparse.EtcPasswdCache, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print the entries as a JSON array")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *asJSON {
+		data, err := cache.ToJSON()
+		if err != nil {
+			fmt.Fprintf(stderr, "etcpwd: %s\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, string(data))
+		return 0
+	}
+	for entry := range cache.All() {
+		fmt.Fprintln(stdout, entry.String())
+	}
+	return 0
+}
+
+func runHomedir(cache *etcpwdparse.EtcPasswdCache, args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: etcpwd homedir <name>")
+		return 2
+	}
+	homedir, err := cache.HomeDirForUsername(args[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "etcpwd: %s\n", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, homedir)
+	return 0
+}
+
+// runValidate parses the given file and reports line-level diagnostics. Every bad line is reported
+// and parse errors always fail, consistency findings are printed as warnings unless --strict turns
+// them into failures.
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	strict := fs.Bool("strict", false, "fail on consistency problems as well as parse errors")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: etcpwd validate [--strict] <file>")
+		return 2
+	}
+	file := fs.Arg(0)
+
+	cache := etcpwdparse.NewEtcPasswdCache(false, etcpwdparse.WithCollectErrors())
+	err := cache.LoadFromPath(file)
+	badLines := 0
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		// the good lines are still loaded so their findings are reported along with the bad lines
+		for _, lineErr := range joined.Unwrap() {
+			fmt.Fprintf(stderr, "%s: error: %s\n", file, lineErr)
+			badLines++
+		}
+	} else if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", file, err)
+		return 1
+	}
+	findings := cache.Validate()
+	level := "warning"
+	if *strict {
+		level = "error"
+	}
+	for _, f := range findings {
+		fmt.Fprintf(stderr, "%s: %s: %s\n", file, level, f)
+	}
+	if badLines > 0 || (*strict && len(findings) > 0) {
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s: ok\n", file)
+	return 0
+}
+
+// userFlags collects the repeated --user name:uid flags of the minimal command.
+type userFlags []string
+
+func (u *userFlags) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *userFlags) Set(value string) error {
+	*u = append(*u, value)
+	return nil
+}
+
+// runMinimal writes a minimal passwd and group file for a scratch image below the given root.
+func runMinimal(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("minimal", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var users userFlags
+	fs.Var(&users, "user", "add a user as name:uid, may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: etcpwd minimal [--user name:uid]... <rootdir>")
+		return 2
+	}
+
+	accounts := etcpwdparse.NewMinimalAccounts()
+	for _, user := range users {
+		name, value, _ := strings.Cut(user, ":")
+		uid, err := strconv.Atoi(value)
+		if err != nil {
+			fmt.Fprintf(stderr, "etcpwd: bad user '%s', expected name:uid\n", user)
+			return 2
+		}
+		if err := accounts.AddUser(name, uid); err != nil {
+			fmt.Fprintf(stderr, "etcpwd: %s\n", err)
+			return 1
+		}
+	}
+	if err := accounts.WriteToRoot(fs.Arg(0)); err != nil {
+		fmt.Fprintf(stderr, "etcpwd: %s\n", err)
+		return 1
+	}
+	return 0
+}
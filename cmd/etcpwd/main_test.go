@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+const fakePwdContent = `root:x:0:0:root:/root:/bin/bash
+alice:x:1000:1000:Alice:/home/alice:/bin/zsh
+`
+
+func writeFakePasswd(t *testing.T) (string, func()) {
+	return writePasswd(t, fakePwdContent)
+}
+
+func writePasswd(t *testing.T, content string) (string, func()) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	pwFile := path.Join(tempDir, "passwd")
+	if err := ioutil.WriteFile(pwFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	return pwFile, func() { os.RemoveAll(tempDir) }
+}
+
+func runWith(args ...string) (int, string, string) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run(args, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	pwFile, cleanup := writeFakePasswd(t)
+	defer cleanup()
+
+	if code, out, _ := runWith("--file", pwFile, "lookup", "alice"); code != 0 || out != "alice:x:1000:1000:Alice:/home/alice:/bin/zsh\n" {
+		t.Fatalf("unexpected lookup result %d %q", code, out)
+	}
+	if code, out, _ := runWith("--file", pwFile, "lookup", "0"); code != 0 || !strings.HasPrefix(out, "root:") {
+		t.Fatalf("unexpected lookup by uid result %d %q", code, out)
+	}
+	if code, _, errOut := runWith("--file", pwFile, "lookup", "bob"); code != 1 || !strings.Contains(errOut, "no such user") {
+		t.Fatalf("unexpected missing lookup result %d %q", code, errOut)
+	}
+	if code, out, _ := runWith("--file", pwFile, "list"); code != 0 || out != fakePwdContent {
+		t.Fatalf("unexpected list result %d %q", code, out)
+	}
+	if code, out, _ := runWith("--file", pwFile, "list", "--json"); code != 0 || !strings.HasPrefix(out, `[{"username":"root"`) {
+		t.Fatalf("unexpected json list result %d %q", code, out)
+	}
+	if code, out, _ := runWith("--file", pwFile, "homedir", "alice"); code != 0 || out != "/home/alice\n" {
+		t.Fatalf("unexpected homedir result %d %q", code, out)
+	}
+	if code, _, _ := runWith("--file", pwFile, "explode"); code != 2 {
+		t.Fatalf("unknown commands should exit with 2")
+	}
+	if code, _, _ := runWith(); code != 2 {
+		t.Fatalf("no command should exit with 2")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	pwFile, cleanup := writeFakePasswd(t)
+	defer cleanup()
+	if code, out, errOut := runWith("validate", "--strict", pwFile); code != 0 || !strings.HasSuffix(out, ": ok\n") || errOut != "" {
+		t.Fatalf("unexpected validate result %d %q %q", code, out, errOut)
+	}
+
+	badFile, badCleanup := writePasswd(t, "root:x:0:0:root:/root:/bin/bash\nbob:x:zero:0::/home/bob:/bin/sh\n")
+	defer badCleanup()
+	if code, _, errOut := runWith("validate", badFile); code != 1 || !strings.Contains(errOut, "line 2") {
+		t.Fatalf("unexpected validate result for bad file %d %q", code, errOut)
+	}
+
+	manyFile, manyCleanup := writePasswd(t, "broken\nroot:x:0:0:root:/root:/bin/bash\nbob:x:zero:0::/home/bob:/bin/sh\nalso:broken\n")
+	defer manyCleanup()
+	code, out, errOut := runWith("validate", manyFile)
+	if code != 1 || out != "" || strings.Count(errOut, ": error: line ") != 3 {
+		t.Fatalf("expected every bad line to be reported %d %q", code, errOut)
+	}
+	for _, line := range []string{"line 1", "line 3", "line 4"} {
+		if !strings.Contains(errOut, line) {
+			t.Fatalf("expected %s to be reported: %q", line, errOut)
+		}
+	}
+
+	dupFile, dupCleanup := writePasswd(t, fakePwdContent+"alice:x:1001:1001::/home/alice2:/bin/sh\n")
+	defer dupCleanup()
+	if code, _, errOut := runWith("validate", dupFile); code != 0 || !strings.Contains(errOut, "warning: line 3") {
+		t.Fatalf("unexpected non-strict validate result %d %q", code, errOut)
+	}
+	if code, _, errOut := runWith("validate", "--strict", dupFile); code != 1 || !strings.Contains(errOut, "error: line 3: user 'alice'") {
+		t.Fatalf("unexpected strict validate result %d %q", code, errOut)
+	}
+}
+
+func TestMinimal(t *testing.T) {
+	root, _ := ioutil.TempDir("", "minimal")
+	defer os.RemoveAll(root)
+
+	if code, _, errOut := runWith("minimal", "--user", "nonroot:65532", "--user", "app:1000", root); code != 0 {
+		t.Fatalf("unexpected minimal result %d %q", code, errOut)
+	}
+	passwd, _ := ioutil.ReadFile(path.Join(root, "etc", "passwd"))
+	if !strings.Contains(string(passwd), "nonroot:x:65532:65532:nonroot:/home/nonroot:/sbin/nologin\n") || !strings.HasPrefix(string(passwd), "root:") {
+		t.Fatalf("unexpected passwd %q", passwd)
+	}
+	group, _ := ioutil.ReadFile(path.Join(root, "etc", "group"))
+	if !strings.Contains(string(group), "app:x:1000:\n") {
+		t.Fatalf("unexpected group %q", group)
+	}
+	if code, out, _ := runWith("--file", path.Join(root, "etc", "passwd"), "lookup", "app"); code != 0 || !strings.HasPrefix(out, "app:x:1000:") {
+		t.Fatalf("unexpected lookup result %d %q", code, out)
+	}
+
+	if code, _, _ := runWith("minimal", "--user", "nonroot", root); code != 2 {
+		t.Fatalf("a user without a uid should exit with 2")
+	}
+	if code, _, _ := runWith("minimal", "--user", "root:5", root); code != 1 {
+		t.Fatalf("a duplicate user should exit with 1")
+	}
+	if code, _, _ := runWith("minimal"); code != 2 {
+		t.Fatalf("a missing root should exit with 2")
+	}
+}
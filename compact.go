@@ -0,0 +1,100 @@
+package etcpwdparse
+
+import (
+	"strings"
+)
+
+// WithCompactStorage reduces the memory used by large caches. Normally every entry refers to the
+// raw line it was loaded from so that it can be written back exactly, which keeps the whole file
+// in memory. With compact storage the raw lines of entries are dropped, repeated field values
+// such as "x", "/sbin/nologin", and shared home directories are stored once, and the other
+// fields are copied out of the line so that it can be freed. Comments and bad lines are still
+// kept, but entries are written back in their canonical form, losing any unusual spacing.
+//
+// The entry structs and lookup indexes take up most of the memory of a cache and are the same
+// size in both modes, so the saving grows with the length of the lines and how often field
+// values repeat. BenchmarkRetainedMemory compares the two modes.
+func WithCompactStorage() Option {
+	return func(o *options) {
+		o.compact = true
+	}
+}
+
+// interner stores one copy of each distinct string it is given.
+type interner struct {
+	values map[string]string
+}
+
+// newInterner returns an empty interner
+func newInterner() *interner {
+	return &interner{values: make(map[string]string)}
+}
+
+// intern returns the stored copy of the value, storing a copy that does not refer to the
+// memory of the given string if it has not been seen before.
+func (in *interner) intern(value string) string {
+	if stored, ok := in.values[value]; ok {
+		return stored
+	}
+	stored := strings.Clone(value)
+	in.values[stored] = stored
+	return stored
+}
+
+// compactEntry returns the entry with all its fields detached from the line it was parsed from.
+// Fields that repeat across entries are interned, while the username and any info or home
+// directory seen for the first time are copied into one new string that they share.
+func (in *interner) compactEntry(entry EtcPasswdEntry) EtcPasswdEntry {
+	entry.password = in.intern(entry.password)
+	entry.shell = in.intern(entry.shell)
+	entry.class = in.intern(entry.class)
+
+	info, infoSeen := in.values[entry.info]
+	homedir, homedirSeen := in.values[entry.homedir]
+	var b strings.Builder
+	b.Grow(len(entry.username) + len(entry.info) + len(entry.homedir))
+	b.WriteString(entry.username)
+	if !infoSeen {
+		b.WriteString(entry.info)
+	}
+	if !homedirSeen {
+		b.WriteString(entry.homedir)
+	}
+	shared := b.String()
+	offset := len(entry.username)
+	entry.username = shared[:offset]
+	if !infoSeen {
+		info, offset = shared[offset:offset+len(entry.info)], offset+len(entry.info)
+		in.values[info] = info
+	}
+	if !homedirSeen {
+		homedir = shared[offset:]
+		in.values[homedir] = homedir
+	}
+	entry.info, entry.homedir = info, homedir
+	return entry
+}
+
+// compactIndexes copies the slices of a freshly loaded cache at their exact sizes, dropping the
+// spare capacity left over from growing them one line at a time.
+func (e *EtcPasswdCache) compactIndexes() {
+	e.entries = clipPointers(e.entries)
+	e.uidindex = clipPointers(e.uidindex)
+	lines := make([]passwdLine, len(e.lines))
+	copy(lines, e.lines)
+	e.lines = lines
+
+	for k, v := range e.gidmap {
+		e.gidmap[k] = clipPointers(v)
+	}
+	for k, v := range e.shellmap {
+		e.shellmap[k] = clipPointers(v)
+	}
+}
+
+// clipPointers returns a copy of the slice without spare capacity
+func clipPointers(entries []*EtcPasswdEntry) []*EtcPasswdEntry {
+	clipped := make([]*EtcPasswdEntry, len(entries))
+	copy(clipped, entries)
+	return clipped
+}
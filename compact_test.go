@@ -0,0 +1,78 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestCompactStorage(t *testing.T) {
+	content := "# users\n" + fakePwdContent + "ftp:x:15:50::/var/ftp:/sbin/nologin\n"
+	normal := NewEtcPasswdCache(false)
+	if err := normal.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	compact := NewEtcPasswdCache(false, WithCompactStorage(), WithDuplicatePolicy(DuplicateFirstWins))
+	if err := compact.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	entries := compact.ListEntries()
+	if len(entries) != 13 {
+		t.Fatalf("%d != 13", len(entries))
+	}
+	for i, entry := range normal.ListEntries()[:13] {
+		if *entry != *entries[i] {
+			t.Fatalf("%v != %v", *entry, *entries[i])
+		}
+	}
+	// repeated values are stored once
+	bin, _ := compact.LookupUserByName("bin")
+	daemon, _ := compact.LookupUserByName("daemon")
+	if strings.Count(content, "/sbin/nologin") < 2 || !sameString(bin.Shell(), daemon.Shell()) {
+		t.Fatalf("shells should have been interned")
+	}
+
+	buf := new(bytes.Buffer)
+	compact.WriteTo(buf)
+	if buf.String() != content {
+		t.Fatalf("unexpected content %q", buf.String())
+	}
+}
+
+// sameString returns true if both strings share the same memory
+func sameString(a, b string) bool {
+	return len(a) > 0 && len(a) == len(b) && unsafe.StringData(a) == unsafe.StringData(b)
+}
+
+// retainedBytesPerEntry loads a large generated file and reports the heap kept alive by the
+// cache divided by the number of entries.
+func retainedBytesPerEntry(b *testing.B, opts ...Option) {
+	buf := new(bytes.Buffer)
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(buf, "user%d:x:%d:100:User %d:/home/user%d:/sbin/nologin\n", i, 10000+i, i, i)
+	}
+	content := buf.String()
+	var before, after runtime.MemStats
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		cache := NewEtcPasswdCache(false, opts...)
+		cache.LoadFromReader(strings.NewReader(content))
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/20000, "retained-B/entry")
+		runtime.KeepAlive(cache)
+	}
+}
+
+func BenchmarkRetainedMemory(b *testing.B) {
+	retainedBytesPerEntry(b)
+}
+
+func BenchmarkRetainedMemoryCompact(b *testing.B) {
+	retainedBytesPerEntry(b, WithCompactStorage())
+}
@@ -0,0 +1,109 @@
+package etcpwdparse
+
+import (
+	"strings"
+)
+
+// CompatKind says whether a compat entry includes or excludes users from the NIS map.
+type CompatKind int
+
+const (
+	// CompatInclude is a line starting with '+'
+	CompatInclude CompatKind = iota
+	// CompatExclude is a line starting with '-'
+	CompatExclude
+)
+
+// CompatSelector says which users from the NIS map a compat entry applies to.
+type CompatSelector int
+
+const (
+	// CompatAll is a bare '+' or '-' which applies to every user
+	CompatAll CompatSelector = iota
+	// CompatUser is '+name' or '-name' which applies to a single user
+	CompatUser
+	// CompatNetgroup is '+@netgroup' or '-@netgroup' which applies to every user in the netgroup
+	CompatNetgroup
+)
+
+// CompatEntry is a parsed NIS compat line such as "+", "-alice", or "+@admins" that is legal in
+// an /etc/passwd file when nsswitch uses "compat" mode. The entries are kept in the cache for
+// inspection but are not resolved since this package does not talk to NIS.
+type CompatEntry struct {
+	kind     CompatKind
+	selector CompatSelector
+	name     string
+	fields   []string
+}
+
+// Kind function returns whether the entry includes or excludes users
+func (e *CompatEntry) Kind() CompatKind {
+	return e.kind
+}
+
+// Selector function returns which users the entry applies to
+func (e *CompatEntry) Selector() CompatSelector {
+	return e.selector
+}
+
+// Name function returns the username or netgroup name, which is empty for CompatAll
+func (e *CompatEntry) Name() string {
+	return e.name
+}
+
+// Fields function returns the remaining colon separated fields of the line. For include entries
+// non-empty fields override the values from the NIS map.
+func (e *CompatEntry) Fields() []string {
+	return e.fields
+}
+
+// IsCompatLine returns true if the line is a NIS compat line rather than a normal entry.
+func IsCompatLine(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")
+}
+
+// ParseCompatLine is a function used to parse a NIS compat line into a CompatEntry object.
+// Errors are returned as a *ParseError.
+func ParseCompatLine(line string) (CompatEntry, error) {
+	result := CompatEntry{}
+	trimmed := strings.TrimSpace(line)
+	if !IsCompatLine(trimmed) {
+		return result, newParseError(line, "", "Compat line must start with '+' or '-'")
+	}
+	if trimmed[0] == '-' {
+		result.kind = CompatExclude
+	}
+	parts := strings.Split(trimmed[1:], ":")
+	if len(parts) > 7 {
+		return result, newParseError(line, "", "Compat line had too many parts %d > 7", len(parts))
+	}
+	selector := strings.TrimSpace(parts[0])
+	switch {
+	case len(selector) == 0:
+		result.selector = CompatAll
+	case strings.HasPrefix(selector, "@"):
+		result.selector = CompatNetgroup
+		result.name = selector[1:]
+		if len(result.name) == 0 {
+			return result, newParseError(line, "username", "Compat line had an empty netgroup name")
+		}
+	default:
+		result.selector = CompatUser
+		result.name = selector
+	}
+	result.fields = make([]string, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		result.fields = append(result.fields, strings.TrimSpace(p))
+	}
+	return result, nil
+}
+
+// ListCompatEntries returns the NIS compat entries found in the loaded file in file order.
+func (e *EtcPasswdCache) ListCompatEntries() []*CompatEntry {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	results := make([]*CompatEntry, len(e.compat))
+	copy(results, e.compat)
+	return results
+}
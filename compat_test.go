@@ -0,0 +1,58 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompatEntries(t *testing.T) {
+	content := `root:x:0:0:root:/root:/bin/bash
++alice::::::/bin/zsh
+-bob
++@admins::::::
+-@contractors
++::::::
+`
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(cache.ListEntries()) != 1 {
+		t.Fatalf("%d != 1", len(cache.ListEntries()))
+	}
+
+	compat := cache.ListCompatEntries()
+	expected := []struct {
+		kind     CompatKind
+		selector CompatSelector
+		name     string
+	}{
+		{CompatInclude, CompatUser, "alice"},
+		{CompatExclude, CompatUser, "bob"},
+		{CompatInclude, CompatNetgroup, "admins"},
+		{CompatExclude, CompatNetgroup, "contractors"},
+		{CompatInclude, CompatAll, ""},
+	}
+	if len(compat) != len(expected) {
+		t.Fatalf("%d != %d", len(compat), len(expected))
+	}
+	for i, c := range compat {
+		if c.Kind() != expected[i].kind || c.Selector() != expected[i].selector || c.Name() != expected[i].name {
+			t.Fatalf("compat entry %d was parsed incorrectly: %+v", i, c)
+		}
+	}
+	if fields := compat[0].Fields(); len(fields) != 6 || fields[5] != "/bin/zsh" {
+		t.Fatalf("unexpected override fields %v", fields)
+	}
+
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	if buf.String() != content {
+		t.Fatalf("%q != %q", buf.String(), content)
+	}
+
+	if _, err := ParseCompatLine("+@"); err == nil {
+		t.Fatalf("Should have failed on an empty netgroup")
+	}
+}
@@ -0,0 +1,91 @@
+package etcpwdparse
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// contextReader stops reading once the context is done so that a slow load gives up between
+// reads.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read returns the context error once the context is done
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// loadContext opens and parses the content in the background and installs it unless the
+// context is done first. A read that is stuck in the kernel, for example on a hung NFS mount,
+// cannot be interrupted, so in that case the background work finishes on its own later and its
+// result is thrown away. A cancelled load is reported to the metrics sink as a failed load.
+func (e *EtcPasswdCache) loadContext(ctx context.Context, path string, open func() (io.ReadCloser, error)) error {
+	start := time.Now()
+	if err := ctx.Err(); err != nil {
+		e.reportLoad(path, nil, start, err)
+		return err
+	}
+	type result struct {
+		next *EtcPasswdCache
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		r, err := open()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer r.Close()
+		next, err := e.parse(&contextReader{ctx: ctx, r: r}, path)
+		if next != nil {
+			next.loadedInfo = statReader(r)
+		}
+		done <- result{next: next, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		e.reportLoad(path, nil, start, ctx.Err())
+		return ctx.Err()
+	case res := <-done:
+		if res.err != nil && ctx.Err() != nil {
+			e.reportLoad(path, nil, start, ctx.Err())
+			return ctx.Err()
+		}
+		if res.next != nil {
+			e.replaceContent(res.next)
+		}
+		e.reportLoad(path, res.next, start, res.err)
+		return res.err
+	}
+}
+
+// LoadFromPathContext is like LoadFromPath but returns the context error as soon as the context
+// is done, leaving the current content untouched.
+func (e *EtcPasswdCache) LoadFromPathContext(ctx context.Context, path string) error {
+	return e.loadContext(ctx, path, func() (io.ReadCloser, error) {
+		return os.Open(path)
+	})
+}
+
+// LoadFromReaderContext is like LoadFromReader but returns the context error as soon as the
+// context is done, leaving the current content untouched.
+func (e *EtcPasswdCache) LoadFromReaderContext(ctx context.Context, r io.Reader) error {
+	return e.loadContext(ctx, "", func() (io.ReadCloser, error) {
+		return io.NopCloser(r), nil
+	})
+}
+
+// LoadDefaultContext is like LoadDefault but returns the context error as soon as the context
+// is done.
+func (e *EtcPasswdCache) LoadDefaultContext(ctx context.Context) error {
+	return e.LoadFromPathContext(ctx, e.opts.defaultPath("/etc/passwd"))
+}
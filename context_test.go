@@ -0,0 +1,93 @@
+package etcpwdparse
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFromPathContext(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	ioutil.WriteFile(pwFile, []byte(fakePwdContent), 0644)
+
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromPathContext(context.Background(), pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(cache.ListEntries()) != 13 {
+		t.Fatalf("%d != 13", len(cache.ListEntries()))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cache.LoadFromPathContext(ctx, path.Join(tempDir, "missing")); err != context.Canceled {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+}
+
+func TestLoadFromReaderContextTimeout(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	cache.LoadFromReader(strings.NewReader(fakePwdContent))
+
+	// a reader that never returns simulates a hung mount
+	r, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := cache.LoadFromReaderContext(ctx, r); err != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline error, got %v", err)
+	}
+	if len(cache.ListEntries()) != 13 {
+		t.Fatalf("content should have been kept: %d != 13", len(cache.ListEntries()))
+	}
+}
+
+func TestGetentSourceContext(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "bin")
+	defer os.RemoveAll(tempDir)
+	script := path.Join(tempDir, "getent")
+	ioutil.WriteFile(script, []byte("#!/bin/sh\nexec sleep 5\n"), 0755)
+
+	source := &GetentSource{Command: script}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := source.LookupByNameContext(ctx, "root"); err != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline error, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("lookup should have been cancelled")
+	}
+}
+
+func TestLoadContextReportsCancellation(t *testing.T) {
+	sink := &recordingSink{}
+	cache := NewEtcPasswdCache(false, WithMetrics(sink))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cache.LoadFromReaderContext(ctx, strings.NewReader(fakePwdContent)); err != context.Canceled {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+
+	r, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := cache.LoadFromReaderContext(ctx, r); err != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline error, got %v", err)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.failures != 2 || len(sink.entries) != 0 {
+		t.Fatalf("expected 2 failures and no loads, got %d and %d", sink.failures, len(sink.entries))
+	}
+}
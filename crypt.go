@@ -0,0 +1,249 @@
+package etcpwdparse
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedHash is returned when a password hash uses a scheme that cannot be verified, such
+// as DES crypt or a yescrypt flavour other than the default one.
+var ErrUnsupportedHash = errors.New("Unsupported password hash scheme")
+
+const (
+	cryptAlphabet      = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	shaCryptRounds     = 5000
+	shaCryptMinRounds  = 1000
+	shaCryptMaxRounds  = 999999999
+	shaCryptMaxSalt    = 16
+	md5CryptMaxSalt    = 8
+	md5CryptIterations = 1000
+)
+
+// sha256CryptOrder and sha512CryptOrder are the byte triplets in which the final digests are
+// encoded by sha-crypt, followed by the leftover bytes.
+var sha256CryptOrder = [][3]int{
+	{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+	{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+}
+
+var sha512CryptOrder = [][3]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+	{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+	{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+	{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+	{62, 20, 41},
+}
+
+var md5CryptOrder = [][3]int{
+	{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5},
+}
+
+// VerifyPasswordHash checks the plaintext against a crypt(3) style hash. The yescrypt ("$y$"),
+// bcrypt ("$2b$", "$2a$", and "$2y$"), sha512-crypt ("$6$"), sha256-crypt ("$5$"), and md5-crypt
+// ("$1$") schemes are supported; other schemes return ErrUnsupportedHash.
+func VerifyPasswordHash(hashed, plaintext string) (bool, error) {
+	var computed string
+	var err error
+	switch {
+	case strings.HasPrefix(hashed, "$y$"):
+		computed, err = yescryptCrypt(hashed, plaintext)
+	case strings.HasPrefix(hashed, "$2b$"), strings.HasPrefix(hashed, "$2a$"), strings.HasPrefix(hashed, "$2y$"):
+		computed, err = bcryptCrypt(hashed, plaintext)
+	case strings.HasPrefix(hashed, "$6$"):
+		computed, err = shaCrypt(sha512.New, sha512CryptOrder, "$6$", hashed, plaintext)
+	case strings.HasPrefix(hashed, "$5$"):
+		computed, err = shaCrypt(sha256.New, sha256CryptOrder, "$5$", hashed, plaintext)
+	case strings.HasPrefix(hashed, "$1$"):
+		computed = md5Crypt(hashed, plaintext)
+	default:
+		return false, ErrUnsupportedHash
+	}
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(hashed)) == 1, nil
+}
+
+// VerifyPassword checks the plaintext against the shadow password of the user. Accounts that
+// are locked, have password logins disabled, or have an empty password never verify.
+func (e *EtcShadowCache) VerifyPassword(username, plaintext string) (bool, error) {
+	entry, ok := e.LookupUserByName(username)
+	if !ok {
+		return false, sentinelf(ErrUserNotFound, "No such user with username '%s'", username)
+	}
+	if ClassifyPassword(entry.password) != PasswordHash {
+		return false, nil
+	}
+	return VerifyPasswordHash(entry.password, plaintext)
+}
+
+// cryptEncode appends the digest bytes to the result in the crypt(3) base64 encoding, taking the
+// bytes in the given order followed by the trailing bytes.
+func cryptEncode(result []byte, digest []byte, order [][3]int, trailing ...int) []byte {
+	encode := func(w uint, n int) {
+		for ; n > 0; n-- {
+			result = append(result, cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	for _, o := range order {
+		encode(uint(digest[o[0]])<<16|uint(digest[o[1]])<<8|uint(digest[o[2]]), 4)
+	}
+	w := uint(0)
+	for _, i := range trailing {
+		w = w<<8 | uint(digest[i])
+	}
+	encode(w, (len(trailing)*8+5)/6)
+	return result
+}
+
+// repeatDigest returns the digest repeated and truncated to length bytes.
+func repeatDigest(digest []byte, length int) []byte {
+	result := make([]byte, 0, length)
+	for len(result)+len(digest) <= length {
+		result = append(result, digest...)
+	}
+	return append(result, digest[:length-len(result)]...)
+}
+
+// shaCrypt computes the sha-crypt hash of the plaintext using the prefix, rounds, and salt of
+// the setting, following the specification by Ulrich Drepper.
+func shaCrypt(newHash func() hash.Hash, order [][3]int, prefix, setting, plaintext string) (string, error) {
+	setting = strings.TrimPrefix(setting, prefix)
+	rounds := shaCryptRounds
+	roundsPrefix := ""
+	if strings.HasPrefix(setting, "rounds=") {
+		value, rest, _ := strings.Cut(strings.TrimPrefix(setting, "rounds="), "$")
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("Password hash had badly formatted rounds %s", value)
+		}
+		rounds = min(max(parsed, shaCryptMinRounds), shaCryptMaxRounds)
+		roundsPrefix = fmt.Sprintf("rounds=%d$", rounds)
+		setting = rest
+	}
+	salt, _, _ := strings.Cut(setting, "$")
+	if len(salt) > shaCryptMaxSalt {
+		salt = salt[:shaCryptMaxSalt]
+	}
+	password := []byte(plaintext)
+
+	h := newHash()
+	h.Write(password)
+	h.Write([]byte(salt))
+	h.Write(password)
+	alternate := h.Sum(nil)
+
+	h.Reset()
+	h.Write(password)
+	h.Write([]byte(salt))
+	h.Write(repeatDigest(alternate, len(password)))
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write(alternate)
+		} else {
+			h.Write(password)
+		}
+	}
+	digest := h.Sum(nil)
+
+	h.Reset()
+	for range password {
+		h.Write(password)
+	}
+	passwordSequence := repeatDigest(h.Sum(nil), len(password))
+
+	h.Reset()
+	for i := 0; i < 16+int(digest[0]); i++ {
+		h.Write([]byte(salt))
+	}
+	saltSequence := repeatDigest(h.Sum(nil), len(salt))
+
+	for i := 0; i < rounds; i++ {
+		h.Reset()
+		if i&1 != 0 {
+			h.Write(passwordSequence)
+		} else {
+			h.Write(digest)
+		}
+		if i%3 != 0 {
+			h.Write(saltSequence)
+		}
+		if i%7 != 0 {
+			h.Write(passwordSequence)
+		}
+		if i&1 != 0 {
+			h.Write(digest)
+		} else {
+			h.Write(passwordSequence)
+		}
+		digest = h.Sum(digest[:0])
+	}
+
+	result := []byte(prefix + roundsPrefix + salt + "$")
+	if len(digest) == sha512.Size {
+		result = cryptEncode(result, digest, order, 63)
+	} else {
+		result = cryptEncode(result, digest, order, 31, 30)
+	}
+	return string(result), nil
+}
+
+// md5Crypt computes the md5-crypt hash of the plaintext using the salt of the setting, as
+// introduced by FreeBSD and still accepted by glibc.
+func md5Crypt(setting, plaintext string) string {
+	salt, _, _ := strings.Cut(strings.TrimPrefix(setting, "$1$"), "$")
+	if len(salt) > md5CryptMaxSalt {
+		salt = salt[:md5CryptMaxSalt]
+	}
+	password := []byte(plaintext)
+
+	h := md5.New()
+	h.Write(password)
+	h.Write([]byte(salt))
+	h.Write(password)
+	alternate := h.Sum(nil)
+
+	h.Reset()
+	h.Write(password)
+	h.Write([]byte("$1$" + salt))
+	h.Write(repeatDigest(alternate, len(password)))
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(password[:1])
+		}
+	}
+	digest := h.Sum(nil)
+
+	for i := 0; i < md5CryptIterations; i++ {
+		h.Reset()
+		if i&1 != 0 {
+			h.Write(password)
+		} else {
+			h.Write(digest)
+		}
+		if i%3 != 0 {
+			h.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			h.Write(password)
+		}
+		if i&1 != 0 {
+			h.Write(digest)
+		} else {
+			h.Write(password)
+		}
+		digest = h.Sum(digest[:0])
+	}
+
+	return string(cryptEncode([]byte("$1$"+salt+"$"), digest, md5CryptOrder, 11))
+}
@@ -0,0 +1,92 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyPasswordHash(t *testing.T) {
+	vectors := []struct {
+		hashed    string
+		plaintext string
+	}{
+		{"$y$j9T$F5Jx5fExrKuPp53xLKQ..1$tnSYvahCwPBHKZUspmcxMfb0.WiB9W.zEaKlOBL35rC", "password"},
+		{"$y$j9T$F5Jx5fExrKuPp53xLKQ..1$5P1uc1zvKhieqEtKttbwCQrTPXpY1cK9wEnTDKAqLD8", ""},
+		{"$y$j75$abcdefghijklmnop$Dz4c4IbXLnyhVP3lRORmHdx4lPfPAZiTB4e2cyBTF66", "Hello world!"},
+		{"$y$jC5$abcdefghijklmnopqrstu.$d3cxKwE4Qtw2lW7SPh9H5dfNVkL8esiffyRRyMoFN32", "Hello world!"},
+		{"$y$j75..$abcdefghijklmnop$k1MekBSzHOAcf3mG0mOYMpRPkSTUbIXjWMX8aaOYtrD", "Hello world!"},
+		{"$y$j75/.$abcdefghijklmnop$mdocUYdfUYzTPT8rfXil0SlAFvfBvXrVYaHMQGZ3.Z/", "Hello world!"},
+		{"$2b$05$abcdefghijklmnopqrstuuWG29KuyeAicPCJODk1zjyGvyQUU2awu", "password"},
+		{"$2a$04$abcdefghijklmnopqrstuuyeG8laUfZvsCmc.AE6qIDYSPGM2efmK", "Hello world!"},
+		{"$2y$04$abcdefghijklmnopqrstuuyeG8laUfZvsCmc.AE6qIDYSPGM2efmK", "Hello world!"},
+		{"$2b$04$abcdefghijklmnopqrstuubyCG3zY1GIXMyxfivm.ClDiInHzxjiq", ""},
+		{"$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", "Hello world!"},
+		{"$6$rounds=1400$anotherlongsalts$5FGyu8c4BZDX4wJgs0Un26YOw2XibT5eTkHF1I1aP3QqStoJI9BHD2YPJYsAjEePVGUyBjdZxcNqMWlrrbIOC.", "Hello world!"},
+		{"$6$abc$mJP3a6FyA8uCnzRtlnNypPwjnvpi5TP9qOrInzrfDmwxUQG38PkpCPdqfTb8JQfAngapMxeim4AZ..hSdRRzD.", ""},
+		{"$6$abc$m9eXA1hs3q4.8V07cFfFu2Opq3Quc/Y6qSjHB1.el9630KaBvKBOW3xXgLJ9ixl0b28YY1QbJkABtslQBLpIw.", "a much longer password that is over sixty four bytes long to test the loops"},
+		{"$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5", "Hello world!"},
+		{"$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA", "Hello world!"},
+		{"$1$saltstri$YMyguxXMBpd2TEZ.vS/3q1", "Hello world!"},
+		{"$1$abc$WBtZ1p5VS2Owg.SRvklhs0", "a much longer password"},
+	}
+	for _, v := range vectors {
+		ok, err := VerifyPasswordHash(v.hashed, v.plaintext)
+		if err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		if !ok {
+			t.Fatalf("%s should have matched '%s'", v.hashed, v.plaintext)
+		}
+		if ok, _ := VerifyPasswordHash(v.hashed, v.plaintext+"x"); ok {
+			t.Fatalf("%s should not have matched a wrong password", v.hashed)
+		}
+	}
+
+	// bcrypt only uses the first 72 bytes of the password
+	for _, plaintext := range []string{strings.Repeat("x", 80), strings.Repeat("x", 72) + "y"} {
+		if ok, _ := VerifyPasswordHash("$2b$04$abcdefghijklmnopqrstuubzadhGtS2zEF.gu0yd0opP6cVzb.e0i", plaintext); !ok {
+			t.Fatalf("bcrypt should have ignored the password past 72 bytes")
+		}
+	}
+
+	for _, hashed := range []string{"$y$.75$abcdefghijklmnop$hash", "$7$C6..../....SodiumChloride$hash", "plain"} {
+		if _, err := VerifyPasswordHash(hashed, "secret"); err != ErrUnsupportedHash {
+			t.Fatalf("%s should have been unsupported: %v", hashed, err)
+		}
+	}
+
+	for _, hashed := range []string{"$y$j75$abc!$hash", "$y$j$salt$hash", "$2b$99$abcdefghijklmnopqrstuu", "$2b$04$short"} {
+		if _, err := VerifyPasswordHash(hashed, "secret"); err == nil || err == ErrUnsupportedHash {
+			t.Fatalf("%s should have been badly formatted: %v", hashed, err)
+		}
+	}
+}
+
+func TestShadowVerifyPassword(t *testing.T) {
+	cache := NewEtcShadowCache(false)
+	content := "alice:$1$saltstri$YMyguxXMBpd2TEZ.vS/3q1:18000:0:99999:7:::\n" +
+		"bob:!$1$saltstri$YMyguxXMBpd2TEZ.vS/3q1:18000:0:99999:7:::\n" +
+		"carol::18000:0:99999:7:::\n" +
+		"dave:$y$j75$abcdefghijklmnop$Dz4c4IbXLnyhVP3lRORmHdx4lPfPAZiTB4e2cyBTF66:18000:0:99999:7:::\n"
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if ok, err := cache.VerifyPassword("alice", "Hello world!"); !ok || err != nil {
+		t.Fatalf("alice should have verified: %v", err)
+	}
+	if ok, _ := cache.VerifyPassword("alice", "wrong"); ok {
+		t.Fatalf("alice should not have verified a wrong password")
+	}
+	if ok, err := cache.VerifyPassword("dave", "Hello world!"); !ok || err != nil {
+		t.Fatalf("dave should have verified: %v", err)
+	}
+	if ok, _ := cache.VerifyPassword("bob", "Hello world!"); ok {
+		t.Fatalf("locked bob should not have verified")
+	}
+	if ok, _ := cache.VerifyPassword("carol", ""); ok {
+		t.Fatalf("carol with no password should not have verified")
+	}
+	if _, err := cache.VerifyPassword("nosuchuser", ""); err == nil {
+		t.Fatalf("Should have failed for an unknown user")
+	}
+}
@@ -0,0 +1,26 @@
+package etcpwdparse
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// WriteCSV writes one CSV row per entry in file order with the columns username, password, uid,
+// gid, info, homedir, and shell. If includeHeader is true the first row holds the column names.
+func (e *EtcPasswdCache) WriteCSV(w io.Writer, includeHeader bool) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	cw := csv.NewWriter(w)
+	if includeHeader {
+		if err := cw.Write(entryFieldNames); err != nil {
+			return err
+		}
+	}
+	for _, entry := range e.entries {
+		if err := cw.Write(entryFieldValues(entry)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
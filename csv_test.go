@@ -0,0 +1,34 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	content := "root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000:Alice Smith,Room 1,,:/home/alice:/bin/zsh\n"
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := cache.WriteCSV(buf, true); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	expected := "username,password,uid,gid,info,homedir,shell\n" +
+		"root,x,0,0,root,/root,/bin/bash\n" +
+		"alice,x,1000,1000,\"Alice Smith,Room 1,,\",/home/alice,/bin/zsh\n"
+	if buf.String() != expected {
+		t.Fatalf("%q != %q", buf.String(), expected)
+	}
+
+	buf.Reset()
+	if err := cache.WriteCSV(buf, false); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if !strings.HasPrefix(buf.String(), "root,") {
+		t.Fatalf("header should not have been written: %q", buf.String())
+	}
+}
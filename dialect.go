@@ -0,0 +1,122 @@
+package etcpwdparse
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect selects the passwd file format that a cache reads and writes.
+type Dialect int
+
+const (
+	// DialectLinux is the standard 7 field /etc/passwd format. This is the default.
+	DialectLinux Dialect = iota
+	// DialectBSD is the 10 field master.passwd format used by FreeBSD and OpenBSD which adds the
+	// login class, password change time, and account expiry time.
+	DialectBSD
+	// DialectSolaris is the 7 field passwd format used by Solaris, illumos, and AIX. Passwd lines
+	// are parsed as for DialectLinux. Shadow lines may omit trailing fields, and the last shadow
+	// field holds the failed login count rather than being reserved.
+	DialectSolaris
+)
+
+// WithDialect sets the file format that the cache reads and writes.
+func WithDialect(dialect Dialect) Option {
+	return func(o *options) {
+		o.dialect = dialect
+	}
+}
+
+// Class function returns the BSD login class. It is only set for DialectBSD entries.
+func (e *EtcPasswdEntry) Class() string {
+	return e.class
+}
+
+// Change function returns the BSD password change time in seconds since the epoch, 0 if the
+// password never has to be changed. It is only set for DialectBSD entries.
+func (e *EtcPasswdEntry) Change() int64 {
+	return e.change
+}
+
+// Expire function returns the BSD account expiry time in seconds since the epoch, 0 if the
+// account never expires. It is only set for DialectBSD entries.
+func (e *EtcPasswdEntry) Expire() int64 {
+	return e.expire
+}
+
+// ParseBSDMasterPasswdLine is a function used to parse a 10 entry master.passwd formatted line
+// into a EtcPasswdEntry object. Errors are returned as a *ParseError.
+func ParseBSDMasterPasswdLine(line string) (EtcPasswdEntry, error) {
+	return parseBSDMasterPasswdLine(line, &options{})
+}
+
+// parseBSDMasterPasswdLine implements ParseBSDMasterPasswdLine with the id overflow policy and
+// field trimming of the given options.
+func parseBSDMasterPasswdLine(line string, o *options) (EtcPasswdEntry, error) {
+	parts := strings.Split(o.trimLine(line), ":")
+	if len(parts) != 10 {
+		return EtcPasswdEntry{}, newParseError(line, "", "Master.passwd line had wrong number of parts %d != 10", len(parts))
+	}
+	// rearrange into the standard 7 fields to reuse the normal parsing rules
+	result, err := parsePasswdLine(strings.Join([]string{parts[0], parts[1], parts[2], parts[3], parts[7], parts[8], parts[9]}, ":"), o)
+	if err != nil {
+		if pe, ok := err.(*ParseError); ok {
+			pe.RawLine = line
+		}
+		return result, err
+	}
+	result.class = o.trimField(parts[4])
+
+	times := []struct {
+		target *int64
+		name   string
+		value  string
+	}{
+		{&result.change, "change", parts[5]},
+		{&result.expire, "expire", parts[6]},
+	}
+	for _, t := range times {
+		value := strings.TrimSpace(t.value)
+		if len(value) == 0 {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return result, newParseError(line, t.name, "Master.passwd line had badly formatted %s %s: %w", t.name, value, err)
+		}
+		*t.target = n
+	}
+	return result, nil
+}
+
+// formatBSDMasterPasswdLine formats the entry as a 10 part master.passwd line.
+func formatBSDMasterPasswdLine(entry *EtcPasswdEntry) string {
+	return strings.Join([]string{
+		entry.username,
+		entry.password,
+		strconv.Itoa(entry.uid),
+		strconv.Itoa(entry.gid),
+		entry.class,
+		strconv.FormatInt(entry.change, 10),
+		strconv.FormatInt(entry.expire, 10),
+		entry.info,
+		entry.homedir,
+		entry.shell,
+	}, ":")
+}
+
+// parseLine parses an entry line in the dialect of the cache.
+func (e *EtcPasswdCache) parseLine(line string) (EtcPasswdEntry, error) {
+	if e.opts.dialect == DialectBSD {
+		return parseBSDMasterPasswdLine(line, &e.opts)
+	}
+	return parsePasswdLine(line, &e.opts)
+}
+
+// formatLine formats an entry line in the dialect of the cache.
+func (e *EtcPasswdCache) formatLine(entry *EtcPasswdEntry) string {
+	if e.opts.dialect == DialectBSD {
+		return formatBSDMasterPasswdLine(entry)
+	}
+	return formatPasswdLine(entry)
+}
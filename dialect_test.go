@@ -0,0 +1,46 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const fakeMasterPasswdContent = `# $FreeBSD$
+root:$6$salt$hash:0:0::0:0:Charlie &:/root:/bin/csh
+toor:*:0:0::0:0:Bourne-again Superuser:/root:
+alice:$6$salt$hash2:1001:1001:staff:1700000000:1800000000:Alice:/home/alice:/bin/sh
+`
+
+func TestBSDDialect(t *testing.T) {
+	cache := NewEtcPasswdCache(false, WithDialect(DialectBSD))
+	if err := cache.LoadFromReader(strings.NewReader(fakeMasterPasswdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	alice, _ := cache.LookupUserByName("alice")
+	if alice.Class() != "staff" || alice.Change() != 1700000000 || alice.Expire() != 1800000000 {
+		t.Fatalf("BSD fields were parsed incorrectly: %+v", alice)
+	}
+	if alice.Info() != "Alice" || alice.Homedir() != "/home/alice" || alice.Shell() != "/bin/sh" {
+		t.Fatalf("standard fields were parsed incorrectly: %+v", alice)
+	}
+
+	cache.AddEntry(EtcPasswdEntry{username: "bob", password: "*", uid: 1002, gid: 1002, class: "default", homedir: "/home/bob", shell: "/bin/sh"})
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	expected := fakeMasterPasswdContent + "bob:*:1002:1002:default:0:0::/home/bob:/bin/sh\n"
+	if buf.String() != expected {
+		t.Fatalf("%q != %q", buf.String(), expected)
+	}
+
+	if _, err := ParseBSDMasterPasswdLine("root:x:0:0:root:/root:/bin/sh"); err == nil {
+		t.Fatalf("Should have failed on a 7 field line")
+	}
+	if _, err := ParseBSDMasterPasswdLine("root:x:0:0::soon:0::/root:/bin/sh"); err == nil {
+		t.Fatalf("Should have failed on a bad change time")
+	}
+	if err := NewEtcPasswdCache(false).LoadFromReader(strings.NewReader(fakeMasterPasswdContent)); err == nil {
+		t.Fatalf("Should have failed to load master.passwd with the linux dialect")
+	}
+}
@@ -0,0 +1,109 @@
+package etcpwdparse
+
+// FieldChange is a single field that differs between two entries with the same username.
+type FieldChange struct {
+	// Field is one of username, password, uid, gid, info, homedir, or shell
+	Field string
+	Old   string
+	New   string
+}
+
+// ModifiedEntry is an entry present in both snapshots whose fields differ.
+type ModifiedEntry struct {
+	Old     EtcPasswdEntry
+	New     EtcPasswdEntry
+	Changes []FieldChange
+}
+
+// PasswdDiff holds the differences between two passwd snapshots.
+type PasswdDiff struct {
+	// Added holds entries only present in the second snapshot, in its file order
+	Added []EtcPasswdEntry
+	// Removed holds entries only present in the first snapshot, in its file order
+	Removed []EtcPasswdEntry
+	// Modified holds entries present in both with different fields, in the second file order
+	Modified []ModifiedEntry
+}
+
+// Empty returns true if the two snapshots had the same entries.
+func (d *PasswdDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// Diff compares two passwd snapshots and returns the entries that were added, removed, or
+// modified going from a to b. Entries are matched by username, and when a username appears more
+// than once only its first entry is compared and any later ones are ignored.
+func Diff(a, b *EtcPasswdCache) PasswdDiff {
+	return diffEntries(a.snapshotEntries(), b.snapshotEntries())
+}
+
+// diffEntries implements Diff on two entry slices.
+func diffEntries(a, b []*EtcPasswdEntry) PasswdDiff {
+	aEntries, aNames := firstEntriesByName(a)
+	bEntries, bNames := firstEntriesByName(b)
+	diff := PasswdDiff{
+		Added:    make([]EtcPasswdEntry, 0),
+		Removed:  make([]EtcPasswdEntry, 0),
+		Modified: make([]ModifiedEntry, 0),
+	}
+	for _, entry := range aEntries {
+		if _, ok := bNames[entry.username]; !ok {
+			diff.Removed = append(diff.Removed, *entry)
+		}
+	}
+	for _, entry := range bEntries {
+		old, ok := aNames[entry.username]
+		if !ok {
+			diff.Added = append(diff.Added, *entry)
+			continue
+		}
+		if changes := diffEntryFields(old, entry); len(changes) > 0 {
+			diff.Modified = append(diff.Modified, ModifiedEntry{Old: *old, New: *entry, Changes: changes})
+		}
+	}
+	return diff
+}
+
+// firstEntriesByName returns the first entry for each username in file order along with a map
+// of the same entries keyed by username.
+func firstEntriesByName(entries []*EtcPasswdEntry) ([]*EtcPasswdEntry, map[string]*EtcPasswdEntry) {
+	ordered := make([]*EtcPasswdEntry, 0, len(entries))
+	names := make(map[string]*EtcPasswdEntry, len(entries))
+	for _, entry := range entries {
+		if _, ok := names[entry.username]; ok {
+			continue
+		}
+		names[entry.username] = entry
+		ordered = append(ordered, entry)
+	}
+	return ordered, names
+}
+
+// diffEntryFields returns the fields that differ between the two entries in field order.
+func diffEntryFields(before, after *EtcPasswdEntry) []FieldChange {
+	oldValues, newValues := entryFieldValues(before), entryFieldValues(after)
+	changes := make([]FieldChange, 0)
+	for i, name := range entryFieldNames {
+		if oldValues[i] != newValues[i] {
+			changes = append(changes, FieldChange{Field: name, Old: oldValues[i], New: newValues[i]})
+		}
+	}
+	return changes
+}
+
+// WithReloadDiff makes each reload keep the differences it made for LastReloadDiff. Without it
+// the differences are only worked out when there are subscribers, see Subscribe.
+func WithReloadDiff() Option {
+	return func(o *options) {
+		o.reloadDiff = true
+	}
+}
+
+// LastReloadDiff returns the differences between the content before and after the most recent
+// reload, so account changes can be logged without keeping a copy of the old content. It is
+// only kept with WithReloadDiff, and is empty after the first load.
+func (e *EtcPasswdCache) LastReloadDiff() PasswdDiff {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.lastReloadDiff
+}
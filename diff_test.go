@@ -0,0 +1,77 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	golden := NewEtcPasswdCache(false)
+	if err := golden.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nbin:x:1:1:bin:/bin:/sbin/nologin\nalice:x:1000:1000::/home/alice:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	running := NewEtcPasswdCache(false)
+	if err := running.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:100::/home/alice:/bin/zsh\nbob:x:1001:1001::/home/bob:/bin/sh\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	d := Diff(golden, running)
+	if d.Empty() {
+		t.Fatalf("diff should not be empty")
+	}
+	if len(d.Added) != 1 || d.Added[0].Username() != "bob" {
+		t.Fatalf("unexpected added %v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Username() != "bin" {
+		t.Fatalf("unexpected removed %v", d.Removed)
+	}
+	if len(d.Modified) != 1 || d.Modified[0].New.Username() != "alice" {
+		t.Fatalf("unexpected modified %v", d.Modified)
+	}
+	changes := d.Modified[0].Changes
+	if len(changes) != 2 {
+		t.Fatalf("%d != 2", len(changes))
+	}
+	if changes[0] != (FieldChange{Field: "gid", Old: "1000", New: "100"}) {
+		t.Fatalf("unexpected change %v", changes[0])
+	}
+	if changes[1] != (FieldChange{Field: "shell", Old: "/bin/bash", New: "/bin/zsh"}) {
+		t.Fatalf("unexpected change %v", changes[1])
+	}
+
+	if d := Diff(golden, golden); !d.Empty() {
+		t.Fatalf("diff against itself should be empty: %v", d)
+	}
+}
+
+func TestLastReloadDiff(t *testing.T) {
+	cache := NewEtcPasswdCache(false, WithReloadDiff())
+	if d := cache.LastReloadDiff(); !d.Empty() {
+		t.Fatalf("diff should be empty before loading: %v", d)
+	}
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if d := cache.LastReloadDiff(); !d.Empty() {
+		t.Fatalf("the first load should not keep a diff: %v", d)
+	}
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000:Alice:/home/alice:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	d := cache.LastReloadDiff()
+	if len(d.Added) != 0 || len(d.Removed) != 0 || len(d.Modified) != 1 {
+		t.Fatalf("unexpected diff %v", d)
+	}
+	if d.Modified[0].Changes[0] != (FieldChange{Field: "info", Old: "", New: "Alice"}) {
+		t.Fatalf("unexpected change %v", d.Modified[0].Changes[0])
+	}
+}
+
+func TestLastReloadDiffIsOptIn(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\n"))
+	cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\n"))
+	if d := cache.LastReloadDiff(); !d.Empty() {
+		t.Fatalf("the diff should not be kept without WithReloadDiff: %v", d)
+	}
+}
@@ -0,0 +1,61 @@
+package etcpwdparse
+
+import (
+	"bytes"
+)
+
+// DryRunResult describes what a set of changes would do to the passwd file.
+type DryRunResult struct {
+	// Content is the file content that would be written
+	Content []byte
+	// Diff is a unified diff from the current content to Content, empty if nothing changes
+	Diff string
+	// Changes lists the entries that would be added, removed, and modified
+	Changes PasswdDiff
+	// Plan holds the same changes as Changes as a list of operations for approval workflows
+	Plan ChangePlan
+}
+
+// DryRun calls fn with a copy of the cache and reports what its changes would do, without
+// changing the cache or writing any file. Any of the mutating methods may be called on the
+// copy, such as AddUser, ModifyUser, DeleteUser, or Reconcile, and saving it with Save or
+// WriteToPath, including through a WriteBack option, does nothing. If fn fails its error is returned with no result.
+//
+// The diff compares the content as it would be written by WriteTo, so it shows the pending
+// changes rather than any difference between the cache and the file on disk.
+func (e *EtcPasswdCache) DryRun(fn func(c *EtcPasswdCache) error) (*DryRunResult, error) {
+	e.mu.RLock()
+	work := e.workingCopy()
+	// the current content is kept aside so that the diff is against what fn started from
+	current := e.newLoadTarget("")
+	current.lines, current.entries = e.lines, e.entries
+	name := e.path
+	e.mu.RUnlock()
+	work.opts.dryRun = true
+	// the copy must not be replaced by a reload of the file part way through
+	work.opts.ttl, work.opts.statCheck = 0, false
+
+	if err := fn(work); err != nil {
+		return nil, err
+	}
+	oldContent, newContent := new(bytes.Buffer), new(bytes.Buffer)
+	if _, err := current.WriteTo(oldContent); err != nil {
+		return nil, err
+	}
+	if _, err := work.WriteTo(newContent); err != nil {
+		return nil, err
+	}
+
+	changes := diffEntries(current.entries, work.entries)
+	plan := changes.Plan()
+	plan.Path = name
+	if name == "" {
+		name = "passwd"
+	}
+	return &DryRunResult{
+		Content: newContent.Bytes(),
+		Diff:    unifiedDiff(name, name+".new", oldContent.String(), newContent.String()),
+		Changes: changes,
+		Plan:    plan,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwd")
+	if err := os.WriteFile(path, []byte(fakeReconcileContent), 0644); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromPath(path); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	result, err := cache.DryRun(func(c *EtcPasswdCache) error {
+		if _, err := c.AddUser(UserSpec{Username: "carol", Info: "Carol", WriteBack: true}); err != nil {
+			return err
+		}
+		_, err := c.ModifyUser("daemon", func(b *EtcPasswdEntryBuilder) { b.Shell("/usr/sbin/nologin") })
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	expected := fmt.Sprintf(`--- %[1]s
++++ %[1]s.new
+@@ -1,5 +1,6 @@
+ # managed accounts
+ root:x:0:0:root:/root:/bin/bash
+-daemon:x:2:2:daemon:/sbin:/sbin/nologin
++daemon:x:2:2:daemon:/sbin:/usr/sbin/nologin
+ alice:x:1000:1000:Alice:/home/alice:/bin/bash
+ bob:x:1001:1001:Bob:/home/bob:/bin/bash
++carol:x:1002:1002:Carol:/home/carol:/bin/sh
+`, path)
+	if result.Diff != expected {
+		t.Fatalf("%q != %q", result.Diff, expected)
+	}
+	if !strings.HasSuffix(string(result.Content), "carol:x:1002:1002:Carol:/home/carol:/bin/sh\n") {
+		t.Fatalf("unexpected content %q", result.Content)
+	}
+	if len(result.Changes.Added) != 1 || len(result.Changes.Modified) != 1 {
+		t.Fatalf("unexpected changes %+v", result.Changes)
+	}
+
+	// neither the cache nor the file were touched
+	if _, ok := cache.LookupUserByName("carol"); ok {
+		t.Fatal("the cache should not have been changed")
+	}
+	if entry, _ := cache.LookupUserByName("daemon"); entry.Shell() != "/sbin/nologin" {
+		t.Fatal("the cache should not have been changed")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != fakeReconcileContent {
+		t.Fatalf("the file should not have been written: %q", data)
+	}
+
+	// applying the same changes for real produces the previewed content
+	if _, err := cache.AddUser(UserSpec{Username: "carol", Info: "Carol"}); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	cache.ModifyUser("daemon", func(b *EtcPasswdEntryBuilder) { b.Shell("/usr/sbin/nologin") })
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	if !bytes.Equal(buf.Bytes(), result.Content) {
+		t.Fatalf("%q != %q", buf.String(), result.Content)
+	}
+}
+
+func TestDryRunNoChanges(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakeReconcileContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	_, err := cache.DryRun(func(c *EtcPasswdCache) error {
+		_, err := c.Reconcile([]UserSpec{{Username: "alice"}}, ReconcileOptions{WriteBack: true})
+		return err
+	})
+	if err == nil {
+		t.Fatal("Should have failed to save a cache without a path")
+	}
+	result, err := cache.DryRun(func(c *EtcPasswdCache) error {
+		_, err := c.Reconcile([]UserSpec{{Username: "alice"}}, ReconcileOptions{})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if result.Diff != "" || !result.Changes.Empty() {
+		t.Fatalf("expected no changes but got %q", result.Diff)
+	}
+	if string(result.Content) != fakeReconcileContent {
+		t.Fatalf("%q != %q", result.Content, fakeReconcileContent)
+	}
+}
+
+func TestDryRunError(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakeReconcileContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	result, err := cache.DryRun(func(c *EtcPasswdCache) error {
+		return c.DeleteUser("nobody")
+	})
+	if err == nil || result != nil {
+		t.Fatal("Should have failed")
+	}
+}
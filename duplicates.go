@@ -0,0 +1,132 @@
+package etcpwdparse
+
+import (
+	"strconv"
+)
+
+// DuplicatePolicy controls what happens when an entry is added or loaded with the same username
+// or user id as an existing entry. Whatever the policy, lines of a loaded file are never removed
+// from the file when it is written back; the policy only decides which entries the cache holds.
+type DuplicatePolicy int
+
+const (
+	// DuplicateKeepBoth keeps both entries. Lookups return the entry added last. This is the
+	// default and matches the historic behaviour of AddEntry.
+	DuplicateKeepBoth DuplicatePolicy = iota
+	// DuplicateFirstWins keeps the existing entry and discards the new one, matching the first
+	// match semantics of getpwnam.
+	DuplicateFirstWins
+	// DuplicateLastWins discards the existing entry in favour of the new one.
+	DuplicateLastWins
+	// DuplicateError rejects the new entry. Loading fails and AddEntry returns an error.
+	DuplicateError
+)
+
+// WithDuplicatePolicy sets how the cache handles entries with duplicate usernames or user ids.
+func WithDuplicatePolicy(policy DuplicatePolicy) Option {
+	return func(o *options) {
+		o.duplicatePolicy = policy
+	}
+}
+
+// Duplicate describes a collision between two entries that was detected while adding or loading.
+type Duplicate struct {
+	// Field is "username" or "uid"
+	Field string
+	// Existing is the entry that was already in the cache
+	Existing EtcPasswdEntry
+	// Added is the entry that collided with it
+	Added EtcPasswdEntry
+}
+
+// Duplicates returns every collision detected since the cache was last loaded, in the order
+// they were found. Collisions are reported whatever the duplicate policy is.
+func (e *EtcPasswdCache) Duplicates() []Duplicate {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	results := make([]Duplicate, len(e.duplicates))
+	copy(results, e.duplicates)
+	return results
+}
+
+// addWithPolicy adds the entry while applying the duplicate policy. The caller must hold the
+// write lock or own the cache.
+func (e *EtcPasswdCache) addWithPolicy(entry EtcPasswdEntry, raw string) error {
+	collisions := make([]Duplicate, 0, 2)
+	if existing, ok := e.namemap[e.opts.nameKey(entry.username)]; ok {
+		collisions = append(collisions, Duplicate{Field: "username", Existing: *existing, Added: entry})
+	}
+	if existing, ok := e.idmap[entry.uid]; ok {
+		collisions = append(collisions, Duplicate{Field: "uid", Existing: *existing, Added: entry})
+	}
+	if len(collisions) == 0 {
+		e.addEntryLine(&entry, raw, e.loadingLine)
+		return nil
+	}
+	e.duplicates = append(e.duplicates, collisions...)
+
+	switch e.opts.duplicatePolicy {
+	case DuplicateError:
+		c := collisions[0]
+		value := c.Added.username
+		if c.Field == "uid" {
+			value = strconv.Itoa(c.Added.uid)
+		}
+		return &ParseError{RawLine: raw, Field: c.Field, Err: sentinelf(ErrDuplicateUser, "Passwd entry had duplicate %s %s", c.Field, value)}
+	case DuplicateFirstWins:
+		e.addRawLine(raw)
+	case DuplicateLastWins:
+		e.dropEntries(func(existing *EtcPasswdEntry) bool {
+			return e.opts.nameKey(existing.username) == e.opts.nameKey(entry.username) || existing.uid == entry.uid
+		})
+		e.addEntryLine(&entry, raw, e.loadingLine)
+	default:
+		e.addEntryLine(&entry, raw, e.loadingLine)
+	}
+	return nil
+}
+
+// addUnparsedWithPolicy is addWithPolicy for an entry given by the caller rather than read from a
+// line. No line was parsed, so a rejected duplicate is returned as the plain ErrDuplicateUser
+// error instead of a *ParseError. The caller must hold the write lock or own the cache.
+func (e *EtcPasswdCache) addUnparsedWithPolicy(entry EtcPasswdEntry) error {
+	err := e.addWithPolicy(entry, "")
+	if pe, ok := err.(*ParseError); ok {
+		return pe.Err
+	}
+	return err
+}
+
+// addRawLine records a line that does not hold an entry so that it is written back as is.
+func (e *EtcPasswdCache) addRawLine(raw string) {
+	if len(raw) > 0 {
+		e.lines = append(e.lines, passwdLine{raw: raw, entry: -1})
+	}
+}
+
+// dropEntries rebuilds the content without the entries matching the predicate. Lines those
+// entries were loaded from are kept as raw lines. This is O(n) but collisions are rare.
+func (e *EtcPasswdCache) dropEntries(drop func(*EtcPasswdEntry) bool) {
+	e.rebuildEntries(e.entries, drop, true)
+}
+
+// rebuildEntries rebuilds the content from the current lines using the given entries slice,
+// leaving out the entries matching the predicate. If keepLines is true the lines of dropped
+// entries are kept as raw lines, otherwise they are removed. The caller must hold the write lock.
+func (e *EtcPasswdCache) rebuildEntries(entries []*EtcPasswdEntry, drop func(*EtcPasswdEntry) bool, keepLines bool) {
+	lines, duplicates, compat := e.lines, e.duplicates, e.compat
+	e.reset()
+	e.duplicates, e.compat = duplicates, compat
+	for _, l := range lines {
+		if l.entry < 0 {
+			e.lines = append(e.lines, l)
+		} else if drop(entries[l.entry]) {
+			if keepLines {
+				e.addRawLine(l.raw)
+			}
+		} else {
+			e.addEntryLine(entries[l.entry], l.raw, l.number)
+		}
+	}
+	e.sortUidIndex()
+}
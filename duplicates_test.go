@@ -0,0 +1,106 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const fakeDuplicateContent = `root:x:0:0:root:/root:/bin/bash
+alice:x:1000:1000::/home/alice:/bin/bash
+toor:x:0:0::/root:/bin/sh
+alice:x:1001:1001::/home/alice2:/bin/zsh
+`
+
+func TestDuplicatePolicies(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakeDuplicateContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(cache.ListEntries()) != 4 {
+		t.Fatalf("%d != 4", len(cache.ListEntries()))
+	}
+	if entry, _ := cache.LookupUserByUid(0); entry.Username() != "toor" {
+		t.Fatalf("%s != toor", entry.Username())
+	}
+	dups := cache.Duplicates()
+	if len(dups) != 2 || dups[0].Field != "uid" || dups[1].Field != "username" {
+		t.Fatalf("unexpected duplicates %+v", dups)
+	}
+	if dups[1].Existing.Uid() != 1000 || dups[1].Added.Uid() != 1001 {
+		t.Fatalf("unexpected duplicate entries %+v", dups[1])
+	}
+
+	cache = NewEtcPasswdCache(false, WithDuplicatePolicy(DuplicateFirstWins))
+	if err := cache.LoadFromReader(strings.NewReader(fakeDuplicateContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(cache.ListEntries()) != 2 {
+		t.Fatalf("%d != 2", len(cache.ListEntries()))
+	}
+	if entry, _ := cache.LookupUserByName("alice"); entry.Uid() != 1000 {
+		t.Fatalf("%d != 1000", entry.Uid())
+	}
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	if buf.String() != fakeDuplicateContent {
+		t.Fatalf("discarded lines should be written back unchanged: %q", buf.String())
+	}
+
+	cache = NewEtcPasswdCache(false, WithDuplicatePolicy(DuplicateLastWins))
+	if err := cache.LoadFromReader(strings.NewReader(fakeDuplicateContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(cache.ListEntries()) != 2 {
+		t.Fatalf("%d != 2", len(cache.ListEntries()))
+	}
+	if entry, _ := cache.LookupUserByName("alice"); entry.Uid() != 1001 {
+		t.Fatalf("%d != 1001", entry.Uid())
+	}
+	if _, ok := cache.LookupUserByName("root"); ok {
+		t.Fatalf("root should have been replaced by toor")
+	}
+	if len(cache.EntriesInUidRange(0, 2000)) != 2 {
+		t.Fatalf("uid index should only have the winners")
+	}
+
+	cache = NewEtcPasswdCache(false, WithDuplicatePolicy(DuplicateError))
+	err := cache.LoadFromReader(strings.NewReader(fakeDuplicateContent))
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.LineNumber != 3 || pe.Field != "uid" {
+		t.Fatalf("Should have failed on line 3: %v", err)
+	}
+	cache.AddEntry(EtcPasswdEntry{username: "bob", uid: 2000})
+	err = cache.AddEntry(EtcPasswdEntry{username: "bob", uid: 2001})
+	if !errors.Is(err, ErrDuplicateUser) || errors.Is(err, ErrBadLine) || errors.As(err, &pe) {
+		t.Fatalf("expected a plain ErrDuplicateUser but got %v", err)
+	}
+	if len(cache.ListEntries()) != 1 {
+		t.Fatalf("%d != 1", len(cache.ListEntries()))
+	}
+}
+
+func TestDuplicateLastWinsKeepsUidIndexSorted(t *testing.T) {
+	cache := NewEtcPasswdCache(false, WithDuplicatePolicy(DuplicateLastWins))
+	content := "a:x:10:10::/home/a:/bin/sh\nb:x:20:20::/home/b:/bin/sh\nc:x:30:30::/home/c:/bin/sh\n"
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := cache.AddEntry(EtcPasswdEntry{username: "c", uid: 5, gid: 5}); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	names := ""
+	for _, entry := range cache.EntriesInUidRange(0, 100) {
+		names += entry.Username() + ","
+	}
+	if names != "c,a,b," {
+		t.Fatalf("unexpected order %s", names)
+	}
+	if r := cache.EntriesInUidRange(0, 6); len(r) != 1 || r[0].Username() != "c" {
+		t.Fatalf("c should be in the range 0-6: %v", r)
+	}
+	if uid, err := cache.NextFreeUid(5, 100); err != nil || uid != 6 {
+		t.Fatalf("%d != 6 (%v)", uid, err)
+	}
+}
@@ -0,0 +1,138 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// BackupSuffix is appended to the path of a database to name the backup written by
+// EditTransaction.Commit, giving /etc/passwd- like shadow-utils.
+const BackupSuffix = "-"
+
+// ValidationError is returned by EditTransaction.Commit when the edited content has problems.
+type ValidationError struct {
+	Findings []Finding
+}
+
+// Error describes the first finding and how many others there are
+func (v *ValidationError) Error() string {
+	if len(v.Findings) == 1 {
+		return fmt.Sprintf("Edited passwd file failed validation: %s", v.Findings[0])
+	}
+	return fmt.Sprintf("Edited passwd file failed validation: %s (and %d more)", v.Findings[0], len(v.Findings)-1)
+}
+
+// EditTransaction is a vipw-style edit of a passwd file. The file is locked and loaded when the
+// transaction begins, the caller mutates the cache, and Commit only installs the result if it
+// passes validation. Exactly one of Commit or Abort must be called to release the lock.
+type EditTransaction struct {
+	path     string
+	lock     *FileLock
+	original []byte
+	info     os.FileInfo
+	cache    *EtcPasswdCache
+	// baseline holds the findings of the content before the edit
+	baseline []Finding
+	done     bool
+}
+
+// BeginEdit locks the passwd file at path with LockFile, keeps a copy of its content, and loads
+// it into a cache for editing. The options are applied to the cache, and writes made through
+// the cache itself are not locked since the transaction already holds the lock.
+func BeginEdit(path string, opts ...Option) (*EditTransaction, error) {
+	// copy the options so that the caller's slice is never appended to
+	cache := NewEtcPasswdCache(false, append(append([]Option(nil), opts...), WithoutLocking())...)
+	lock, err := LockFile(path, cache.opts.lockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	original, err := ioutil.ReadFile(path)
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	if err := cache.load(bytes.NewReader(original), path); err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	return &EditTransaction{path: path, lock: lock, original: original, info: info, cache: cache, baseline: cache.Validate()}, nil
+}
+
+// Cache returns the cache holding the content being edited
+func (t *EditTransaction) Cache() *EtcPasswdCache {
+	return t.cache
+}
+
+// Commit checks the edited content and installs it. The content must parse again without errors
+// and Validate must report no findings that the file did not already have before the edit,
+// otherwise a ValidationError is returned and the file is left untouched. Before the new content is installed the original is written to path with
+// BackupSuffix appended. The lock is released whether or not the commit succeeds.
+func (t *EditTransaction) Commit() error {
+	if t.done {
+		return fmt.Errorf("Edit transaction was already finished")
+	}
+	defer t.finish()
+
+	buf := new(bytes.Buffer)
+	if _, err := t.cache.WriteTo(buf); err != nil {
+		return err
+	}
+	// parse with every option the transaction was started with, such as WithLenientLines, so
+	// that lines kept as they were loaded are read back the same way
+	check, err := t.cache.parse(bytes.NewReader(buf.Bytes()), "")
+	if err != nil {
+		return err
+	}
+	if findings := introducedFindings(t.baseline, check.Validate()); len(findings) > 0 {
+		return &ValidationError{Findings: findings}
+	}
+
+	if err := writeFileAtomicLike(t.path+BackupSuffix, t.original, 0644, t.info); err != nil {
+		return err
+	}
+	return writeFileAtomicLike(t.path, buf.Bytes(), 0644, t.info)
+}
+
+// introducedFindings returns the findings in after that are not in before. Findings are matched
+// by kind and username since line numbers move when entries are added or removed.
+func introducedFindings(before, after []Finding) []Finding {
+	type key struct {
+		kind     FindingKind
+		username string
+	}
+	existing := make(map[key]int)
+	for _, f := range before {
+		existing[key{f.Kind, f.Username}]++
+	}
+	results := make([]Finding, 0)
+	for _, f := range after {
+		k := key{f.Kind, f.Username}
+		if existing[k] > 0 {
+			existing[k]--
+			continue
+		}
+		results = append(results, f)
+	}
+	return results
+}
+
+// Abort releases the lock without writing anything
+func (t *EditTransaction) Abort() error {
+	if t.done {
+		return nil
+	}
+	return t.finish()
+}
+
+// finish marks the transaction as done and releases the lock
+func (t *EditTransaction) finish() error {
+	t.done = true
+	return t.lock.Unlock()
+}
@@ -0,0 +1,133 @@
+package etcpwdparse
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestEditTransaction(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	original := "# users\nroot:x:0:0:root:/root:/bin/bash\n"
+	ioutil.WriteFile(pwFile, []byte(original), 0644)
+
+	tx, err := BeginEdit(pwFile)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, err := os.Stat(pwFile + ".lock"); err != nil {
+		t.Fatalf("file should be locked during the edit: %s", err)
+	}
+	if _, err := tx.Cache().AddUser(UserSpec{Username: "alice"}); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, err := os.Stat(pwFile + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("lock should have been released")
+	}
+	content, _ := ioutil.ReadFile(pwFile)
+	if string(content) != original+"alice:x:1000:1000::/home/alice:/bin/sh\n" {
+		t.Fatalf("unexpected content %q", string(content))
+	}
+	backup, _ := ioutil.ReadFile(pwFile + BackupSuffix)
+	if string(backup) != original {
+		t.Fatalf("unexpected backup %q", string(backup))
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatalf("Should have failed to commit twice")
+	}
+}
+
+func TestEditTransactionRejectsInvalid(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	original := "root:x:0:0:root:/root:/bin/bash\n"
+	ioutil.WriteFile(pwFile, []byte(original), 0644)
+
+	tx, err := BeginEdit(pwFile)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	tx.Cache().AddEntry(EtcPasswdEntry{username: "toor", uid: 0, homedir: "/root", shell: "/bin/bash"})
+	err = tx.Commit()
+	if verr, ok := err.(*ValidationError); !ok || verr.Findings[0].Kind != FindingDuplicateUid {
+		t.Fatalf("expected a duplicate uid validation error, got %v", err)
+	}
+	content, _ := ioutil.ReadFile(pwFile)
+	if string(content) != original {
+		t.Fatalf("file should not have changed: %q", string(content))
+	}
+	if _, err := os.Stat(pwFile + BackupSuffix); !os.IsNotExist(err) {
+		t.Fatalf("no backup should have been written")
+	}
+
+	tx, err = BeginEdit(pwFile)
+	if err != nil {
+		t.Fatalf("lock should have been released: %s", err)
+	}
+	if err := tx.Abort(); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+}
+
+func TestEditTransactionLenient(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	original := "root:x:0:0:root:/root:/bin/bash\nlegacy:x:500:500::/home/legacy:/bin/sh::\n"
+	ioutil.WriteFile(pwFile, []byte(original), 0644)
+
+	tx, err := BeginEdit(pwFile, WithLenientLines())
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, err := tx.Cache().AddUser(UserSpec{Username: "alice"}); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	content, _ := ioutil.ReadFile(pwFile)
+	if string(content) != original+"alice:x:1000:1000::/home/alice:/bin/sh\n" {
+		t.Fatalf("unexpected content %q", string(content))
+	}
+}
+
+func TestEditTransactionKeepsExistingFindings(t *testing.T) {
+	pwFile := path.Join(t.TempDir(), "passwd")
+	original := "root:x:0:0:root:/root:/bin/bash\nlegacy:x:500:500::legacy:/bin/sh\n"
+	ioutil.WriteFile(pwFile, []byte(original), 0644)
+
+	opts := make([]Option, 0, 1)
+	tx, err := BeginEdit(pwFile, opts...)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if opts[:1][0] != nil {
+		t.Fatalf("the spare capacity of the caller's options should not have been written to")
+	}
+
+	// the relative home directory was already there, so it does not block the commit
+	if _, err := tx.Cache().AddUser(UserSpec{Username: "alice"}); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	tx, err = BeginEdit(pwFile)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	tx.Cache().AddEntry(EtcPasswdEntry{username: "bob", uid: 1001, gid: 1001, homedir: "bob", shell: "/bin/sh"})
+	err = tx.Commit()
+	if verr, ok := err.(*ValidationError); !ok || len(verr.Findings) != 1 || verr.Findings[0].Username != "bob" {
+		t.Fatalf("expected a finding for bob only, got %v", err)
+	}
+}
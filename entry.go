@@ -0,0 +1,176 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// entryFieldNames are the names of the 7 entry fields in file order, used as keys and headers
+// by the encoders.
+var entryFieldNames = []string{"username", "password", "uid", "gid", "info", "homedir", "shell"}
+
+// entryFieldValues returns the 7 entry fields as strings in the same order as entryFieldNames.
+func entryFieldValues(entry *EtcPasswdEntry) []string {
+	return []string{
+		entry.username,
+		entry.password,
+		strconv.Itoa(entry.uid),
+		strconv.Itoa(entry.gid),
+		entry.info,
+		entry.homedir,
+		entry.shell,
+	}
+}
+
+// NewEtcPasswdEntry returns a validated entry that is ready to add to a cache. The username must
+// not be empty, the ids must not be negative, and no field may contain a ':' or a line break since
+// these would corrupt the file when written.
+func NewEtcPasswdEntry(username, password string, uid, gid int, info, homedir, shell string) (EtcPasswdEntry, error) {
+	entry := EtcPasswdEntry{
+		username: username,
+		password: password,
+		uid:      uid,
+		gid:      gid,
+		info:     info,
+		homedir:  homedir,
+		shell:    shell,
+	}
+	if err := validateEntry(&entry); err != nil {
+		return EtcPasswdEntry{}, err
+	}
+	return entry, nil
+}
+
+// validateEntry checks that the entry can be safely written as a passwd line.
+func validateEntry(entry *EtcPasswdEntry) error {
+	if err := validateFields(entry); err != nil {
+		return err
+	}
+	if entry.uid < 0 {
+		return fmt.Errorf("Passwd entry had negative uid %d", entry.uid)
+	}
+	if entry.gid < 0 {
+		return fmt.Errorf("Passwd entry had negative gid %d", entry.gid)
+	}
+	return nil
+}
+
+// validateFields checks the text fields of the entry, leaving out the id checks of
+// validateEntry for entries such as the legacy negative nobody ids that the parser accepts.
+func validateFields(entry *EtcPasswdEntry) error {
+	if len(entry.username) == 0 {
+		return fmt.Errorf("Passwd entry must have a username")
+	}
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"username", entry.username},
+		{"password", entry.password},
+		{"info", entry.info},
+		{"homedir", entry.homedir},
+		{"shell", entry.shell},
+		{"class", entry.class},
+	}
+	for _, f := range fields {
+		if strings.ContainsAny(f.value, ":\n\r") {
+			return fmt.Errorf("Passwd entry %s '%s' contains a ':' or line break", f.name, f.value)
+		}
+	}
+	return nil
+}
+
+// EtcPasswdEntryBuilder builds an entry field by field. The password defaults to "x" which
+// means that the real password hash lives in the shadow file.
+type EtcPasswdEntryBuilder struct {
+	entry EtcPasswdEntry
+}
+
+// NewEtcPasswdEntryBuilder returns a builder for an entry with the given username
+func NewEtcPasswdEntryBuilder(username string) *EtcPasswdEntryBuilder {
+	return &EtcPasswdEntryBuilder{
+		entry: EtcPasswdEntry{username: username, password: "x"},
+	}
+}
+
+// NewEtcPasswdEntryBuilderFrom returns a builder initialised with the fields of an existing entry
+func NewEtcPasswdEntryBuilderFrom(entry EtcPasswdEntry) *EtcPasswdEntryBuilder {
+	return &EtcPasswdEntryBuilder{entry: entry}
+}
+
+// Username sets the username
+func (b *EtcPasswdEntryBuilder) Username(username string) *EtcPasswdEntryBuilder {
+	b.entry.username = username
+	return b
+}
+
+// Password sets the password field
+func (b *EtcPasswdEntryBuilder) Password(password string) *EtcPasswdEntryBuilder {
+	b.entry.password = password
+	return b
+}
+
+// Uid sets the user id
+func (b *EtcPasswdEntryBuilder) Uid(uid int) *EtcPasswdEntryBuilder {
+	b.entry.uid = uid
+	return b
+}
+
+// Gid sets the primary group id
+func (b *EtcPasswdEntryBuilder) Gid(gid int) *EtcPasswdEntryBuilder {
+	b.entry.gid = gid
+	return b
+}
+
+// Info sets the info (GECOS) field
+func (b *EtcPasswdEntryBuilder) Info(info string) *EtcPasswdEntryBuilder {
+	b.entry.info = info
+	return b
+}
+
+// Homedir sets the home directory
+func (b *EtcPasswdEntryBuilder) Homedir(homedir string) *EtcPasswdEntryBuilder {
+	b.entry.homedir = homedir
+	return b
+}
+
+// Shell sets the login shell
+func (b *EtcPasswdEntryBuilder) Shell(shell string) *EtcPasswdEntryBuilder {
+	b.entry.shell = shell
+	return b
+}
+
+// Build validates the fields and returns the entry
+func (b *EtcPasswdEntryBuilder) Build() (EtcPasswdEntry, error) {
+	if err := validateEntry(&b.entry); err != nil {
+		return EtcPasswdEntry{}, err
+	}
+	return b.entry, nil
+}
+
+// Clone returns a copy of the entry that can be changed or kept without affecting the cache.
+func (e *EtcPasswdEntry) Clone() *EtcPasswdEntry {
+	clone := *e
+	return &clone
+}
+
+// Equal returns true if both entries have the same fields, including the BSD master.passwd
+// fields. Two nil entries are equal.
+func (e *EtcPasswdEntry) Equal(other *EtcPasswdEntry) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+	return *e == *other
+}
+
+// EqualIgnoringPassword is like Equal but ignores the password field, for example to detect
+// changes between a file and a copy with its hashes redacted.
+func (e *EtcPasswdEntry) EqualIgnoringPassword(other *EtcPasswdEntry) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+	a, b := *e, *other
+	a.password, b.password = "", ""
+	return a == b
+}
@@ -0,0 +1,79 @@
+package etcpwdparse
+
+import (
+	"testing"
+)
+
+func TestNewEtcPasswdEntry(t *testing.T) {
+	entry, err := NewEtcPasswdEntry("alice", "x", 1000, 1000, "Alice", "/home/alice", "/bin/bash")
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if formatPasswdLine(&entry) != "alice:x:1000:1000:Alice:/home/alice:/bin/bash" {
+		t.Fatalf("unexpected entry %s", formatPasswdLine(&entry))
+	}
+
+	if _, err := NewEtcPasswdEntry("", "x", 1000, 1000, "", "/", "/bin/sh"); err == nil {
+		t.Fatalf("Should have failed on an empty username")
+	}
+	if _, err := NewEtcPasswdEntry("bob", "x", -1, 1000, "", "/", "/bin/sh"); err == nil {
+		t.Fatalf("Should have failed on a negative uid")
+	}
+	if _, err := NewEtcPasswdEntry("bob", "x", 1000, 1000, "Bob:Smith", "/", "/bin/sh"); err == nil {
+		t.Fatalf("Should have failed on a colon in the info field")
+	}
+}
+
+func TestEtcPasswdEntryBuilder(t *testing.T) {
+	entry, err := NewEtcPasswdEntryBuilder("carol").Uid(1001).Gid(100).Homedir("/home/carol").Shell("/bin/zsh").Build()
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if formatPasswdLine(&entry) != "carol:x:1001:100::/home/carol:/bin/zsh" {
+		t.Fatalf("unexpected entry %s", formatPasswdLine(&entry))
+	}
+
+	cache := NewEtcPasswdCache(false)
+	cache.AddEntry(entry)
+	if uid, _ := cache.UidForUsername("carol"); uid != 1001 {
+		t.Fatalf("%d != 1001", uid)
+	}
+
+	if _, err := NewEtcPasswdEntryBuilder("dave").Shell("/bin/sh\n").Build(); err == nil {
+		t.Fatalf("Should have failed on a line break in the shell")
+	}
+}
+
+func TestEntryCloneAndEqual(t *testing.T) {
+	entry, err := NewEtcPasswdEntry("alice", "$6$salt$hash", 1000, 1000, "Alice", "/home/alice", "/bin/bash")
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	clone := entry.Clone()
+	if clone == &entry || !clone.Equal(&entry) {
+		t.Fatalf("clone should be an equal copy")
+	}
+	clone.shell = "/bin/zsh"
+	if entry.Shell() != "/bin/bash" || clone.Equal(&entry) {
+		t.Fatalf("changing the clone should not change the entry")
+	}
+
+	redacted := entry.Clone()
+	redacted.password = "x"
+	if redacted.Equal(&entry) || !redacted.EqualIgnoringPassword(&entry) {
+		t.Fatalf("only the password should differ")
+	}
+	if clone.EqualIgnoringPassword(&entry) {
+		t.Fatalf("the shell should differ")
+	}
+	bsd := entry.Clone()
+	bsd.class = "staff"
+	if bsd.Equal(&entry) {
+		t.Fatalf("the login class should be compared")
+	}
+
+	var missing *EtcPasswdEntry
+	if !missing.Equal(nil) || missing.Equal(&entry) || entry.Equal(nil) || entry.EqualIgnoringPassword(nil) {
+		t.Fatalf("nil entries should only equal each other")
+	}
+}
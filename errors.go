@@ -0,0 +1,122 @@
+package etcpwdparse
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors that the errors returned by this package can be matched against with errors.Is.
+var (
+	// ErrUserNotFound is matched by errors for a username or uid that has no entry
+	ErrUserNotFound = errors.New("No such user")
+	// ErrGroupNotFound is matched by errors for a group name or gid that has no entry
+	ErrGroupNotFound = errors.New("No such group")
+	// ErrDuplicateUser is matched by errors for a username or uid that is already taken,
+	// including duplicates rejected while loading with DuplicateError
+	ErrDuplicateUser = errors.New("Duplicate user")
+	// ErrDuplicateGroup is matched by errors for a group name or gid that is already taken
+	ErrDuplicateGroup = errors.New("Duplicate group")
+	// ErrBadLine is matched by every *ParseError
+	ErrBadLine = errors.New("Bad line")
+)
+
+// WithCollectErrors makes loading carry on past bad lines, keeping them like ignored lines, and
+// then return every failure at once joined with errors.Join, for validation tools that want to
+// report all the problems in a file rather than the first. The good entries are still loaded.
+// Each joined error is a *ParseError with its line number, and the list can be recovered with
+// the Unwrap() []error method of the result. It takes precedence over ignoring bad lines.
+func WithCollectErrors() Option {
+	return func(o *options) {
+		o.collectErrors = true
+	}
+}
+
+// WithBadLineHandler sets a function that is called with every bad line that is skipped because
+// bad lines are ignored or collected with WithCollectErrors, so that they can be logged or
+// counted instead of vanishing silently. The error is usually a *ParseError. The handler is
+// called while loading, before the new content replaces the old.
+func WithBadLineHandler(handler func(lineNumber int, line string, err error)) Option {
+	return func(o *options) {
+		o.badLineHandler = handler
+	}
+}
+
+// badLine passes a skipped line to the handler set with WithBadLineHandler.
+func (o *options) badLine(lineNumber int, raw string, err error) {
+	err = withLineNumber(err, lineNumber, raw)
+	o.warn("Skipped bad line", "line", lineNumber, "error", err)
+	if o.badLineHandler != nil {
+		o.badLineHandler(lineNumber, raw, err)
+	}
+}
+
+// sentinelError is an error with its own message that matches a sentinel error.
+type sentinelError struct {
+	sentinel error
+	message  string
+}
+
+// Error returns the message
+func (e *sentinelError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the sentinel so that it can be matched with errors.Is
+func (e *sentinelError) Unwrap() error {
+	return e.sentinel
+}
+
+// sentinelf returns an error with a formatted message that matches the sentinel with errors.Is.
+func sentinelf(sentinel error, format string, args ...interface{}) error {
+	return &sentinelError{sentinel: sentinel, message: fmt.Sprintf(format, args...)}
+}
+
+// ParseError describes a line that could not be parsed. When the error is returned while loading
+// a file, LineNumber holds the 1-based number of the offending line and RawLine holds the line
+// exactly as it appeared in the file.
+type ParseError struct {
+	// LineNumber is the 1-based line number in the file, or 0 if the line was parsed on its own
+	LineNumber int
+	// RawLine is the content of the line that failed to parse
+	RawLine string
+	// Field is the name of the field that was badly formatted, or empty if the line as a whole was bad
+	Field string
+	// Err is the underlying cause
+	Err error
+}
+
+// Error returns the cause prefixed with the line number when it is known
+func (e *ParseError) Error() string {
+	if e.LineNumber > 0 {
+		return fmt.Sprintf("line %d: %s", e.LineNumber, e.Err)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying cause so that it can be inspected with errors.Is and errors.As
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is ErrBadLine, so that any parse error matches it
+func (e *ParseError) Is(target error) bool {
+	return target == ErrBadLine
+}
+
+// newParseError builds a ParseError for the given line and field with a formatted cause.
+func newParseError(line, field string, format string, args ...interface{}) *ParseError {
+	return &ParseError{
+		RawLine: line,
+		Field:   field,
+		Err:     fmt.Errorf(format, args...),
+	}
+}
+
+// withLineNumber attaches the line number and raw line to the error if it is a ParseError.
+func withLineNumber(err error, lineNumber int, raw string) error {
+	if pe, ok := err.(*ParseError); ok {
+		pe.LineNumber = lineNumber
+		pe.RawLine = raw
+	}
+	return err
+}
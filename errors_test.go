@@ -0,0 +1,131 @@
+package etcpwdparse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, err := cache.UidForUsername("alice"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound but got %v", err)
+	}
+	if _, err := cache.HomeDirForUsername("alice"); !errors.Is(err, ErrUserNotFound) || err.Error() != "No such user with username 'alice'" {
+		t.Fatalf("expected ErrUserNotFound with the usual message but got %v", err)
+	}
+	if _, err := cache.RemoveByUid(1000); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound but got %v", err)
+	}
+	if _, err := cache.AddUser(UserSpec{Username: "root"}); !errors.Is(err, ErrDuplicateUser) || errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrDuplicateUser but got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "passwd")
+	os.WriteFile(path, []byte("root:x:0:0:root:/root:/bin/bash\nbroken\n"), 0644)
+	err := cache.LoadFromPath(path)
+	var pe *ParseError
+	if !errors.Is(err, ErrBadLine) || !errors.As(err, &pe) || pe.LineNumber != 2 {
+		t.Fatalf("expected ErrBadLine on line 2 but got %v", err)
+	}
+
+	os.WriteFile(path, []byte("root:x:0:0:root:/root:/bin/bash\ntoor:x:0:0:root:/root:/bin/bash\n"), 0644)
+	err = NewEtcPasswdCache(false, WithDuplicatePolicy(DuplicateError)).LoadFromPath(path)
+	if !errors.Is(err, ErrDuplicateUser) || !errors.Is(err, ErrBadLine) {
+		t.Fatalf("expected ErrDuplicateUser and ErrBadLine but got %v", err)
+	}
+
+	if err := cache.LoadFromPath(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrBadLine) {
+		t.Fatalf("expected a missing file error but got %v", err)
+	}
+
+	groups := NewEtcGroupCache(false)
+	groups.LoadFromReader(strings.NewReader("root:x:0:\n"))
+	if _, err := groups.PrimaryGroupName(&EtcPasswdEntry{username: "root", gid: 42}); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("expected ErrGroupNotFound but got %v", err)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	content := "root:x:0:0:root:/root:/bin/bash\nbroken\nalice:x:abc:1000::/home/alice:/bin/bash\nbob:x:1001:1001::/home/bob:/bin/sh\n+@bad:extra\n"
+	for _, ignore := range []bool{false, true} {
+		cache := NewEtcPasswdCache(ignore, WithCollectErrors())
+		err := cache.LoadFromReader(strings.NewReader(content))
+		if err == nil {
+			t.Fatalf("Should have failed")
+		}
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok {
+			t.Fatalf("expected a joined error but got %T", err)
+		}
+		lines := make([]int, 0)
+		for _, e := range joined.Unwrap() {
+			var pe *ParseError
+			if !errors.As(e, &pe) {
+				t.Fatalf("expected a *ParseError but got %T", e)
+			}
+			lines = append(lines, pe.LineNumber)
+		}
+		if len(lines) < 2 || lines[0] != 2 || lines[1] != 3 {
+			t.Fatalf("unexpected failing lines %v", lines)
+		}
+		if !errors.Is(err, ErrBadLine) || !strings.Contains(err.Error(), "line 3: ") {
+			t.Fatalf("unexpected error %v", err)
+		}
+		// the good entries are loaded and the bad lines are kept
+		if _, ok := cache.LookupUserByName("bob"); !ok {
+			t.Fatalf("bob should have been loaded")
+		}
+		if len(cache.ListEntries()) != 2 {
+			t.Fatalf("only root and bob should have been loaded")
+		}
+	}
+
+	cache := NewEtcPasswdCache(false, WithCollectErrors())
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+}
+
+func TestBadLineHandler(t *testing.T) {
+	type badLine struct {
+		number int
+		line   string
+	}
+	seen := make([]badLine, 0)
+	handler := WithBadLineHandler(func(lineNumber int, line string, err error) {
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.LineNumber != lineNumber {
+			t.Fatalf("expected a *ParseError for line %d but got %v", lineNumber, err)
+		}
+		seen = append(seen, badLine{lineNumber, line})
+	})
+
+	cache := NewEtcPasswdCache(true, handler)
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\n# comment\nbroken\nalice:x:abc:1000::/home/alice:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(seen) != 2 || seen[0] != (badLine{3, "broken"}) || seen[1].number != 4 {
+		t.Fatalf("unexpected bad lines %v", seen)
+	}
+
+	seen = seen[:0]
+	groups := NewEtcGroupCache(true, handler)
+	if err := groups.LoadFromReader(strings.NewReader("root:x:0:\nwheel:x:bad:\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(seen) != 1 || seen[0] != (badLine{2, "wheel:x:bad:"}) {
+		t.Fatalf("unexpected bad lines %v", seen)
+	}
+
+	// nothing is skipped when bad lines fail the load
+	seen = seen[:0]
+	if err := NewEtcPasswdCache(false, handler).LoadFromReader(strings.NewReader("broken\n")); err == nil || len(seen) != 0 {
+		t.Fatalf("the load should have failed without calling the handler: %v %v", err, seen)
+	}
+}
@@ -0,0 +1,156 @@
+package etcpwdparse
+
+import (
+	"sync"
+)
+
+// ChangeKind identifies what happened to a user in a ChangeEvent.
+type ChangeKind int
+
+const (
+	// UserAdded is sent for a username that appeared in the reloaded content
+	UserAdded ChangeKind = iota
+	// UserRemoved is sent for a username that disappeared from the reloaded content
+	UserRemoved
+	// UserModified is sent for a username whose fields changed in the reloaded content
+	UserModified
+)
+
+// String returns the name of the change kind
+func (k ChangeKind) String() string {
+	switch k {
+	case UserAdded:
+		return "added"
+	case UserRemoved:
+		return "removed"
+	case UserModified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// ChangeEvent describes a change to one user between two loads of the cache.
+type ChangeEvent struct {
+	Kind ChangeKind
+	// Entry is the new entry, or the entry that was removed for UserRemoved
+	Entry EtcPasswdEntry
+	// Previous is the entry before the change, only set for UserModified
+	Previous EtcPasswdEntry
+	// Changes lists the fields that changed, only set for UserModified
+	Changes []FieldChange
+}
+
+// subscriber queues events for one channel so that a slow reader never blocks a reload and
+// never misses an event.
+type subscriber struct {
+	mu     sync.Mutex
+	queue  []ChangeEvent
+	signal chan struct{}
+	stop   chan struct{}
+	out    chan ChangeEvent
+}
+
+// pump delivers the queued events to the channel in order until the subscriber is stopped.
+func (s *subscriber) pump() {
+	defer close(s.out)
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.signal:
+		}
+		s.mu.Lock()
+		events := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, ev := range events {
+			select {
+			case s.out <- ev:
+			case <-s.stop:
+				return
+			}
+		}
+	}
+}
+
+// push queues the events and wakes the pump.
+func (s *subscriber) push(events []ChangeEvent) {
+	s.mu.Lock()
+	s.queue = append(s.queue, events...)
+	s.mu.Unlock()
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe returns a channel that receives a ChangeEvent for every user added, removed, or
+// modified each time the content is reloaded, for example by StartWatching, WithTTL, or a call
+// to LoadFromPath. Users are matched by username as in Diff. Events are queued for each
+// subscriber so a slow reader does not hold up reloads. Call Unsubscribe to stop the events and
+// close the channel.
+func (e *EtcPasswdCache) Subscribe() <-chan ChangeEvent {
+	s := &subscriber{
+		signal: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		out:    make(chan ChangeEvent),
+	}
+	go s.pump()
+	e.subMu.Lock()
+	e.subscribers = append(e.subscribers, s)
+	e.subMu.Unlock()
+	return s.out
+}
+
+// Unsubscribe stops the events for a channel returned by Subscribe and closes it. Events that
+// have not been received yet are discarded.
+func (e *EtcPasswdCache) Unsubscribe(ch <-chan ChangeEvent) {
+	e.subMu.Lock()
+	defer e.subMu.Unlock()
+	for i, s := range e.subscribers {
+		if s.out == ch {
+			close(s.stop)
+			e.subscribers = append(e.subscribers[:i], e.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// hasSubscribers returns true if any channel is subscribed to the changes.
+func (e *EtcPasswdCache) hasSubscribers() bool {
+	e.subMu.Lock()
+	defer e.subMu.Unlock()
+	return len(e.subscribers) > 0
+}
+
+// publishChanges sends the differences made by a reload to the subscribers. It is called while
+// the write lock is held so that events are queued in the same order as the reloads happen.
+func (e *EtcPasswdCache) publishChanges(d PasswdDiff) {
+	e.subMu.Lock()
+	defer e.subMu.Unlock()
+	if len(e.subscribers) == 0 {
+		return
+	}
+	events := changeEvents(d)
+	if len(events) == 0 {
+		return
+	}
+	for _, s := range e.subscribers {
+		s.push(events)
+	}
+}
+
+// changeEvents converts a diff into events, removals first, then additions and modifications.
+func changeEvents(d PasswdDiff) []ChangeEvent {
+	events := make([]ChangeEvent, 0, len(d.Added)+len(d.Removed)+len(d.Modified))
+	for _, entry := range d.Removed {
+		events = append(events, ChangeEvent{Kind: UserRemoved, Entry: entry})
+	}
+	for _, entry := range d.Added {
+		events = append(events, ChangeEvent{Kind: UserAdded, Entry: entry})
+	}
+	for _, m := range d.Modified {
+		events = append(events, ChangeEvent{Kind: UserModified, Entry: m.New, Previous: m.Old, Changes: m.Changes})
+	}
+	return events
+}
@@ -0,0 +1,63 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func receiveEvent(t *testing.T, ch <-chan ChangeEvent) ChangeEvent {
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for an event")
+	}
+	return ChangeEvent{}
+}
+
+func TestSubscribe(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\nbob:x:1001:1001::/home/bob:/bin/sh\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	ch := cache.Subscribe()
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/zsh\ncarol:x:1002:1002::/home/carol:/bin/sh\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	// a second reload is queued behind the first
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	if ev := receiveEvent(t, ch); ev.Kind != UserRemoved || ev.Entry.Username() != "bob" {
+		t.Fatalf("unexpected event %v", ev)
+	}
+	if ev := receiveEvent(t, ch); ev.Kind != UserAdded || ev.Entry.Username() != "carol" {
+		t.Fatalf("unexpected event %v", ev)
+	}
+	ev := receiveEvent(t, ch)
+	if ev.Kind != UserModified || ev.Entry.Shell() != "/bin/zsh" || ev.Previous.Shell() != "/bin/bash" {
+		t.Fatalf("unexpected event %v", ev)
+	}
+	if len(ev.Changes) != 1 || ev.Changes[0].Field != "shell" {
+		t.Fatalf("unexpected changes %v", ev.Changes)
+	}
+	if ev := receiveEvent(t, ch); ev.Kind != UserRemoved || ev.Entry.Username() != "alice" {
+		t.Fatalf("unexpected event %v", ev)
+	}
+	if ev := receiveEvent(t, ch); ev.Kind != UserRemoved || ev.Entry.Username() != "carol" {
+		t.Fatalf("unexpected event %v", ev)
+	}
+
+	cache.Unsubscribe(ch)
+	if _, ok := <-ch; ok {
+		t.Fatalf("channel should have been closed")
+	}
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if UserModified.String() != "modified" {
+		t.Fatalf("%s != modified", UserModified.String())
+	}
+}
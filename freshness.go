@@ -0,0 +1,100 @@
+package etcpwdparse
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// WithTTL makes the cache reload its file on the next lookup once the content is older than
+// the given duration, giving long running services bounded staleness without a file watcher.
+// The reload happens inside the lookup that notices it, other lookups at the same time keep
+// using the old content. If the reload fails the old content is kept and tried again after
+// another TTL. Reloading replaces the content, so changes that have not been saved are lost.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithStatCheck makes every lookup stat the file the cache was loaded from and reload it if
+// its inode, modification time, or size changed since the load. This costs one stat per lookup,
+// which is cheaper than watching the file and never serves stale content on hosts where
+// accounts change rarely. It can be combined with WithTTL. Like WithTTL, reloading replaces
+// the content and loses changes that have not been saved. If the reload fails the last good
+// content is kept and the file is not read again until it changes.
+func WithStatCheck() Option {
+	return func(o *options) {
+		o.statCheck = true
+	}
+}
+
+// statReader returns the file information of the reader if it is a file
+func statReader(r io.Reader) os.FileInfo {
+	if f, ok := r.(*os.File); ok {
+		if info, err := f.Stat(); err == nil {
+			return info
+		}
+	}
+	return nil
+}
+
+// refresh reloads the file if the content has gone stale according to the TTL or stat check
+// options. It must be called without holding the lock.
+func (e *EtcPasswdCache) refresh() {
+	if e.opts.ttl <= 0 && !e.opts.statCheck {
+		return
+	}
+	if _, stale := e.stalePath(); !stale {
+		return
+	}
+	// only one lookup reloads, the others carry on with the current content
+	if !e.reloadMu.TryLock() {
+		return
+	}
+	defer e.reloadMu.Unlock()
+	// check again in case another lookup reloaded while we were getting the reload lock
+	path, stale := e.stalePath()
+	if !stale {
+		return
+	}
+	e.opts.debug("Reloading stale passwd file", "path", path)
+	if e.opts.metrics != nil {
+		e.opts.metrics.Reloaded()
+	}
+	// stat before reading so that a write racing with the failed load is noticed next time
+	info, statErr := os.Stat(path)
+	err := e.LoadFromPath(path)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failedInfo = nil
+	if err != nil {
+		// the failure is logged by the load, keep serving the current content until the TTL
+		// passes again or the file changes from the one that failed
+		e.loadedAt = time.Now()
+		if statErr == nil {
+			e.failedInfo = info
+		}
+	}
+}
+
+// stalePath returns the path of the loaded file and whether its content needs reloading
+func (e *EtcPasswdCache) stalePath() (string, bool) {
+	e.mu.RLock()
+	path, loadedAt, loadedInfo, failedInfo := e.path, e.loadedAt, e.loadedInfo, e.failedInfo
+	e.mu.RUnlock()
+	if path == "" {
+		return path, false
+	}
+	if e.opts.ttl > 0 && time.Since(loadedAt) >= e.opts.ttl {
+		return path, true
+	}
+	if e.opts.statCheck {
+		// a file that cannot be stat'ed right now is left alone rather than failing lookups
+		info, err := os.Stat(path)
+		if err == nil && (loadedInfo == nil || fileChanged(loadedInfo, info)) && (failedInfo == nil || fileChanged(failedInfo, info)) {
+			return path, true
+		}
+	}
+	return path, false
+}
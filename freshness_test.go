@@ -0,0 +1,114 @@
+package etcpwdparse
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestWithTTL(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	ioutil.WriteFile(pwFile, []byte("root:x:0:0:root:/root:/bin/bash\n"), 0644)
+
+	cache := NewEtcPasswdCache(false, WithTTL(50*time.Millisecond))
+	if err := cache.LoadFromPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	ioutil.WriteFile(pwFile, []byte("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/sh\n"), 0644)
+	if _, ok := cache.LookupUserByName("alice"); ok {
+		t.Fatalf("alice should not be visible before the TTL passes")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := cache.LookupUserByName("alice"); !ok {
+		t.Fatalf("alice should be visible after the TTL passes")
+	}
+
+	// a failed reload keeps the old content
+	os.Remove(pwFile)
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := cache.LookupUserByUid(1000); !ok {
+		t.Fatalf("content should have been kept after a failed reload")
+	}
+
+	untimed := NewEtcPasswdCache(false)
+	ioutil.WriteFile(pwFile, []byte("root:x:0:0:root:/root:/bin/bash\n"), 0644)
+	untimed.LoadFromPath(pwFile)
+	ioutil.WriteFile(pwFile, []byte("bob:x:1001:1001::/home/bob:/bin/sh\n"), 0644)
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := untimed.LookupUserByName("bob"); ok {
+		t.Fatalf("caches without a TTL should never reload")
+	}
+}
+
+func TestWithStatCheck(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	ioutil.WriteFile(pwFile, []byte("root:x:0:0:root:/root:/bin/bash\n"), 0644)
+
+	cache := NewEtcPasswdCache(false, WithStatCheck())
+	if err := cache.LoadFromPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, ok := cache.LookupUserByName("root"); !ok {
+		t.Fatalf("root should exist")
+	}
+
+	// replacing the file changes the inode so the next lookup reloads straight away
+	other := NewEtcPasswdCache(false, WithoutLocking())
+	other.LoadFromPath(pwFile)
+	other.AddEntry(EtcPasswdEntry{username: "alice", uid: 1000, gid: 1000, homedir: "/home/alice", shell: "/bin/sh"})
+	if err := other.WriteToPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, ok := cache.LookupUserByName("alice"); !ok {
+		t.Fatalf("alice should be visible after the file changed")
+	}
+
+	// once reloaded the file is not read again until it changes
+	cache.AddEntry(EtcPasswdEntry{username: "unsaved", uid: 2000})
+	if _, ok := cache.LookupUserByName("unsaved"); !ok {
+		t.Fatalf("the cache should not reload an unchanged file")
+	}
+
+	os.Remove(pwFile)
+	if _, ok := cache.LookupUserByName("alice"); !ok {
+		t.Fatalf("content should have been kept when the file is missing")
+	}
+}
+
+func TestWithStatCheckFailedReload(t *testing.T) {
+	pwFile := path.Join(t.TempDir(), "passwd")
+	ioutil.WriteFile(pwFile, []byte("root:x:0:0:root:/root:/bin/bash\n"), 0644)
+
+	sink := &recordingSink{}
+	cache := NewEtcPasswdCache(false, WithStatCheck(), WithMetrics(sink))
+	if err := cache.LoadFromPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	// a broken file is read once and then left alone until it changes again
+	ioutil.WriteFile(pwFile+".new", []byte("root:x:0:0:root:/root:/bin/bash\nbroken\n"), 0644)
+	os.Rename(pwFile+".new", pwFile)
+	for i := 0; i < 5; i++ {
+		if _, ok := cache.LookupUserByName("root"); !ok {
+			t.Fatalf("the last good content should have been kept")
+		}
+	}
+	if sink.reloads != 1 || sink.failures != 1 {
+		t.Fatalf("expected 1 failed reload, got %d reloads and %d failures", sink.reloads, sink.failures)
+	}
+
+	ioutil.WriteFile(pwFile+".new", []byte("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/sh\n"), 0644)
+	os.Rename(pwFile+".new", pwFile)
+	if _, ok := cache.LookupUserByName("alice"); !ok {
+		t.Fatalf("alice should be visible once the file is fixed")
+	}
+	if sink.reloads != 2 {
+		t.Fatalf("expected 2 reloads, got %d", sink.reloads)
+	}
+}
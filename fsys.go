@@ -0,0 +1,21 @@
+package etcpwdparse
+
+import (
+	"io/fs"
+	"time"
+)
+
+// LoadFromFS loads the struct from the named file in the given filesystem, such as an
+// embed.FS, an fstest.MapFS, or the filesystem of a zip archive, and replaces the cached
+// content. The path uses the slash separated form that fs.FS expects, like "etc/passwd". The
+// path is not remembered as it does not name a file on disk, so the content is never reloaded
+// by WithTTL, WithStatCheck, or StartWatching, and Save fails. Use WriteToPath instead.
+func (e *EtcPasswdCache) LoadFromFS(fsys fs.FS, path string) error {
+	f, err := fsys.Open(path)
+	if err != nil {
+		e.reportLoad(path, nil, time.Now(), err)
+		return err
+	}
+	defer f.Close()
+	return e.load(f, "")
+}
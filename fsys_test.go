@@ -0,0 +1,33 @@
+package etcpwdparse
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/passwd": &fstest.MapFile{Data: []byte("root:x:0:0:root:/root:/bin/bash\nbob:x:1000:1000::/home/bob:/bin/sh\n")},
+	}
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromFS(fsys, "etc/passwd"); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, ok := cache.LookupUserByName("bob"); !ok {
+		t.Fatalf("Should have found bob")
+	}
+
+	// failures leave the content untouched
+	if err := cache.LoadFromFS(fsys, "etc/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+	fsys["etc/passwd"] = &fstest.MapFile{Data: []byte("broken\n")}
+	if err := cache.LoadFromFS(fsys, "etc/passwd"); !errors.Is(err, ErrBadLine) {
+		t.Fatalf("expected a bad line, got %v", err)
+	}
+	if len(cache.ListEntries()) != 2 {
+		t.Fatalf("expected the content to be kept, got %d entries", len(cache.ListEntries()))
+	}
+}
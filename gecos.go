@@ -0,0 +1,121 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Gecos holds the comma separated subfields of the info field as used by finger and chfn.
+type Gecos struct {
+	FullName  string
+	Room      string
+	WorkPhone string
+	HomePhone string
+	// Other holds anything after the fourth subfield, including any further commas
+	Other string
+}
+
+// ParseGecos splits an info field into its subfields. Missing subfields are empty.
+func ParseGecos(info string) Gecos {
+	parts := strings.SplitN(info, ",", 5)
+	for len(parts) < 5 {
+		parts = append(parts, "")
+	}
+	return Gecos{
+		FullName:  parts[0],
+		Room:      parts[1],
+		WorkPhone: parts[2],
+		HomePhone: parts[3],
+		Other:     parts[4],
+	}
+}
+
+// String joins the subfields back into an info field, leaving out trailing empty subfields
+func (g Gecos) String() string {
+	parts := []string{g.FullName, g.Room, g.WorkPhone, g.HomePhone, g.Other}
+	for len(parts) > 1 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts, ",")
+}
+
+// Gecos function returns the info field of the entry split into its subfields
+func (e *EtcPasswdEntry) Gecos() Gecos {
+	return ParseGecos(e.info)
+}
+
+// checkGecosSubfield rejects values that cannot be stored in a subfield. The info field has no
+// escaping mechanism so, like chfn, the separators ',' and ':', '=' and control characters are
+// refused rather than silently corrupting the neighbouring subfields.
+func checkGecosSubfield(name, value string) error {
+	for _, r := range value {
+		if r == ',' || r == ':' || r == '=' || r < 0x20 || r == 0x7f {
+			return fmt.Errorf("Gecos %s may not contain %q", name, r)
+		}
+	}
+	return nil
+}
+
+// SetFullName replaces the full name subfield, keeping the others
+func (g *Gecos) SetFullName(fullName string) error {
+	if err := checkGecosSubfield("full name", fullName); err != nil {
+		return err
+	}
+	g.FullName = fullName
+	return nil
+}
+
+// SetRoom replaces the room number subfield, keeping the others
+func (g *Gecos) SetRoom(room string) error {
+	if err := checkGecosSubfield("room", room); err != nil {
+		return err
+	}
+	g.Room = room
+	return nil
+}
+
+// SetWorkPhone replaces the work phone subfield, keeping the others
+func (g *Gecos) SetWorkPhone(phone string) error {
+	if err := checkGecosSubfield("work phone", phone); err != nil {
+		return err
+	}
+	g.WorkPhone = phone
+	return nil
+}
+
+// SetHomePhone replaces the home phone subfield, keeping the others
+func (g *Gecos) SetHomePhone(phone string) error {
+	if err := checkGecosSubfield("home phone", phone); err != nil {
+		return err
+	}
+	g.HomePhone = phone
+	return nil
+}
+
+// Gecos sets the info field from the given subfields
+func (b *EtcPasswdEntryBuilder) Gecos(g Gecos) *EtcPasswdEntryBuilder {
+	b.entry.info = g.String()
+	return b
+}
+
+// UpdateGecos applies the update function to the subfields of the named user, like chfn, and
+// replaces the user with ModifyUser. Nothing changes if update returns an error, for example
+// from one of the Gecos setters. Like the ModifyUser callback, update runs without the cache lock
+// held and may be called again if the user changes concurrently. Call Save to persist the change
+// to disk.
+func (e *EtcPasswdCache) UpdateGecos(name string, update func(g *Gecos) error) (EtcPasswdEntry, error) {
+	var updateErr error
+	entry, err := e.ModifyUser(name, func(b *EtcPasswdEntryBuilder) {
+		g := b.entry.Gecos()
+		if updateErr = update(&g); updateErr == nil {
+			b.Gecos(g)
+		}
+	})
+	if err == nil {
+		err = updateErr
+	}
+	if err != nil {
+		return EtcPasswdEntry{}, err
+	}
+	return entry, nil
+}
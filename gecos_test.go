@@ -0,0 +1,87 @@
+package etcpwdparse
+
+import (
+	"testing"
+)
+
+func TestParseGecos(t *testing.T) {
+	g := ParseGecos("Alice Smith,Room 101,555-1234,555-9876,alice@example.com,extra")
+	if g.FullName != "Alice Smith" {
+		t.Fatalf("%s != Alice Smith", g.FullName)
+	}
+	if g.Room != "Room 101" || g.WorkPhone != "555-1234" || g.HomePhone != "555-9876" {
+		t.Fatalf("unexpected subfields %#v", g)
+	}
+	if g.Other != "alice@example.com,extra" {
+		t.Fatalf("%s != alice@example.com,extra", g.Other)
+	}
+	if g.String() != "Alice Smith,Room 101,555-1234,555-9876,alice@example.com,extra" {
+		t.Fatalf("unexpected string %s", g.String())
+	}
+
+	g = ParseGecos("FTP User")
+	if g.FullName != "FTP User" || g.Room != "" || g.Other != "" {
+		t.Fatalf("unexpected subfields %#v", g)
+	}
+	if g.String() != "FTP User" {
+		t.Fatalf("%s != FTP User", g.String())
+	}
+	if s := (Gecos{FullName: "Bob", WorkPhone: "123"}).String(); s != "Bob,,123" {
+		t.Fatalf("%s != Bob,,123", s)
+	}
+	if ParseGecos("").String() != "" {
+		t.Fatalf("empty info should stay empty")
+	}
+
+	entry, _ := ParsePasswdLine("ftp:x:14:50:FTP User,Basement:/var/ftp:/sbin/nologin")
+	if entry.Gecos().Room != "Basement" {
+		t.Fatalf("%s != Basement", entry.Gecos().Room)
+	}
+}
+
+func TestGecosSetters(t *testing.T) {
+	g := ParseGecos("Alice,Room 1,555-1234,,notes")
+	if err := g.SetFullName("Alice Jones"); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := g.SetWorkPhone("555-0000"); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if g.String() != "Alice Jones,Room 1,555-0000,,notes" {
+		t.Fatalf("unexpected string %s", g.String())
+	}
+	for _, bad := range []string{"Jones, Alice", "a:b", "a=b", "a\nb"} {
+		if err := g.SetFullName(bad); err == nil {
+			t.Fatalf("Should have failed on %q", bad)
+		}
+	}
+	if err := g.SetRoom("Room,2"); err == nil {
+		t.Fatalf("Should have failed on a comma")
+	}
+	if g.FullName != "Alice Jones" || g.Room != "Room 1" {
+		t.Fatalf("failed setters should not change the subfields: %#v", g)
+	}
+}
+
+func TestUpdateGecos(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	cache.AddEntry(EtcPasswdEntry{username: "alice", uid: 1000, info: "Alice,Room 1"})
+
+	entry, err := cache.UpdateGecos("alice", func(g *Gecos) error {
+		return g.SetFullName("Alice Jones")
+	})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry.Info() != "Alice Jones,Room 1" {
+		t.Fatalf("%s != Alice Jones,Room 1", entry.Info())
+	}
+	if _, err := cache.UpdateGecos("alice", func(g *Gecos) error {
+		return g.SetHomePhone("1,2")
+	}); err == nil {
+		t.Fatalf("Should have failed on a comma")
+	}
+	if current, _ := cache.LookupUserByName("alice"); current.Info() != "Alice Jones,Room 1" {
+		t.Fatalf("failed update should not change the entry: %s", current.Info())
+	}
+}
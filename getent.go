@@ -0,0 +1,97 @@
+package etcpwdparse
+
+import (
+	"time"
+)
+
+// Source identifies where the answer to a lookup came from.
+type Source int
+
+const (
+	// SourceNone means the lookup was not answered
+	SourceNone Source = iota
+	// SourceFile means the entry came from the loaded passwd content
+	SourceFile
+	// SourceGetent means the entry came from the getent fallback
+	SourceGetent
+)
+
+// String returns a short name for the source
+func (s Source) String() string {
+	switch s {
+	case SourceFile:
+		return "file"
+	case SourceGetent:
+		return "getent"
+	}
+	return "none"
+}
+
+// WithGetentFallback makes lookups by name or uid that miss the loaded content fall back to
+// running `getent passwd <key>`, so that users from LDAP, SSSD, and other NSS sources can still
+// be resolved. Entries found this way are not added to the cache.
+func WithGetentFallback() Option {
+	return func(o *options) {
+		o.getentCommand = "getent"
+	}
+}
+
+// WithGetentTimeout changes how long the getent fallback waits for each lookup, the default is
+// DefaultGetentTimeout. A lookup that times out is treated as a miss.
+func WithGetentTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.getentTimeout = timeout
+	}
+}
+
+// getentFallback returns the source used for fallback lookups, or nil if it is disabled.
+func (e *EtcPasswdCache) getentFallback() *GetentSource {
+	if len(e.opts.getentCommand) == 0 {
+		return nil
+	}
+	return &GetentSource{Command: e.opts.getentCommand, Timeout: e.opts.getentTimeout}
+}
+
+// LookupUserByNameWithSource returns the entry for the given username along with where it was
+// found. See WithGetentFallback.
+func (e *EtcPasswdCache) LookupUserByNameWithSource(name string) (*EtcPasswdEntry, Source, bool) {
+	e.refresh()
+	e.mu.RLock()
+	entry, ok := e.namemap[e.opts.nameKey(name)]
+	e.mu.RUnlock()
+	if ok {
+		e.opts.countLookup(true)
+		return entry, SourceFile, true
+	}
+	if fallback := e.getentFallback(); fallback != nil {
+		e.opts.debug("Looking up user with getent", "username", name)
+		if entry, ok := fallback.LookupByName(name); ok {
+			e.opts.countLookup(true)
+			return entry, SourceGetent, true
+		}
+	}
+	e.opts.countLookup(false)
+	return nil, SourceNone, false
+}
+
+// LookupUserByUidWithSource returns the entry for the given userid along with where it was
+// found. See WithGetentFallback.
+func (e *EtcPasswdCache) LookupUserByUidWithSource(id int) (*EtcPasswdEntry, Source, bool) {
+	e.refresh()
+	e.mu.RLock()
+	entry, ok := e.idmap[id]
+	e.mu.RUnlock()
+	if ok {
+		e.opts.countLookup(true)
+		return entry, SourceFile, true
+	}
+	if fallback := e.getentFallback(); fallback != nil {
+		e.opts.debug("Looking up user with getent", "uid", id)
+		if entry, ok := fallback.LookupByUid(id); ok {
+			e.opts.countLookup(true)
+			return entry, SourceGetent, true
+		}
+	}
+	e.opts.countLookup(false)
+	return nil, SourceNone, false
+}
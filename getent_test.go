@@ -0,0 +1,95 @@
+package etcpwdparse
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fakeGetentScript = `#!/bin/sh
+[ "$2" = "--" ] || exit 64
+case "$3" in
+  ldapuser|5000) echo "ldapuser:*:5000:5000:LDAP User:/home/ldapuser:/bin/bash" ;;
+  *) exit 2 ;;
+esac
+`
+
+func TestGetentFallback(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "bin")
+	defer os.RemoveAll(tempDir)
+	script := path.Join(tempDir, "getent")
+	if err := ioutil.WriteFile(script, []byte(fakeGetentScript), 0755); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, ok := cache.LookupUserByName("ldapuser"); ok {
+		t.Fatalf("ldapuser should not be found without the fallback")
+	}
+
+	cache = NewEtcPasswdCache(false, WithGetentFallback())
+	cache.opts.getentCommand = script
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	entry, source, ok := cache.LookupUserByNameWithSource("root")
+	if !ok || source != SourceFile || entry.Uid() != 0 {
+		t.Fatalf("root should come from the file")
+	}
+	entry, source, ok = cache.LookupUserByNameWithSource("ldapuser")
+	if !ok || source != SourceGetent || entry.Homedir() != "/home/ldapuser" {
+		t.Fatalf("ldapuser should come from getent")
+	}
+	entry, source, ok = cache.LookupUserByUidWithSource(5000)
+	if !ok || source != SourceGetent || entry.Username() != "ldapuser" {
+		t.Fatalf("uid 5000 should come from getent")
+	}
+	if _, source, ok = cache.LookupUserByNameWithSource("missing"); ok || source != SourceNone {
+		t.Fatalf("missing should not be found")
+	}
+	if uid, err := cache.UidForUsername("ldapuser"); err != nil || uid != 5000 {
+		t.Fatalf("shortcut functions should use the fallback")
+	}
+	if len(cache.ListEntries()) != 13 {
+		t.Fatalf("fallback entries should not be added to the cache")
+	}
+}
+
+func TestGetentFallbackArguments(t *testing.T) {
+	tempDir := t.TempDir()
+	script := path.Join(tempDir, "getent")
+	argsFile := path.Join(tempDir, "args")
+	ioutil.WriteFile(script, []byte("#!/bin/sh\nprintf '%s\\n' \"$@\" > "+argsFile+"\nexit 2\n"), 0755)
+
+	cache := NewEtcPasswdCache(false, WithGetentFallback())
+	cache.opts.getentCommand = script
+	if _, ok := cache.LookupUserByName("--service=files"); ok {
+		t.Fatalf("the user should not be found")
+	}
+	args, _ := ioutil.ReadFile(argsFile)
+	if string(args) != "passwd\n--\n--service=files\n" {
+		t.Fatalf("the key should come after the end of the options: %q", args)
+	}
+}
+
+func TestGetentFallbackTimeout(t *testing.T) {
+	script := path.Join(t.TempDir(), "getent")
+	ioutil.WriteFile(script, []byte("#!/bin/sh\nexec sleep 5\n"), 0755)
+
+	cache := NewEtcPasswdCache(false, WithGetentFallback(), WithGetentTimeout(100*time.Millisecond))
+	cache.opts.getentCommand = script
+	start := time.Now()
+	if _, ok := cache.LookupUserByUid(5000); ok {
+		t.Fatalf("the user should not be found")
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("the lookup should have timed out")
+	}
+}
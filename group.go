@@ -0,0 +1,189 @@
+package etcpwdparse
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EtcGroupEntry is a parsed line from the etc group file. It contains all 4 parts of the structure.
+type EtcGroupEntry struct {
+	name     string
+	password string
+	gid      int
+	members  []string
+}
+
+// Name function returns the group name for the entry
+func (e *EtcGroupEntry) Name() string {
+	return e.name
+}
+
+// Password function returns the encrypted password string for the entry
+func (e *EtcGroupEntry) Password() string {
+	return e.password
+}
+
+// Gid function returns the group id for the entry
+func (e *EtcGroupEntry) Gid() int {
+	return e.gid
+}
+
+// Members function returns the usernames of the supplementary members of the group
+func (e *EtcGroupEntry) Members() []string {
+	return e.members
+}
+
+// String returns the entry formatted as an /etc/group line without a line ending. The result
+// can be parsed again with ParseGroupLine.
+func (e EtcGroupEntry) String() string {
+	return strings.Join([]string{e.name, e.password, strconv.Itoa(e.gid), strings.Join(e.members, ",")}, ":")
+}
+
+// EtcGroupCache is an object that stores a set of entries from the group file and
+// has quick lookup functions.
+type EtcGroupCache struct {
+	entries        []*EtcGroupEntry
+	namemap        map[string]*EtcGroupEntry
+	idmap          map[int]*EtcGroupEntry
+	ignoreBadLines bool
+	opts           options
+}
+
+// Gid32 function returns the group id as the unsigned 32 bit value used by the kernel
+func (e *EtcGroupEntry) Gid32() uint32 {
+	return uint32(e.gid)
+}
+
+// ParseGroupLine is a function used to parse a 4 entry /etc/group line formatted line
+// into a EtcGroupEntry object. Errors are returned as a *ParseError.
+func ParseGroupLine(line string) (EtcGroupEntry, error) {
+	return parseGroupLine(line, &options{})
+}
+
+// parseGroupLine implements ParseGroupLine with the id overflow policy of the given options.
+func parseGroupLine(line string, o *options) (EtcGroupEntry, error) {
+	result := EtcGroupEntry{}
+	parts := strings.Split(strings.TrimSpace(line), ":")
+	if len(parts) != 4 {
+		return result, newParseError(line, "", "Group line had wrong number of parts %d != 4", len(parts))
+	}
+	result.name = strings.TrimSpace(parts[0])
+	result.password = strings.TrimSpace(parts[1])
+
+	gid, err := parseID(parts[2], o.idOverflow)
+	if err != nil {
+		return result, newParseError(line, "gid", "Group line had badly formatted gid %s: %w", parts[2], err)
+	}
+	result.gid = gid
+
+	result.members = splitMemberList(parts[3])
+	return result, nil
+}
+
+// splitMemberList splits a comma separated list of usernames, dropping empty items.
+func splitMemberList(field string) []string {
+	results := make([]string, 0)
+	for _, member := range strings.Split(field, ",") {
+		member = strings.TrimSpace(member)
+		if len(member) > 0 {
+			results = append(results, member)
+		}
+	}
+	return results
+}
+
+// AddEntry adds an entry object to the cache object and links it into the lookup maps.
+// Overrides any existing item in the lookup maps.
+func (e *EtcGroupCache) AddEntry(entry EtcGroupEntry) {
+	if e.namemap == nil {
+		e.reset()
+	}
+	e.entries = append(e.entries, &entry)
+	e.namemap[entry.name] = &entry
+	e.idmap[entry.gid] = &entry
+}
+
+// LoadFromPath loads the struct from a file on disk and replaces the cached content.
+func (e *EtcGroupCache) LoadFromPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return e.LoadFromReader(f)
+}
+
+// reset replaces the content with empty structures.
+func (e *EtcGroupCache) reset() {
+	e.entries = make([]*EtcGroupEntry, 0)
+	e.namemap = make(map[string]*EtcGroupEntry)
+	e.idmap = make(map[int]*EtcGroupEntry)
+}
+
+// LoadFromReader loads the struct from group formatted content read from the given reader
+// and replaces the cached content. The existing content is left untouched if loading fails.
+func (e *EtcGroupCache) LoadFromReader(r io.Reader) error {
+	next := &EtcGroupCache{ignoreBadLines: e.ignoreBadLines, opts: e.opts}
+	next.reset()
+	err := readNumberedLines(r, func(lineNumber int, raw, line string) error {
+		entry, err := parseGroupLine(line, &e.opts)
+		if err != nil {
+			if e.ignoreBadLines {
+				e.opts.badLine(lineNumber, raw, err)
+				return nil
+			}
+			return err
+		}
+		next.AddEntry(entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	e.entries, e.namemap, e.idmap = next.entries, next.namemap, next.idmap
+	return nil
+}
+
+// NewEtcGroupCache returns an empty group cache configured with the given options.
+func NewEtcGroupCache(ignoreBadLines bool, opts ...Option) *EtcGroupCache {
+	return &EtcGroupCache{
+		ignoreBadLines: ignoreBadLines,
+		opts:           applyOptions(opts),
+	}
+}
+
+// NewLoadedEtcGroupCache returns a loaded group cache in a single call.
+func NewLoadedEtcGroupCache(opts ...Option) (*EtcGroupCache, error) {
+	result := NewEtcGroupCache(false, opts...)
+	if err := result.LoadDefault(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LoadDefault loads the struct from the /etc/group file, relative to the root given by WithRoot.
+// The ETCPWDPARSE_GROUP environment variable overrides the location.
+func (e *EtcGroupCache) LoadDefault() error {
+	return e.LoadFromPath(e.opts.defaultPath("/etc/group"))
+}
+
+// LookupGroupByName returns the entry for the given group name
+func (e *EtcGroupCache) LookupGroupByName(name string) (*EtcGroupEntry, bool) {
+	entry, ok := e.namemap[name]
+	return entry, ok
+}
+
+// LookupGroupByGid returns the entry for the given group id
+func (e *EtcGroupCache) LookupGroupByGid(id int) (*EtcGroupEntry, bool) {
+	entry, ok := e.idmap[id]
+	return entry, ok
+}
+
+// ListEntries returns a slice containing references to all the entry objects
+func (e *EtcGroupCache) ListEntries() []*EtcGroupEntry {
+	results := make([]*EtcGroupEntry, len(e.entries))
+	copy(results, e.entries)
+	return results
+}
@@ -0,0 +1,97 @@
+package etcpwdparse
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+const fakeGroupContent = `
+# commented line
+root:x:0:
+bin:x:1:root,daemon
+daemon:x:2:root,bin
+wheel:x:10:alice, bob
+users:x:100:
+`
+
+func TestGroupFull(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	grFile := path.Join(tempDir, "group")
+	err := ioutil.WriteFile(grFile, []byte(fakeGroupContent), 0644)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	defer os.Remove(grFile)
+
+	cache := NewEtcGroupCache(false)
+	err = cache.LoadFromPath(grFile)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	wheelEntry, _ := cache.LookupGroupByName("wheel")
+	if wheelEntry.Name() != "wheel" {
+		t.Fatalf("%s != wheel", wheelEntry.Name())
+	}
+	if wheelEntry.Password() != "x" {
+		t.Fatalf("%s != x", wheelEntry.Password())
+	}
+	if wheelEntry.Gid() != 10 {
+		t.Fatalf("%d != 10", wheelEntry.Gid())
+	}
+	if strings.Join(wheelEntry.Members(), ",") != "alice,bob" {
+		t.Fatalf("%v != [alice bob]", wheelEntry.Members())
+	}
+
+	usersEntry, _ := cache.LookupGroupByGid(100)
+	if usersEntry.Name() != "users" {
+		t.Fatalf("%s != users", usersEntry.Name())
+	}
+	if len(usersEntry.Members()) != 0 {
+		t.Fatalf("%d != 0", len(usersEntry.Members()))
+	}
+
+	if len(cache.ListEntries()) != 5 {
+		t.Fatalf("%d != 5", len(cache.ListEntries()))
+	}
+}
+
+func TestGroupBadLines(t *testing.T) {
+	content := fakeGroupContent + "broken:line\n"
+
+	cache := NewEtcGroupCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(content)); err == nil {
+		t.Fatalf("Should have failed on a bad line")
+	}
+
+	cache = NewEtcGroupCache(true)
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(cache.ListEntries()) != 5 {
+		t.Fatalf("%d != 5", len(cache.ListEntries()))
+	}
+}
+
+func TestGroupAddEntryAndFailedLoad(t *testing.T) {
+	cache := NewEtcGroupCache(false)
+	entry, _ := ParseGroupLine("staff:x:50:alice")
+	cache.AddEntry(entry)
+	if found, ok := cache.LookupGroupByGid(50); !ok || found.Name() != "staff" {
+		t.Fatalf("expected staff to be added to an empty cache")
+	}
+
+	// a failed load leaves the existing content in place
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:\nbroken:line\n")); err == nil {
+		t.Fatalf("Should have failed on a bad line")
+	}
+	if _, ok := cache.LookupGroupByName("root"); ok {
+		t.Fatalf("root should not have been loaded")
+	}
+	if _, ok := cache.LookupGroupByName("staff"); !ok || len(cache.ListEntries()) != 1 {
+		t.Fatalf("expected the existing content to be kept")
+	}
+}
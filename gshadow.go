@@ -0,0 +1,145 @@
+package etcpwdparse
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// EtcGshadowEntry is a parsed line from the etc gshadow file. It contains all 4 parts of the structure.
+type EtcGshadowEntry struct {
+	name           string
+	password       string
+	administrators []string
+	members        []string
+}
+
+// Name function returns the group name for the entry
+func (e *EtcGshadowEntry) Name() string {
+	return e.name
+}
+
+// Password function returns the encrypted group password for the entry
+func (e *EtcGshadowEntry) Password() string {
+	return e.password
+}
+
+// Administrators function returns the usernames of the group administrators
+func (e *EtcGshadowEntry) Administrators() []string {
+	return e.administrators
+}
+
+// Members function returns the usernames of the group members
+func (e *EtcGshadowEntry) Members() []string {
+	return e.members
+}
+
+// EtcGshadowCache is an object that stores a set of entries from the gshadow file and
+// has quick lookup functions.
+type EtcGshadowCache struct {
+	entries        []*EtcGshadowEntry
+	namemap        map[string]*EtcGshadowEntry
+	ignoreBadLines bool
+	opts           options
+}
+
+// ParseGshadowLine is a function used to parse a 4 entry /etc/gshadow line formatted line
+// into a EtcGshadowEntry object. Errors are returned as a *ParseError.
+func ParseGshadowLine(line string) (EtcGshadowEntry, error) {
+	result := EtcGshadowEntry{}
+	parts := strings.Split(strings.TrimSpace(line), ":")
+	if len(parts) != 4 {
+		return result, newParseError(line, "", "Gshadow line had wrong number of parts %d != 4", len(parts))
+	}
+	result.name = strings.TrimSpace(parts[0])
+	result.password = strings.TrimSpace(parts[1])
+	result.administrators = splitMemberList(parts[2])
+	result.members = splitMemberList(parts[3])
+	return result, nil
+}
+
+// AddEntry adds an entry object to the cache object and links it into the lookup map.
+// Overrides any existing item in the lookup map.
+func (e *EtcGshadowCache) AddEntry(entry EtcGshadowEntry) {
+	if e.namemap == nil {
+		e.reset()
+	}
+	e.entries = append(e.entries, &entry)
+	e.namemap[entry.name] = &entry
+}
+
+// LoadFromPath loads the struct from a file on disk and replaces the cached content.
+func (e *EtcGshadowCache) LoadFromPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return e.LoadFromReader(f)
+}
+
+// reset replaces the content with empty structures.
+func (e *EtcGshadowCache) reset() {
+	e.entries = make([]*EtcGshadowEntry, 0)
+	e.namemap = make(map[string]*EtcGshadowEntry)
+}
+
+// LoadFromReader loads the struct from gshadow formatted content read from the given reader
+// and replaces the cached content. The existing content is left untouched if loading fails.
+func (e *EtcGshadowCache) LoadFromReader(r io.Reader) error {
+	next := &EtcGshadowCache{ignoreBadLines: e.ignoreBadLines, opts: e.opts}
+	next.reset()
+	err := readNumberedLines(r, func(lineNumber int, raw, line string) error {
+		entry, err := ParseGshadowLine(line)
+		if err != nil {
+			if e.ignoreBadLines {
+				e.opts.badLine(lineNumber, raw, err)
+				return nil
+			}
+			return err
+		}
+		next.AddEntry(entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	e.entries, e.namemap = next.entries, next.namemap
+	return nil
+}
+
+// NewEtcGshadowCache returns an empty gshadow cache configured with the given options.
+func NewEtcGshadowCache(ignoreBadLines bool, opts ...Option) *EtcGshadowCache {
+	return &EtcGshadowCache{
+		ignoreBadLines: ignoreBadLines,
+		opts:           applyOptions(opts),
+	}
+}
+
+// NewLoadedEtcGshadowCache returns a loaded gshadow cache in a single call.
+// The /etc/gshadow file is usually only readable by root.
+func NewLoadedEtcGshadowCache(opts ...Option) (*EtcGshadowCache, error) {
+	result := NewEtcGshadowCache(false, opts...)
+	if err := result.LoadDefault(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LoadDefault loads the struct from the /etc/gshadow file, relative to the root given by WithRoot
+func (e *EtcGshadowCache) LoadDefault() error {
+	return e.LoadFromPath(e.opts.defaultPath("/etc/gshadow"))
+}
+
+// LookupGroupByName returns the entry for the given group name
+func (e *EtcGshadowCache) LookupGroupByName(name string) (*EtcGshadowEntry, bool) {
+	entry, ok := e.namemap[name]
+	return entry, ok
+}
+
+// ListEntries returns a slice containing references to all the entry objects
+func (e *EtcGshadowCache) ListEntries() []*EtcGshadowEntry {
+	results := make([]*EtcGshadowEntry, len(e.entries))
+	copy(results, e.entries)
+	return results
+}
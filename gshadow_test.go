@@ -0,0 +1,65 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+const fakeGshadowContent = `
+# commented line
+root:::
+wheel:!:alice:alice,bob
+docker:$6$salt$hash:carol,dave:eve
+`
+
+func TestGshadowFull(t *testing.T) {
+	cache := NewEtcGshadowCache(false)
+	err := cache.LoadFromReader(strings.NewReader(fakeGshadowContent))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	dockerEntry, _ := cache.LookupGroupByName("docker")
+	if dockerEntry.Password() != "$6$salt$hash" {
+		t.Fatalf("%s != $6$salt$hash", dockerEntry.Password())
+	}
+	if strings.Join(dockerEntry.Administrators(), ",") != "carol,dave" {
+		t.Fatalf("%v != [carol dave]", dockerEntry.Administrators())
+	}
+	if strings.Join(dockerEntry.Members(), ",") != "eve" {
+		t.Fatalf("%v != [eve]", dockerEntry.Members())
+	}
+
+	rootEntry, _ := cache.LookupGroupByName("root")
+	if len(rootEntry.Administrators()) != 0 || len(rootEntry.Members()) != 0 {
+		t.Fatalf("root should have no administrators or members")
+	}
+
+	if len(cache.ListEntries()) != 3 {
+		t.Fatalf("%d != 3", len(cache.ListEntries()))
+	}
+
+	if _, err := ParseGshadowLine("wheel:!:alice"); err == nil {
+		t.Fatalf("Should have failed on a short line")
+	}
+}
+
+func TestGshadowAddEntryAndFailedLoad(t *testing.T) {
+	cache := NewEtcGshadowCache(false)
+	entry, _ := ParseGshadowLine("staff:!::alice")
+	cache.AddEntry(entry)
+	if _, ok := cache.LookupGroupByName("staff"); !ok {
+		t.Fatalf("expected staff to be added to an empty cache")
+	}
+
+	// a failed load leaves the existing content in place
+	if err := cache.LoadFromReader(strings.NewReader("root:::\nbroken:line\n")); err == nil {
+		t.Fatalf("Should have failed on a bad line")
+	}
+	if _, ok := cache.LookupGroupByName("root"); ok {
+		t.Fatalf("root should not have been loaded")
+	}
+	if _, ok := cache.LookupGroupByName("staff"); !ok || len(cache.ListEntries()) != 1 {
+		t.Fatalf("expected the existing content to be kept")
+	}
+}
@@ -0,0 +1,77 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// FindingMissingHomedir is reported by AuditHomedirs when the home directory does not exist
+	// or is not a directory
+	FindingMissingHomedir FindingKind = "missing-homedir"
+	// FindingHomedirOwner is reported by AuditHomedirs when the home directory is not owned by
+	// the user
+	FindingHomedirOwner FindingKind = "homedir-owner"
+	// FindingHomedirMode is reported by AuditHomedirs when the home directory can be written by
+	// the group or by everyone
+	FindingHomedirMode FindingKind = "homedir-mode"
+)
+
+// AuditHomedirs stats the home directory of every account with a login shell, see
+// CanLoginShell, and reports the ones that are missing, not owned by the user, or writable by
+// the group or by everyone, in file order. Service accounts are skipped since they typically
+// share a root owned directory. Paths are taken relative to the root given by WithRoot.
+// Ownership is not checked on platforms without unix file owners.
+func (e *EtcPasswdCache) AuditHomedirs() []Finding {
+	type account struct {
+		lineNumber int
+		entry      EtcPasswdEntry
+	}
+	// copy the accounts so that slow stats, for example on a hung mount, do not block writers
+	e.refresh()
+	e.mu.RLock()
+	root := e.opts.root
+	accounts := make([]account, 0)
+	for _, l := range e.lines {
+		if l.entry < 0 {
+			continue
+		}
+		entry := e.entries[l.entry]
+		if !entry.CanLoginShell() || len(entry.homedir) == 0 {
+			continue
+		}
+		accounts = append(accounts, account{lineNumber: l.number, entry: *entry})
+	}
+	e.mu.RUnlock()
+
+	findings := make([]Finding, 0)
+	for _, a := range accounts {
+		entry := a.entry
+		add := func(kind FindingKind, format string, args ...interface{}) {
+			findings = append(findings, Finding{
+				Kind:       kind,
+				LineNumber: a.lineNumber,
+				Username:   entry.username,
+				Message:    fmt.Sprintf(format, args...),
+			})
+		}
+
+		info, err := os.Stat(filepath.Join(root, entry.homedir))
+		if err != nil {
+			add(FindingMissingHomedir, "home directory '%s' cannot be read: %s", entry.homedir, err)
+			continue
+		}
+		if !info.IsDir() {
+			add(FindingMissingHomedir, "home directory '%s' is not a directory", entry.homedir)
+			continue
+		}
+		if uid, _, ok := fileOwner(info); ok && uid != entry.uid {
+			add(FindingHomedirOwner, "home directory '%s' is owned by uid %d", entry.homedir, uid)
+		}
+		if info.Mode().Perm()&0022 != 0 {
+			add(FindingHomedirMode, "home directory '%s' has mode %04o and can be written by other users", entry.homedir, info.Mode().Perm())
+		}
+	}
+	return findings
+}
@@ -0,0 +1,44 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package etcpwdparse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditHomedirs(t *testing.T) {
+	root, _ := os.MkdirTemp("", "homedirs")
+	defer os.RemoveAll(root)
+	for _, name := range []string{"alice", "carol", "dave"} {
+		os.MkdirAll(filepath.Join(root, "home", name), 0700)
+	}
+	os.Chmod(filepath.Join(root, "home", "carol"), 0777)
+	os.WriteFile(filepath.Join(root, "home", "erin"), []byte("not a directory"), 0644)
+
+	uid := os.Getuid()
+	content := fmt.Sprintf(`alice:x:%[1]d:100::/home/alice:/bin/bash
+bob:x:%[1]d:100::/home/bob:/bin/bash
+daemon:x:2:2:daemon:/nonexistent:/usr/sbin/nologin
+carol:x:%[1]d:100::/home/carol:/bin/bash
+dave:x:%[2]d:100::/home/dave:/bin/bash
+erin:x:%[1]d:100::/home/erin:/bin/bash
+`, uid, uid+1)
+	cache := NewEtcPasswdCache(false, WithRoot(root))
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	findings := cache.AuditHomedirs()
+	got := make([]string, 0)
+	for _, f := range findings {
+		got = append(got, fmt.Sprintf("%d:%s:%s", f.LineNumber, f.Username, f.Kind))
+	}
+	expected := "2:bob:missing-homedir,4:carol:homedir-mode,5:dave:homedir-owner,6:erin:missing-homedir"
+	if strings.Join(got, ",") != expected {
+		t.Fatalf("%s != %s", strings.Join(got, ","), expected)
+	}
+}
@@ -0,0 +1,66 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// MaxID is the largest user or group id, ids are unsigned 32 bit numbers on Linux and the BSDs
+const MaxID = math.MaxUint32
+
+// IDOverflowPolicy decides what happens to a uid or gid outside the 0 to MaxID range.
+type IDOverflowPolicy int
+
+const (
+	// IDOverflowAllow keeps any id that fits in an int, including negative ids found in some
+	// legacy files. This is the default and matches the behaviour of earlier versions.
+	IDOverflowAllow IDOverflowPolicy = iota
+	// IDOverflowError treats ids outside the 0 to MaxID range as a parse error
+	IDOverflowError
+	// IDOverflowWrap reduces ids modulo 2^32 like the kernel does, so the legacy nobody id of -2
+	// becomes 4294967294
+	IDOverflowWrap
+)
+
+// WithIDOverflow sets how uids and gids outside the 0 to MaxID range are handled while loading.
+func WithIDOverflow(policy IDOverflowPolicy) Option {
+	return func(o *options) {
+		o.idOverflow = policy
+	}
+}
+
+// parseID parses a uid or gid field according to the policy. Ids up to MaxID are accepted on
+// every platform; where int is 32 bits wide the ids above math.MaxInt32 are stored as their
+// two's complement so that Uid32 and Gid32 still return the right value.
+func parseID(value string, policy IDOverflowPolicy) (int, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > MaxID {
+		switch policy {
+		case IDOverflowError:
+			return 0, fmt.Errorf("id %d is outside the range 0 to %d", n, uint32(MaxID))
+		case IDOverflowWrap:
+			n = int64(uint32(n))
+		}
+	}
+	if n > math.MaxInt || n < math.MinInt {
+		if n > MaxID || n < math.MinInt32 {
+			return 0, fmt.Errorf("id %d does not fit in an int", n)
+		}
+		return int(int32(uint32(n))), nil
+	}
+	return int(n), nil
+}
+
+// Uid32 function returns the user id as the unsigned 32 bit value used by the kernel
+func (e *EtcPasswdEntry) Uid32() uint32 {
+	return uint32(e.uid)
+}
+
+// Gid32 function returns the primary group id as the unsigned 32 bit value used by the kernel
+func (e *EtcPasswdEntry) Gid32() uint32 {
+	return uint32(e.gid)
+}
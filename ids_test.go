@@ -0,0 +1,73 @@
+package etcpwdparse
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLargeIDs(t *testing.T) {
+	entry, err := ParsePasswdLine("nobody:x:4294967294:4294967294:Nobody:/:/sbin/nologin")
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry.Uid32() != 4294967294 || entry.Gid32() != 4294967294 {
+		t.Fatalf("%d != 4294967294", entry.Uid32())
+	}
+
+	legacy := "nfsnobody:x:-2:-2::/:/sbin/nologin\n"
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(legacy)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry, _ := cache.LookupUserByName("nfsnobody"); entry.Uid() != -2 || entry.Uid32() != 4294967294 {
+		t.Fatalf("unexpected uid %d", entry.Uid())
+	}
+
+	cache = NewEtcPasswdCache(false, WithIDOverflow(IDOverflowWrap))
+	if err := cache.LoadFromReader(strings.NewReader(legacy + "big:x:4294967296:4294967297::/:/bin/sh\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry, _ := cache.LookupUserByName("nfsnobody"); entry.Uid32() != 4294967294 || (strconv.IntSize == 64 && int64(entry.Uid()) != 4294967294) {
+		t.Fatalf("unexpected uid %d", entry.Uid())
+	}
+	if entry, _ := cache.LookupUserByName("big"); entry.Uid() != 0 || entry.Gid() != 1 {
+		t.Fatalf("unexpected ids %d %d", entry.Uid(), entry.Gid())
+	}
+
+	err = NewEtcPasswdCache(false, WithIDOverflow(IDOverflowError)).LoadFromReader(strings.NewReader(legacy))
+	if pe, ok := err.(*ParseError); !ok || pe.Field != "uid" || pe.LineNumber != 1 {
+		t.Fatalf("expected a uid error, got %v", err)
+	}
+	err = NewEtcPasswdCache(false, WithIDOverflow(IDOverflowError)).LoadFromReader(strings.NewReader("big:x:1:4294967296::/:/bin/sh\n"))
+	if pe, ok := err.(*ParseError); !ok || pe.Field != "gid" {
+		t.Fatalf("expected a gid error, got %v", err)
+	}
+	if _, err := ParsePasswdLine("big:x:99999999999999999999:0::/:/bin/sh"); err == nil {
+		t.Fatalf("Should have failed on an id that does not fit in 64 bits")
+	}
+}
+
+func TestLargeGroupIDs(t *testing.T) {
+	entry, err := ParseGroupLine("nogroup:x:4294967294:")
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry.Gid32() != 4294967294 {
+		t.Fatalf("%d != 4294967294", entry.Gid32())
+	}
+
+	legacy := "nfsnobody:x:-2:\n"
+	cache := NewEtcGroupCache(false, WithIDOverflow(IDOverflowWrap))
+	if err := cache.LoadFromReader(strings.NewReader(legacy)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry, _ := cache.LookupGroupByName("nfsnobody"); entry.Gid32() != 4294967294 || (strconv.IntSize == 64 && int64(entry.Gid()) != 4294967294) {
+		t.Fatalf("unexpected gid %d", entry.Gid())
+	}
+
+	err = NewEtcGroupCache(false, WithIDOverflow(IDOverflowError)).LoadFromReader(strings.NewReader(legacy))
+	if pe, ok := err.(*ParseError); !ok || pe.Field != "gid" || pe.LineNumber != 1 {
+		t.Fatalf("expected a gid error, got %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package etcpwdparse
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// imageFiles are the files extracted from the merged filesystem of an image.
+var imageFiles = []string{"etc/passwd", "etc/group"}
+
+// maxImageFile bounds the size of the manifest, index, and account files read from an image.
+const maxImageFile = 16 << 20
+
+// ImageAccounts holds the user and group files found in a container image. Either cache is nil
+// if the file does not exist in the image, as is the case for images built FROM scratch.
+type ImageAccounts struct {
+	Passwd *EtcPasswdCache
+	Groups *EtcGroupCache
+}
+
+// imageLayer is what a single layer contributes to the tracked files.
+type imageLayer struct {
+	files     map[string][]byte
+	whiteouts []string
+	opaque    []string
+}
+
+// LoadImageTarball reads the etc/passwd and etc/group files of a container image saved with
+// `docker save` or in an OCI image layout tarball, without running it. The layers are applied
+// in order, honouring whiteout and opaque directory markers. For multi-platform images the
+// first manifest in the index is used. Only regular files are followed, and gzip compressed or
+// uncompressed layers are supported.
+func LoadImageTarball(tarball string, opts ...Option) (*ImageAccounts, error) {
+	layers, err := imageLayerPaths(tarball)
+	if err != nil {
+		return nil, err
+	}
+	// the same blob may be used for several layers
+	order := make(map[string][]int)
+	for i, name := range layers {
+		order[name] = append(order[name], i)
+	}
+	contents := make([]*imageLayer, len(layers))
+	err = walkTarball(tarball, func(name string, r io.Reader) error {
+		indexes, ok := order[name]
+		if !ok || contents[indexes[0]] != nil {
+			return nil
+		}
+		layer, err := readImageLayer(r)
+		if err != nil {
+			return fmt.Errorf("Image layer %s could not be read: %w", name, err)
+		}
+		for _, i := range indexes {
+			contents[i] = &layer
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, layer := range contents {
+		if layer == nil {
+			return nil, fmt.Errorf("Image tarball is missing layer %s", layers[i])
+		}
+	}
+
+	merged := make(map[string][]byte)
+	for _, layer := range contents {
+		for target := range merged {
+			for _, dir := range layer.opaque {
+				if len(dir) == 0 || strings.HasPrefix(target, dir+"/") {
+					delete(merged, target)
+				}
+			}
+			for _, removed := range layer.whiteouts {
+				if target == removed || strings.HasPrefix(target, removed+"/") {
+					delete(merged, target)
+				}
+			}
+		}
+		for target, data := range layer.files {
+			merged[target] = data
+		}
+	}
+
+	result := &ImageAccounts{}
+	if data, ok := merged["etc/passwd"]; ok {
+		result.Passwd = NewEtcPasswdCache(false, opts...)
+		if err := result.Passwd.LoadFromReader(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	}
+	if data, ok := merged["etc/group"]; ok {
+		result.Groups = NewEtcGroupCache(false, opts...)
+		if err := result.Groups.LoadFromReader(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// walkTarball calls fn for each regular file in the tarball with its cleaned name.
+func walkTarball(tarball string, fn func(name string, r io.Reader) error) error {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := tar.NewReader(f)
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(cleanImagePath(header.Name), r); err != nil {
+			return err
+		}
+	}
+}
+
+// cleanImagePath normalizes a tar member name to a relative slash separated path.
+func cleanImagePath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// readImageFiles reads the given files from the tarball, returning the ones found.
+func readImageFiles(tarball string, names ...string) (map[string][]byte, error) {
+	results := make(map[string][]byte)
+	err := walkTarball(tarball, func(name string, r io.Reader) error {
+		for _, wanted := range names {
+			if name == wanted {
+				data, err := readImageFile(r, name)
+				if err != nil {
+					return err
+				}
+				results[name] = data
+			}
+		}
+		return nil
+	})
+	return results, err
+}
+
+// imageLayerPaths returns the tarball paths of the image layers from the bottom up, using the
+// docker manifest.json if it exists and the OCI index.json otherwise.
+func imageLayerPaths(tarball string) ([]string, error) {
+	files, err := readImageFiles(tarball, "manifest.json", "index.json")
+	if err != nil {
+		return nil, err
+	}
+	if data, ok := files["manifest.json"]; ok {
+		var manifests []struct {
+			Layers []string `json:"Layers"`
+		}
+		if err := json.Unmarshal(data, &manifests); err != nil {
+			return nil, fmt.Errorf("Image manifest.json was badly formatted: %w", err)
+		}
+		if len(manifests) == 0 {
+			return nil, fmt.Errorf("Image manifest.json lists no images")
+		}
+		results := make([]string, 0, len(manifests[0].Layers))
+		for _, layer := range manifests[0].Layers {
+			results = append(results, cleanImagePath(layer))
+		}
+		return results, nil
+	}
+	data, ok := files["index.json"]
+	if !ok {
+		return nil, fmt.Errorf("Image tarball has neither manifest.json nor index.json")
+	}
+	// follow nested indexes down to the first image manifest
+	for depth := 0; depth < 8; depth++ {
+		var document struct {
+			Manifests []struct {
+				Digest string `json:"digest"`
+			} `json:"manifests"`
+			Layers []struct {
+				Digest string `json:"digest"`
+			} `json:"layers"`
+		}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("Image index was badly formatted: %w", err)
+		}
+		if len(document.Manifests) == 0 {
+			results := make([]string, 0, len(document.Layers))
+			for _, layer := range document.Layers {
+				results = append(results, blobPath(layer.Digest))
+			}
+			return results, nil
+		}
+		name := blobPath(document.Manifests[0].Digest)
+		blobs, err := readImageFiles(tarball, name)
+		if err != nil {
+			return nil, err
+		}
+		if data, ok = blobs[name]; !ok {
+			return nil, fmt.Errorf("Image tarball is missing blob %s", name)
+		}
+	}
+	return nil, fmt.Errorf("Image index nests too deeply")
+}
+
+// readImageFile reads a whole file from the tarball, failing if it is larger than maxImageFile.
+func readImageFile(r io.Reader, name string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxImageFile+1))
+	if err == nil && len(data) > maxImageFile {
+		return nil, fmt.Errorf("Image file %s is larger than %d bytes", name, maxImageFile)
+	}
+	return data, err
+}
+
+// blobPath returns the OCI layout path of the blob with the given digest.
+func blobPath(digest string) string {
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	return path.Join("blobs", algorithm, hex)
+}
+
+// readImageLayer extracts the tracked files and the whiteouts that may affect them from a
+// layer tar, which may be gzip compressed.
+func readImageLayer(r io.Reader) (imageLayer, error) {
+	result := imageLayer{files: make(map[string][]byte)}
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return result, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = buffered
+	}
+
+	layer := tar.NewReader(r)
+	for {
+		header, err := layer.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		name := cleanImagePath(header.Name)
+		dir, base := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+		if base == ".wh..wh..opq" {
+			result.opaque = append(result.opaque, dir)
+			continue
+		}
+		if strings.HasPrefix(base, ".wh.") {
+			result.whiteouts = append(result.whiteouts, path.Join(dir, strings.TrimPrefix(base, ".wh.")))
+			continue
+		}
+		for _, wanted := range imageFiles {
+			if name != wanted {
+				continue
+			}
+			if header.Typeflag != tar.TypeReg {
+				// a directory or link replaces whatever the lower layers had
+				result.whiteouts = append(result.whiteouts, name)
+				continue
+			}
+			data, err := readImageFile(layer, name)
+			if err != nil {
+				return result, err
+			}
+			result.files[name] = data
+		}
+	}
+}
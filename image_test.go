@@ -0,0 +1,119 @@
+package etcpwdparse
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarFile is a member of a tarball built by buildTar.
+type tarFile struct {
+	name string
+	data []byte
+}
+
+func buildTar(t *testing.T, files ...tarFile) []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, f := range files {
+		if err := w.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		w.Write(f.data)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func writeTarball(t *testing.T, data []byte) string {
+	tempDir, _ := os.MkdirTemp("", "image")
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+	p := filepath.Join(tempDir, "image.tar")
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	return p
+}
+
+func TestLoadImageTarballDocker(t *testing.T) {
+	base := gzipBytes(buildTar(t,
+		tarFile{"./etc/passwd", []byte("root:x:0:0:root:/root:/bin/sh\n")},
+		tarFile{"./etc/group", []byte("root:x:0:\n")},
+	))
+	top := buildTar(t,
+		tarFile{"etc/.wh.group", nil},
+		tarFile{"etc/passwd", []byte("root:x:0:0:root:/root:/bin/sh\nnonroot:x:65532:65532::/home/nonroot:/sbin/nologin\n")},
+	)
+	image := buildTar(t,
+		tarFile{"top/layer.tar", top},
+		tarFile{"base/layer.tar", base},
+		tarFile{"manifest.json", []byte(`[{"Config":"config.json","RepoTags":["example:latest"],"Layers":["base/layer.tar","top/layer.tar"]}]`)},
+	)
+
+	accounts, err := LoadImageTarball(writeTarball(t, image))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if accounts.Groups != nil {
+		t.Fatalf("the group file should have been whited out")
+	}
+	if len(accounts.Passwd.ListEntries()) != 2 {
+		t.Fatalf("%d != 2", len(accounts.Passwd.ListEntries()))
+	}
+	if _, ok := accounts.Passwd.LookupUserByName("nonroot"); !ok {
+		t.Fatalf("nonroot should have been found")
+	}
+}
+
+func TestLoadImageTarballOCI(t *testing.T) {
+	base := gzipBytes(buildTar(t,
+		tarFile{"etc/passwd", []byte("root:x:0:0:root:/root:/bin/sh\n")},
+		tarFile{"etc/group", []byte("root:x:0:\n")},
+	))
+	top := buildTar(t,
+		tarFile{"etc/.wh..wh..opq", nil},
+		tarFile{"etc/group", []byte("root:x:0:\nstaff:x:50:\n")},
+	)
+	image := buildTar(t,
+		tarFile{"oci-layout", []byte(`{"imageLayoutVersion":"1.0.0"}`)},
+		tarFile{"index.json", []byte(`{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"sha256:index"}]}`)},
+		tarFile{"blobs/sha256/index", []byte(`{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:manifest"}]}`)},
+		tarFile{"blobs/sha256/manifest", []byte(`{"schemaVersion":2,"layers":[{"digest":"sha256:base"},{"digest":"sha256:top"}]}`)},
+		tarFile{"blobs/sha256/base", base},
+		tarFile{"blobs/sha256/top", top},
+	)
+
+	accounts, err := LoadImageTarball(writeTarball(t, image))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if accounts.Passwd != nil {
+		t.Fatalf("the passwd file should have been hidden by the opaque directory")
+	}
+	if _, ok := accounts.Groups.LookupGroupByName("staff"); !ok {
+		t.Fatalf("staff should have been found")
+	}
+
+	missing := buildTar(t,
+		tarFile{"manifest.json", []byte(`[{"Layers":["missing/layer.tar"]}]`)},
+	)
+	if _, err := LoadImageTarball(writeTarball(t, missing)); err == nil {
+		t.Fatalf("Should have failed on a missing layer")
+	}
+	if _, err := LoadImageTarball(writeTarball(t, buildTar(t))); err == nil {
+		t.Fatalf("Should have failed without a manifest")
+	}
+}
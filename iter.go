@@ -0,0 +1,62 @@
+package etcpwdparse
+
+import (
+	"iter"
+	"sort"
+)
+
+// snapshotEntries returns the current entries slice. Existing elements of the slice are never
+// modified in place, it is only appended to or replaced, so it can be iterated without holding
+// the lock. This means the iterators below see the content as it was when iteration started.
+func (e *EtcPasswdCache) snapshotEntries() []*EtcPasswdEntry {
+	e.refresh()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.entries
+}
+
+// All returns an iterator over the entries in file order. Unlike ListEntries it does not copy
+// the entries into a new slice.
+func (e *EtcPasswdCache) All() iter.Seq[*EtcPasswdEntry] {
+	return func(yield func(*EtcPasswdEntry) bool) {
+		entries := e.snapshotEntries()
+		for _, entry := range entries {
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+}
+
+// ByUid returns an iterator over the entries ordered by user id. Entries with the same user id
+// keep their file order.
+func (e *EtcPasswdCache) ByUid() iter.Seq[*EtcPasswdEntry] {
+	return e.sortedBy(func(a, b *EtcPasswdEntry) bool {
+		return a.uid < b.uid
+	})
+}
+
+// ByName returns an iterator over the entries ordered by username. Entries with the same
+// username keep their file order.
+func (e *EtcPasswdCache) ByName() iter.Seq[*EtcPasswdEntry] {
+	return e.sortedBy(func(a, b *EtcPasswdEntry) bool {
+		return a.username < b.username
+	})
+}
+
+// sortedBy returns an iterator over the entries in the order defined by less.
+func (e *EtcPasswdCache) sortedBy(less func(a, b *EtcPasswdEntry) bool) iter.Seq[*EtcPasswdEntry] {
+	return func(yield func(*EtcPasswdEntry) bool) {
+		entries := e.snapshotEntries()
+		sorted := make([]*EtcPasswdEntry, len(entries))
+		copy(sorted, entries)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return less(sorted[i], sorted[j])
+		})
+		for _, entry := range sorted {
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIterators(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	names := make([]string, 0)
+	for entry := range cache.All() {
+		names = append(names, entry.Username())
+		if len(names) == 3 {
+			break
+		}
+	}
+	if strings.Join(names, ",") != "root,bin,daemon" {
+		t.Fatalf("%v != [root bin daemon]", names)
+	}
+
+	names = names[:0]
+	for entry := range cache.ByName() {
+		names = append(names, entry.Username())
+	}
+	if names[0] != "adm" || names[len(names)-1] != "sync" {
+		t.Fatalf("unexpected name order %v", names)
+	}
+
+	last := -1
+	count := 0
+	for entry := range cache.ByUid() {
+		if entry.Uid() < last {
+			t.Fatalf("%d came after %d", entry.Uid(), last)
+		}
+		last = entry.Uid()
+		count++
+	}
+	if count != 13 {
+		t.Fatalf("%d != 13", count)
+	}
+}
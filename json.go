@@ -0,0 +1,92 @@
+package etcpwdparse
+
+import (
+	"encoding/json"
+)
+
+// jsonEntry is the JSON representation of an EtcPasswdEntry.
+type jsonEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Uid      int    `json:"uid"`
+	Gid      int    `json:"gid"`
+	Info     string `json:"info"`
+	Homedir  string `json:"homedir"`
+	Shell    string `json:"shell"`
+	// the BSD master.passwd fields are only written when they are set
+	Class  string `json:"class,omitempty"`
+	Change int64  `json:"change,omitempty"`
+	Expire int64  `json:"expire,omitempty"`
+}
+
+// MarshalJSON encodes the entry as a JSON object with a key for each of the 7 fields, and for
+// the BSD class, change, and expire fields when they are set.
+func (e EtcPasswdEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonEntry{
+		Username: e.username,
+		Password: e.password,
+		Uid:      e.uid,
+		Gid:      e.gid,
+		Info:     e.info,
+		Homedir:  e.homedir,
+		Shell:    e.shell,
+		Class:    e.class,
+		Change:   e.change,
+		Expire:   e.expire,
+	})
+}
+
+// UnmarshalJSON decodes an entry from the object produced by MarshalJSON. The fields are
+// validated in the same way as NewEtcPasswdEntry, but negative ids are accepted like the parser
+// does so that any loaded content survives a round trip.
+func (e *EtcPasswdEntry) UnmarshalJSON(data []byte) error {
+	var raw jsonEntry
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	entry := EtcPasswdEntry{
+		username: raw.Username,
+		password: raw.Password,
+		uid:      raw.Uid,
+		gid:      raw.Gid,
+		info:     raw.Info,
+		homedir:  raw.Homedir,
+		shell:    raw.Shell,
+		class:    raw.Class,
+		change:   raw.Change,
+		expire:   raw.Expire,
+	}
+	if err := validateFields(&entry); err != nil {
+		return err
+	}
+	*e = entry
+	return nil
+}
+
+// ToJSON encodes all the entries in the cache as a JSON array in file order.
+func (e *EtcPasswdCache) ToJSON() ([]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	entries := e.entries
+	if entries == nil {
+		entries = make([]*EtcPasswdEntry, 0)
+	}
+	return json.Marshal(entries)
+}
+
+// FromJSON replaces the cached content with the entries decoded from a JSON array as produced
+// by ToJSON. The existing content is left untouched if decoding fails.
+func (e *EtcPasswdCache) FromJSON(data []byte) error {
+	var entries []EtcPasswdEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	next := e.newLoadTarget("")
+	for _, entry := range entries {
+		if err := next.addWithPolicy(entry, ""); err != nil {
+			return err
+		}
+	}
+	e.replaceContent(next)
+	return nil
+}
@@ -0,0 +1,104 @@
+package etcpwdparse
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEntryJSON(t *testing.T) {
+	entry, _ := ParsePasswdLine("ftp:x:14:50:FTP User:/var/ftp:/sbin/nologin")
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	expected := `{"username":"ftp","password":"x","uid":14,"gid":50,"info":"FTP User","homedir":"/var/ftp","shell":"/sbin/nologin"}`
+	if string(data) != expected {
+		t.Fatalf("%s != %s", string(data), expected)
+	}
+
+	var decoded EtcPasswdEntry
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if decoded != entry {
+		t.Fatalf("%+v != %+v", decoded, entry)
+	}
+
+	if err := json.Unmarshal([]byte(`{"username":"","uid":1}`), &decoded); err == nil {
+		t.Fatalf("Should have failed on an empty username")
+	}
+}
+
+func TestCacheJSON(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	data, err := cache.ToJSON()
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	other := NewEtcPasswdCache(false)
+	if err := other.FromJSON(data); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(other.ListEntries()) != 13 {
+		t.Fatalf("%d != 13", len(other.ListEntries()))
+	}
+	if hd, _ := other.HomeDirForUsername("games"); hd != "/usr/games" {
+		t.Fatalf("%s != /usr/games", hd)
+	}
+
+	if err := other.FromJSON([]byte(`[{"username":"bad:name"}]`)); err == nil {
+		t.Fatalf("Should have failed on a bad username")
+	}
+	if len(other.ListEntries()) != 13 {
+		t.Fatalf("content should have been left untouched")
+	}
+
+	empty, _ := NewEtcPasswdCache(false).ToJSON()
+	if string(empty) != "[]" {
+		t.Fatalf("%s != []", string(empty))
+	}
+}
+
+func TestCacheJSONRoundTrip(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nnobody:*:-2:-2:Unprivileged User:/var/empty:/usr/bin/false\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	data, err := cache.ToJSON()
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	other := NewEtcPasswdCache(false)
+	if err := other.FromJSON(data); err != nil {
+		t.Fatalf("negative ids accepted by the parser should round trip: %s", err)
+	}
+	if nobody, ok := other.LookupUserByUid(-2); !ok || nobody.Gid() != -2 {
+		t.Fatalf("nobody should have kept its ids")
+	}
+
+	bsd := NewEtcPasswdCache(false, WithDialect(DialectBSD))
+	if err := bsd.LoadFromReader(strings.NewReader(fakeMasterPasswdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	data, err = bsd.ToJSON()
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if !strings.Contains(string(data), `"class":"staff","change":1700000000,"expire":1800000000`) {
+		t.Fatalf("the BSD fields should have been encoded: %s", data)
+	}
+	other = NewEtcPasswdCache(false, WithDialect(DialectBSD))
+	if err := other.FromJSON(data); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	before, _ := bsd.LookupUserByName("alice")
+	after, _ := other.LookupUserByName("alice")
+	if *after != *before {
+		t.Fatalf("%+v != %+v", after, before)
+	}
+}
@@ -0,0 +1,282 @@
+package etcpwdparse
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ldifLineLength is the length at which LDIF lines are folded, as recommended by RFC 2849.
+const ldifLineLength = 76
+
+// ldifSafe returns true if the value can be written as is rather than base64 encoded as RFC 2849
+// requires for values with leading spaces, colons, or angle brackets, trailing spaces, or
+// characters outside printable ASCII.
+func ldifSafe(value string) bool {
+	if len(value) == 0 {
+		return true
+	}
+	if value[0] == ' ' || value[0] == ':' || value[0] == '<' || value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < 0x20 || value[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// writeLDIFAttribute writes a single attribute, base64 encoding and folding it as needed.
+func writeLDIFAttribute(w *bufio.Writer, name, value string) {
+	line := name + ": " + value
+	if !ldifSafe(value) {
+		line = name + ":: " + base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	for len(line) > ldifLineLength {
+		w.WriteString(line[:ldifLineLength])
+		w.WriteString("\n ")
+		line = line[ldifLineLength:]
+	}
+	w.WriteString(line)
+	w.WriteString("\n")
+}
+
+// escapeDNValue escapes a value for use in a distinguished name as described in RFC 4514.
+func escapeDNValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case strings.IndexByte(",+\"\\<>;", c) >= 0,
+			c == '#' && i == 0,
+			c == ' ' && (i == 0 || i == len(value)-1):
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// WriteLDIF writes every entry in file order as a posixAccount LDIF record named by uid below
+// the given base DN, such as "ou=People,dc=example,dc=com", for loading into an LDAP directory.
+// The cn is taken from the full name in the gecos field or the username if that is empty, and
+// a password hash held in the entry itself is written as a {crypt} userPassword.
+func (e *EtcPasswdCache) WriteLDIF(w io.Writer, baseDN string) error {
+	bw := bufio.NewWriter(w)
+	for i, entry := range e.snapshotEntries() {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		writeLDIFEntry(bw, entry, baseDN)
+	}
+	return bw.Flush()
+}
+
+// writeLDIFEntry writes a single posixAccount record.
+func writeLDIFEntry(w *bufio.Writer, entry *EtcPasswdEntry, baseDN string) {
+	dn := "uid=" + escapeDNValue(entry.username)
+	if len(baseDN) > 0 {
+		dn += "," + baseDN
+	}
+	cn := entry.Gecos().FullName
+	if len(cn) == 0 {
+		cn = entry.username
+	}
+	writeLDIFAttribute(w, "dn", dn)
+	for _, class := range []string{"top", "account", "posixAccount"} {
+		writeLDIFAttribute(w, "objectClass", class)
+	}
+	attributes := []struct {
+		name  string
+		value string
+	}{
+		{"cn", cn},
+		{"uid", entry.username},
+		{"uidNumber", strconv.Itoa(entry.uid)},
+		{"gidNumber", strconv.Itoa(entry.gid)},
+		{"homeDirectory", entry.homedir},
+		{"loginShell", entry.shell},
+		{"gecos", entry.info},
+	}
+	for _, a := range attributes {
+		if len(a.value) > 0 {
+			writeLDIFAttribute(w, a.name, a.value)
+		}
+	}
+	if ClassifyPassword(entry.password) == PasswordHash {
+		writeLDIFAttribute(w, "userPassword", fmt.Sprintf("{crypt}%s", entry.password))
+	}
+}
+
+// ldifRecord is a parsed LDIF record, with attribute names in lower case.
+type ldifRecord struct {
+	lineNumber int
+	dn         string
+	attributes map[string][]string
+}
+
+// first returns the first value of the named attribute
+func (r *ldifRecord) first(name string) (string, bool) {
+	values := r.attributes[name]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// readLDIF splits LDIF content into records, unfolding continuation lines and decoding base64
+// values. Values given by URL with ":<" are rejected.
+func readLDIF(r io.Reader) ([]*ldifRecord, error) {
+	// unfold into logical lines first, an empty line ends a record
+	type logicalLine struct {
+		number int
+		text   string
+	}
+	lines := make([]logicalLine, 0)
+	lineNumber := 0
+	err := readRawLines(r, func(raw string) error {
+		lineNumber++
+		raw = strings.TrimSuffix(raw, "\r")
+		switch {
+		case strings.HasPrefix(raw, " "):
+			if last := len(lines) - 1; last >= 0 && len(lines[last].text) > 0 {
+				lines[last].text += raw[1:]
+			}
+		case strings.HasPrefix(raw, "#"):
+		case len(strings.TrimSpace(raw)) == 0:
+			lines = append(lines, logicalLine{number: lineNumber})
+		default:
+			lines = append(lines, logicalLine{number: lineNumber, text: raw})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*ldifRecord, 0)
+	var current *ldifRecord
+	for _, line := range lines {
+		if len(line.text) == 0 {
+			current = nil
+			continue
+		}
+		name, value, ok := strings.Cut(line.text, ":")
+		if !ok {
+			return nil, &ParseError{LineNumber: line.number, RawLine: line.text, Err: fmt.Errorf("LDIF line had no ':'")}
+		}
+		switch {
+		case strings.HasPrefix(value, ":"):
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[1:]))
+			if err != nil {
+				return nil, &ParseError{LineNumber: line.number, RawLine: line.text, Field: name, Err: fmt.Errorf("LDIF line had bad base64 value: %w", err)}
+			}
+			value = string(decoded)
+		case strings.HasPrefix(value, "<"):
+			return nil, &ParseError{LineNumber: line.number, RawLine: line.text, Field: name, Err: fmt.Errorf("LDIF values given by URL are not supported")}
+		default:
+			value = strings.TrimLeft(value, " ")
+		}
+		// attribute options such as ";lang-en" are dropped
+		name, _, _ = strings.Cut(strings.ToLower(name), ";")
+		if current == nil {
+			if name == "version" {
+				continue
+			}
+			current = &ldifRecord{lineNumber: line.number, attributes: make(map[string][]string)}
+			records = append(records, current)
+		}
+		if name == "dn" && len(current.dn) == 0 {
+			current.dn = value
+			continue
+		}
+		current.attributes[name] = append(current.attributes[name], value)
+	}
+	return records, nil
+}
+
+// ldifEntry converts a posixAccount record into an entry. The gecos attribute is used for the
+// info field, falling back to cn, and a {crypt} userPassword becomes the password field.
+func ldifEntry(record *ldifRecord) (EtcPasswdEntry, error) {
+	fail := func(field, format string, args ...interface{}) (EtcPasswdEntry, error) {
+		return EtcPasswdEntry{}, &ParseError{LineNumber: record.lineNumber, RawLine: "dn: " + record.dn, Field: field, Err: fmt.Errorf(format, args...)}
+	}
+	username, ok := record.first("uid")
+	if !ok {
+		return fail("username", "LDIF posixAccount had no uid")
+	}
+	ids := make([]int, 2)
+	for i, name := range []string{"uidnumber", "gidnumber"} {
+		value, ok := record.first(name)
+		if !ok {
+			return fail(name, "LDIF posixAccount had no %s", name)
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fail(name, "LDIF posixAccount had badly formatted %s %s", name, value)
+		}
+		ids[i] = id
+	}
+	info, ok := record.first("gecos")
+	if !ok {
+		info, _ = record.first("cn")
+	}
+	homedir, _ := record.first("homedirectory")
+	shell, _ := record.first("loginshell")
+	password := "x"
+	for _, value := range record.attributes["userpassword"] {
+		if len(value) > 7 && strings.EqualFold(value[:7], "{crypt}") {
+			password = value[7:]
+			break
+		}
+	}
+	entry, err := NewEtcPasswdEntry(username, password, ids[0], ids[1], info, homedir, shell)
+	if err != nil {
+		return fail("", "%s", err)
+	}
+	return entry, nil
+}
+
+// LoadFromLDIF replaces the cached content with the posixAccount records read from LDIF, such
+// as the output of ldapsearch or WriteLDIF. Records without the posixAccount object class, and
+// change records other than additions, are skipped. Bad records are skipped when ignoring bad
+// lines, otherwise they fail the load and the existing content is left untouched. Errors are
+// returned as a *ParseError.
+func (e *EtcPasswdCache) LoadFromLDIF(r io.Reader) error {
+	records, err := readLDIF(r)
+	if err != nil {
+		return err
+	}
+	next := e.newLoadTarget("")
+	for _, record := range records {
+		if change, ok := record.first("changetype"); ok && !strings.EqualFold(change, "add") {
+			continue
+		}
+		posix := false
+		for _, class := range record.attributes["objectclass"] {
+			posix = posix || strings.EqualFold(class, "posixAccount")
+		}
+		if !posix {
+			continue
+		}
+		entry, err := ldifEntry(record)
+		if err != nil {
+			if e.ignoreBadLines {
+				e.opts.badLine(record.lineNumber, "dn: "+record.dn, err)
+				continue
+			}
+			return err
+		}
+		if err := next.addWithPolicy(entry, ""); err != nil {
+			return err
+		}
+	}
+	e.replaceContent(next)
+	return nil
+}
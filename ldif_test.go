@@ -0,0 +1,161 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteLDIF(t *testing.T) {
+	content := "alice:x:1000:100:Alice Smith,Room 1,,:/home/alice:/bin/bash\n" +
+		"svc,1:$6$salt$hash:998:998::/var/lib/svc:\n" +
+		"jose:x:1001:100:José:/home/jose:/bin/zsh\n"
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := cache.WriteLDIF(buf, "ou=People,dc=example,dc=com"); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	expected := `dn: uid=alice,ou=People,dc=example,dc=com
+objectClass: top
+objectClass: account
+objectClass: posixAccount
+cn: Alice Smith
+uid: alice
+uidNumber: 1000
+gidNumber: 100
+homeDirectory: /home/alice
+loginShell: /bin/bash
+gecos: Alice Smith,Room 1,,
+
+dn: uid=svc\,1,ou=People,dc=example,dc=com
+objectClass: top
+objectClass: account
+objectClass: posixAccount
+cn: svc,1
+uid: svc,1
+uidNumber: 998
+gidNumber: 998
+homeDirectory: /var/lib/svc
+userPassword: {crypt}$6$salt$hash
+
+dn: uid=jose,ou=People,dc=example,dc=com
+objectClass: top
+objectClass: account
+objectClass: posixAccount
+cn:: Sm9zw6k=
+uid: jose
+uidNumber: 1001
+gidNumber: 100
+homeDirectory: /home/jose
+loginShell: /bin/zsh
+gecos:: Sm9zw6k=
+`
+	if buf.String() != expected {
+		t.Fatalf("%s != %s", buf.String(), expected)
+	}
+}
+
+func TestWriteLDIFAttributeFolding(t *testing.T) {
+	buf := new(bytes.Buffer)
+	cache := NewEtcPasswdCache(false)
+	cache.LoadFromReader(strings.NewReader("long:x:1:1:" + strings.Repeat("a", 100) + ":/:/bin/sh\n"))
+	cache.WriteLDIF(buf, "")
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if len(line) > ldifLineLength+1 {
+			t.Fatalf("line was not folded: %q", line)
+		}
+	}
+	if !strings.Contains(buf.String(), "\n "+strings.Repeat("a", 10)) {
+		t.Fatalf("expected a continuation line in %q", buf.String())
+	}
+	if !strings.HasPrefix(buf.String(), "dn: uid=long\n") {
+		t.Fatalf("an empty base DN should be left out: %q", buf.String())
+	}
+}
+
+const fakeLDIFContent = `version: 1
+
+# the organizational unit is skipped
+dn: ou=People,dc=example,dc=com
+objectClass: organizationalUnit
+ou: People
+
+dn: uid=alice,ou=People,dc=example,dc=com
+objectClass: top
+objectClass: account
+objectClass: posixAccount
+objectClass: shadowAccount
+cn: Alice Smith
+uid: alice
+uidNumber: 1000
+gidNumber: 100
+homeDirectory: /home/alice
+loginShell: /bin/bash
+userPassword: {CRYPT}$6$salt$hash
+
+dn: uid=jose,ou=People,dc=example,dc=com
+objectclass: posixAccount
+cn;lang-en: Jose
+uid: jose
+uidNumber: 1001
+gidNumber: 100
+homeDirectory: /home/j
+ ose
+gecos:: Sm9zw6k=
+
+dn: uid=bob,ou=People,dc=example,dc=com
+changetype: delete
+`
+
+func TestLoadFromLDIF(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromLDIF(strings.NewReader(fakeLDIFContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(cache.ListEntries()) != 2 {
+		t.Fatalf("%d != 2", len(cache.ListEntries()))
+	}
+	alice, _ := cache.LookupUserByName("alice")
+	if alice.String() != "alice:$6$salt$hash:1000:100:Alice Smith:/home/alice:/bin/bash" {
+		t.Fatalf("unexpected entry %s", alice)
+	}
+	jose, _ := cache.LookupUserByName("jose")
+	if jose.String() != "jose:x:1001:100:José:/home/jose:" {
+		t.Fatalf("unexpected entry %s", jose)
+	}
+
+	// WriteLDIF output loads back to the same entries
+	original := NewEtcPasswdCache(false)
+	original.LoadFromReader(strings.NewReader(fakePwdContent))
+	buf := new(bytes.Buffer)
+	original.WriteLDIF(buf, "ou=People,dc=example,dc=com")
+	if err := cache.LoadFromLDIF(buf); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if d := Diff(original, cache); !d.Empty() {
+		t.Fatalf("round trip should not have changed anything: %+v", d)
+	}
+
+	bad := "dn: uid=bad\nobjectClass: posixAccount\nuid: bad\nuidNumber: abc\ngidNumber: 1\n"
+	err := cache.LoadFromLDIF(strings.NewReader("dn: uid=ok\nobjectClass: posixAccount\nuid: ok\nuidNumber: 1\ngidNumber: 1\nhomeDirectory: /\n\n" + bad))
+	pe, ok := err.(*ParseError)
+	if !ok || pe.LineNumber != 8 || pe.Field != "uidnumber" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(cache.ListEntries()) != len(original.ListEntries()) {
+		t.Fatalf("a failed load should leave the content untouched")
+	}
+	lenient := NewEtcPasswdCache(true)
+	if err := lenient.LoadFromLDIF(strings.NewReader(bad + "\n" + fakeLDIFContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(lenient.ListEntries()) != 2 {
+		t.Fatalf("%d != 2", len(lenient.ListEntries()))
+	}
+	if err := cache.LoadFromLDIF(strings.NewReader("dn: uid=x\njpegPhoto:< file:///tmp/x\n")); err == nil {
+		t.Fatalf("Should have failed on a URL value")
+	}
+}
@@ -0,0 +1,38 @@
+package etcpwdparse
+
+import (
+	"strings"
+)
+
+// minLenientFields is the fewest fields a lenient line may have: the username, password, uid,
+// and gid.
+const minLenientFields = 4
+
+// WithLenientLines makes the passwd parser accept lines written by tools that leave out trailing
+// fields or add trailing colons, instead of rejecting the whole user. A line with 4 to 6 fields
+// gets the missing info, home directory, and shell fields as empty values; an empty shell is
+// treated as /bin/sh by login(1). Extra fields after the shell are dropped as long as they are
+// empty. Lines without a uid and gid are still rejected. The original line is written back as
+// long as the entry is not changed. This only applies to the standard 7 field format.
+func WithLenientLines() Option {
+	return func(o *options) {
+		o.lenient = true
+	}
+}
+
+// splitLenientFields is like splitFields but pads short lines with empty fields and drops empty
+// trailing fields. It returns len(parts) if the line was accepted.
+func splitLenientFields(line string, parts []string) int {
+	fields := strings.Split(line, ":")
+	for len(fields) > len(parts) && len(fields[len(fields)-1]) == 0 {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) < minLenientFields || len(fields) > len(parts) {
+		return len(fields)
+	}
+	for i := range parts {
+		parts[i] = ""
+	}
+	copy(parts, fields)
+	return len(parts)
+}
@@ -0,0 +1,56 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const fakeLenientContent = `root:x:0:0:root:/root:/bin/bash
+svc:x:900:900:Service:/var/lib/svc
+batch:x:901:901
+alice:x:1000:1000:Alice:/home/alice:/bin/bash::
+`
+
+func TestLenientLines(t *testing.T) {
+	if err := NewEtcPasswdCache(false).LoadFromReader(strings.NewReader(fakeLenientContent)); err == nil {
+		t.Fatalf("Should have failed without lenient lines")
+	}
+
+	cache := NewEtcPasswdCache(false, WithLenientLines())
+	if err := cache.LoadFromReader(strings.NewReader(fakeLenientContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry, _ := cache.LookupUserByName("svc"); entry.Homedir() != "/var/lib/svc" || entry.Shell() != "" {
+		t.Fatalf("unexpected svc entry %v", entry)
+	}
+	if entry, _ := cache.LookupUserByName("batch"); entry.Gid() != 901 || entry.Info() != "" || entry.Homedir() != "" {
+		t.Fatalf("unexpected batch entry %v", entry)
+	}
+	if entry, _ := cache.LookupUserByName("alice"); entry.Shell() != "/bin/bash" {
+		t.Fatalf("%q != /bin/bash", entry.Shell())
+	}
+
+	// unchanged lines are written back as they were
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	if buf.String() != fakeLenientContent {
+		t.Fatalf("%q != %q", buf.String(), fakeLenientContent)
+	}
+	cache.ModifyUser("svc", func(b *EtcPasswdEntryBuilder) { b.Shell("/usr/sbin/nologin") })
+	buf.Reset()
+	cache.WriteTo(buf)
+	if !strings.Contains(buf.String(), "\nsvc:x:900:900:Service:/var/lib/svc:/usr/sbin/nologin\n") {
+		t.Fatalf("unexpected content %q", buf.String())
+	}
+
+	bad := []string{
+		"nobody:x:65534\n",
+		"carol:x:1002:1002:Carol:/home/carol:/bin/sh:extra\n",
+	}
+	for _, content := range bad {
+		if err := NewEtcPasswdCache(false, WithLenientLines()).LoadFromReader(strings.NewReader(content)); err == nil {
+			t.Fatalf("Should have failed to load %q", content)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package etcpwdparse
+
+// LibcSource is a UserSource that calls getpwnam_r, getpwuid_r, and getpwent from the C
+// library, so the answers go through the full NSS stack of the host. It is only functional
+// when the package is built with cgo and the etcpwdparse_libc build tag, otherwise every
+// lookup misses; see LibcAvailable. It is intended for cross-checking the file parser or as the
+// last source of a ChainSource, the pure Go file parser stays the default.
+type LibcSource struct{}
@@ -0,0 +1,104 @@
+//go:build cgo && etcpwdparse_libc
+
+package etcpwdparse
+
+/*
+#include <errno.h>
+#include <pwd.h>
+#include <stdlib.h>
+#include <sys/types.h>
+#include <unistd.h>
+*/
+import "C"
+
+import (
+	"iter"
+	"sync"
+	"unsafe"
+)
+
+// LibcAvailable is true when the package was built with the etcpwdparse_libc tag and cgo, so
+// that LibcSource calls into the C library.
+const LibcAvailable = true
+
+// maxLibcBuffer bounds the buffer grown for getpwnam_r and getpwuid_r on ERANGE.
+const maxLibcBuffer = 1 << 20
+
+// libcEnumerateMu serializes setpwent, getpwent, and endpwent, which share global state.
+var libcEnumerateMu sync.Mutex
+
+// passwdFromC copies the C struct into an entry.
+func passwdFromC(pwd *C.struct_passwd) *EtcPasswdEntry {
+	return &EtcPasswdEntry{
+		username: C.GoString(pwd.pw_name),
+		password: C.GoString(pwd.pw_passwd),
+		uid:      int(pwd.pw_uid),
+		gid:      int(pwd.pw_gid),
+		info:     C.GoString(pwd.pw_gecos),
+		homedir:  C.GoString(pwd.pw_dir),
+		shell:    C.GoString(pwd.pw_shell),
+	}
+}
+
+// libcLookup calls one of the reentrant lookup functions, growing the buffer until it fits.
+func libcLookup(fn func(pwd *C.struct_passwd, buf *C.char, size C.size_t, result **C.struct_passwd) C.int) (*EtcPasswdEntry, bool) {
+	size := C.size_t(C.sysconf(C._SC_GETPW_R_SIZE_MAX))
+	if C.long(size) <= 0 {
+		size = 1024
+	}
+	for {
+		buf := C.malloc(size)
+		var pwd C.struct_passwd
+		var result *C.struct_passwd
+		rv := fn(&pwd, (*C.char)(buf), size, &result)
+		if rv == C.ERANGE && size < maxLibcBuffer {
+			C.free(buf)
+			size *= 2
+			continue
+		}
+		defer C.free(buf)
+		if rv != 0 || result == nil {
+			return nil, false
+		}
+		return passwdFromC(&pwd), true
+	}
+}
+
+// LookupByName returns the entry for the given username from getpwnam_r
+func (s *LibcSource) LookupByName(name string) (*EtcPasswdEntry, bool) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return libcLookup(func(pwd *C.struct_passwd, buf *C.char, size C.size_t, result **C.struct_passwd) C.int {
+		return C.getpwnam_r(cname, pwd, buf, size, result)
+	})
+}
+
+// LookupByUid returns the entry for the given user id from getpwuid_r
+func (s *LibcSource) LookupByUid(uid int) (*EtcPasswdEntry, bool) {
+	if uid < 0 || int64(uid) > MaxID {
+		return nil, false
+	}
+	return libcLookup(func(pwd *C.struct_passwd, buf *C.char, size C.size_t, result **C.struct_passwd) C.int {
+		return C.getpwuid_r(C.uid_t(uid), pwd, buf, size, result)
+	})
+}
+
+// All enumerates every user with getpwent. The entries are collected before the first is
+// yielded so the C library state is not held while the caller runs.
+func (s *LibcSource) All() iter.Seq[*EtcPasswdEntry] {
+	return func(yield func(*EtcPasswdEntry) bool) {
+		libcEnumerateMu.Lock()
+		entries := make([]*EtcPasswdEntry, 0)
+		C.setpwent()
+		for pwd := C.getpwent(); pwd != nil; pwd = C.getpwent() {
+			entries = append(entries, passwdFromC(pwd))
+		}
+		C.endpwent()
+		libcEnumerateMu.Unlock()
+		for _, entry := range entries {
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+}
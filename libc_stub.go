@@ -0,0 +1,26 @@
+//go:build !(cgo && etcpwdparse_libc)
+
+package etcpwdparse
+
+import (
+	"iter"
+)
+
+// LibcAvailable is true when the package was built with the etcpwdparse_libc tag and cgo, so
+// that LibcSource calls into the C library.
+const LibcAvailable = false
+
+// LookupByName returns nothing since the package was built without the C library backend
+func (s *LibcSource) LookupByName(name string) (*EtcPasswdEntry, bool) {
+	return nil, false
+}
+
+// LookupByUid returns nothing since the package was built without the C library backend
+func (s *LibcSource) LookupByUid(uid int) (*EtcPasswdEntry, bool) {
+	return nil, false
+}
+
+// All returns an empty iterator since the package was built without the C library backend
+func (s *LibcSource) All() iter.Seq[*EtcPasswdEntry] {
+	return func(yield func(*EtcPasswdEntry) bool) {}
+}
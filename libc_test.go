@@ -0,0 +1,39 @@
+package etcpwdparse
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLibcSource(t *testing.T) {
+	source := &LibcSource{}
+	root, ok := source.LookupByUid(0)
+	if !LibcAvailable {
+		if ok {
+			t.Fatalf("lookups should miss without the C library backend")
+		}
+		return
+	}
+	if _, err := os.Stat("/etc/passwd"); err != nil {
+		t.Skip("no /etc/passwd to compare against")
+	}
+	if !ok || root.Username() != "root" {
+		t.Fatalf("root should have been found")
+	}
+	byName, ok := source.LookupByName("root")
+	if !ok || byName.Uid() != 0 || byName.Homedir() != root.Homedir() {
+		t.Fatalf("root should have been found by name")
+	}
+	if _, ok := source.LookupByName("no-such-user-for-etcpwdparse"); ok {
+		t.Fatalf("the unknown user should not have been found")
+	}
+	found := false
+	for entry := range source.All() {
+		if entry.Uid() == 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("root should have been enumerated")
+	}
+}
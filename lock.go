@@ -0,0 +1,173 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLockTimeout is how long WriteToPath waits for the locks, the same as lckpwdf
+	DefaultLockTimeout = 15 * time.Second
+	// globalLockName is the lock file used by lckpwdf in the directory of the databases
+	globalLockName = ".pwd.lock"
+	// lockPollInterval is how often a held lock is retried until the timeout
+	lockPollInterval = 100 * time.Millisecond
+)
+
+// FileLock holds the shadow-utils compatible locks for a database file. It is returned by
+// LockFile and must be released with Unlock.
+type FileLock struct {
+	lockPaths []string
+	global    *os.File
+	inProcess chan struct{}
+}
+
+// processLocks serializes the lockers within this process by the path of their lckpwdf lock
+// file. fcntl locks belong to the whole process, so without it a second goroutine would share
+// the lock held by the first and drop it for both when it closes its own descriptor.
+var (
+	processLocksMu sync.Mutex
+	processLocks   = make(map[string]chan struct{})
+)
+
+// processLock returns the channel used as the in-process mutex for the lock file at path.
+func processLock(path string) chan struct{} {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	processLocksMu.Lock()
+	defer processLocksMu.Unlock()
+	result, ok := processLocks[path]
+	if !ok {
+		result = make(chan struct{}, 1)
+		processLocks[path] = result
+	}
+	return result
+}
+
+// LockFile takes the same locks as shadow-utils before modifying the database at path, so that
+// writes can safely coexist with useradd, passwd, and vipw. First the lckpwdf lock is taken by
+// placing a write lock on .pwd.lock in the same directory, then the file is locked with a
+// path.lock file holding our process id, which is created with a hard link so that it appears
+// atomically. Lock files left behind by processes that no longer exist are removed. LockFile
+// retries until the timeout passes. Goroutines of this process wait for each other in the same
+// way, since the lckpwdf lock is only held once per process.
+func LockFile(path string, timeout time.Duration) (*FileLock, error) {
+	return lockFiles(timeout, path)
+}
+
+// lockFiles is LockFile for several databases changed together, such as passwd and shadow. The
+// lckpwdf lock is taken once in the directory of the first path and then each file is locked in
+// order.
+func lockFiles(timeout time.Duration, paths ...string) (*FileLock, error) {
+	deadline := time.Now().Add(timeout)
+	globalPath := filepath.Join(filepath.Dir(paths[0]), globalLockName)
+	inProcess := processLock(globalPath)
+	// try without waiting first, select picks at random when the timer has already fired
+	select {
+	case inProcess <- struct{}{}:
+	default:
+		timer := time.NewTimer(time.Until(deadline))
+		select {
+		case inProcess <- struct{}{}:
+			timer.Stop()
+		case <-timer.C:
+			return nil, fmt.Errorf("Could not lock %s: file is locked by this process", globalPath)
+		}
+	}
+	global, err := os.OpenFile(globalPath, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		<-inProcess
+		return nil, err
+	}
+	if err := lockDescriptor(global, deadline); err != nil {
+		global.Close()
+		<-inProcess
+		return nil, fmt.Errorf("Could not lock %s: %s", globalPath, err)
+	}
+
+	lock := &FileLock{global: global, inProcess: inProcess}
+	for _, path := range paths {
+		lockPath := path + ".lock"
+		for {
+			locked, err := tryLinkLock(path, lockPath)
+			if err != nil {
+				lock.Unlock()
+				return nil, err
+			}
+			if locked {
+				lock.lockPaths = append(lock.lockPaths, lockPath)
+				break
+			}
+			if time.Now().After(deadline) {
+				lock.Unlock()
+				return nil, fmt.Errorf("Could not lock %s: file is locked by another process", path)
+			}
+			time.Sleep(lockPollInterval)
+		}
+	}
+	return lock, nil
+}
+
+// tryLinkLock makes one attempt at creating the per-file lock in the style of shadow-utils
+// do_lock_file. It returns false if another live process holds the lock. The file holding our
+// pid gets a unique name rather than the path.pid of shadow-utils, so that attempts made by
+// different goroutines never remove each other's file.
+func tryLinkLock(path, lockPath string) (bool, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return false, err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	_, err = tmp.WriteString(strconv.Itoa(os.Getpid()))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = os.Link(tmpPath, lockPath)
+	if err == nil {
+		return true, nil
+	}
+	if !os.IsExist(err) {
+		return false, err
+	}
+	content, err := os.ReadFile(lockPath)
+	if err != nil {
+		// the holder may have just released it
+		return false, nil
+	}
+	holder, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err == nil && holder > 0 && processExists(holder) {
+		return false, nil
+	}
+	// stale lock file from a process that no longer exists
+	os.Remove(lockPath)
+	return os.Link(tmpPath, lockPath) == nil, nil
+}
+
+// Unlock releases the per-file locks and then the lckpwdf lock
+func (l *FileLock) Unlock() error {
+	var err error
+	for i := len(l.lockPaths) - 1; i >= 0; i-- {
+		if rerr := os.Remove(l.lockPaths[i]); err == nil {
+			err = rerr
+		}
+	}
+	if cerr := l.global.Close(); err == nil {
+		err = cerr
+	}
+	if l.inProcess != nil {
+		<-l.inProcess
+		l.inProcess = nil
+	}
+	return err
+}
@@ -0,0 +1,20 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package etcpwdparse
+
+import (
+	"os"
+	"time"
+)
+
+// lockDescriptor is a no-op on platforms without fcntl record locks, only the per-file lock
+// is used there.
+func lockDescriptor(f *os.File, deadline time.Time) error {
+	return nil
+}
+
+// processExists assumes the process is running since it cannot be checked portably, so stale
+// lock files must be removed by hand.
+func processExists(pid int) bool {
+	return true
+}
@@ -0,0 +1,136 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	ioutil.WriteFile(pwFile, []byte("root:x:0:0:root:/root:/bin/bash\n"), 0644)
+
+	lock, err := LockFile(pwFile, time.Second)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	content, err := ioutil.ReadFile(pwFile + ".lock")
+	if err != nil || string(content) != strconv.Itoa(os.Getpid()) {
+		t.Fatalf("lock file should hold our pid: %q %v", string(content), err)
+	}
+	if _, err := os.Stat(path.Join(tempDir, ".pwd.lock")); err != nil {
+		t.Fatalf("global lock file should exist: %s", err)
+	}
+
+	// a second attempt sees the live lock and times out
+	if _, err := LockFile(pwFile, 150*time.Millisecond); err == nil {
+		t.Fatalf("Should have failed while the file is locked")
+	}
+
+	cache := NewEtcPasswdCache(false, WithLockTimeout(150*time.Millisecond))
+	cache.LoadFromPath(pwFile)
+	if err := cache.WriteToPath(pwFile); err == nil {
+		t.Fatalf("Should have failed to write while the file is locked")
+	}
+	unlocked := NewEtcPasswdCache(false, WithoutLocking())
+	unlocked.LoadFromPath(pwFile)
+	if err := unlocked.WriteToPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, err := os.Stat(pwFile + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("lock file should have been removed")
+	}
+	if err := cache.WriteToPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+}
+
+func TestLockFileStale(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	// pids are never this large so the holder cannot be running
+	ioutil.WriteFile(pwFile+".lock", []byte("2147483647"), 0600)
+
+	lock, err := LockFile(pwFile, time.Second)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	lock.Unlock()
+}
+
+func TestLockFileGoroutines(t *testing.T) {
+	tempDir := t.TempDir()
+	pwFile := path.Join(tempDir, "passwd")
+	ioutil.WriteFile(pwFile, []byte("root:x:0:0:root:/root:/bin/bash\n"), 0644)
+
+	// each goroutine must hold the lock on its own while it is between LockFile and Unlock
+	var wg sync.WaitGroup
+	var holders int32
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				lock, err := LockFile(pwFile, 10*time.Second)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if atomic.AddInt32(&holders, 1) != 1 {
+					errs <- fmt.Errorf("lock was held by more than one goroutine")
+				}
+				atomic.AddInt32(&holders, -1)
+				if err := lock.Unlock(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if matches, _ := filepath.Glob(pwFile + ".*"); len(matches) != 0 {
+		t.Fatalf("expected no leftover lock files: %v", matches)
+	}
+}
+
+func TestLockFileZeroTimeout(t *testing.T) {
+	pwFile := filepath.Join(t.TempDir(), "passwd")
+	ioutil.WriteFile(pwFile, []byte("root:x:0:0:root:/root:/bin/bash\n"), 0644)
+
+	// an uncontended lock is always taken, however short the timeout
+	for i := 0; i < 50; i++ {
+		lock, err := LockFile(pwFile, 0)
+		if err != nil {
+			t.Fatalf("Should not have failed on attempt %d: %s", i, err)
+		}
+		lock.Unlock()
+	}
+
+	lock, err := LockFile(pwFile, 0)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	defer lock.Unlock()
+	if _, err := LockFile(pwFile, 0); err == nil {
+		t.Fatalf("Should have failed while the file is locked")
+	}
+}
@@ -0,0 +1,32 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package etcpwdparse
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// lockDescriptor places an fcntl write lock on the whole file like lckpwdf, retrying until the
+// deadline. The lock is released when the file is closed.
+func lockDescriptor(f *os.File, deadline time.Time) error {
+	lk := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: io.SeekStart}
+	for {
+		err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lk)
+		if err == nil {
+			return nil
+		}
+		if (err != syscall.EAGAIN && err != syscall.EACCES) || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// processExists returns true if a process with the given id is running
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,28 @@
+package etcpwdparse
+
+import (
+	"log/slog"
+)
+
+// WithLogger makes the cache log what it is doing: loads and reloads of the passwd file at debug
+// level, lookups answered by the getent fallback at debug level, and failed loads and skipped bad
+// lines at warn level. Nothing is logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// debug logs at debug level if a logger was given
+func (o *options) debug(msg string, args ...interface{}) {
+	if o.logger != nil {
+		o.logger.Debug(msg, args...)
+	}
+}
+
+// warn logs at warn level if a logger was given
+func (o *options) warn(msg string, args ...interface{}) {
+	if o.logger != nil {
+		o.logger.Warn(msg, args...)
+	}
+}
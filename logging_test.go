@@ -0,0 +1,52 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	path := filepath.Join(t.TempDir(), "passwd")
+	os.WriteFile(path, []byte("root:x:0:0:root:/root:/bin/bash\nbroken\n"), 0644)
+
+	cache := NewEtcPasswdCache(true, WithLogger(logger))
+	if err := cache.LoadFromPath(path); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := cache.LoadFromPath(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatalf("Should have failed")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{
+		`level=WARN msg="Skipped bad line" line=2 error="line 2: Passwd line had wrong number of parts 1 != 7"`,
+		`level=DEBUG msg="Loaded passwd content" path=` + path + ` entries=1`,
+		`level=WARN msg="Failed to load passwd content" path=`,
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected log %q", buf.String())
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, expected[i]) {
+			t.Fatalf("%q does not start with %q", line, expected[i])
+		}
+	}
+
+	// nothing is logged without a logger
+	if err := NewEtcPasswdCache(true).LoadFromPath(path); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+}
@@ -0,0 +1,156 @@
+package etcpwdparse
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoginDefs holds the settings from the etc login.defs file which controls the policy used by
+// useradd, passwd, and friends. The typed accessors return the shadow-utils default when a
+// setting is missing or badly formatted.
+type LoginDefs struct {
+	values map[string]string
+	opts   options
+}
+
+// NewLoginDefs returns an empty set of settings which will report the defaults for everything.
+func NewLoginDefs(opts ...Option) *LoginDefs {
+	return &LoginDefs{values: make(map[string]string), opts: applyOptions(opts)}
+}
+
+// NewLoadedLoginDefs returns the settings loaded from /etc/login.defs in a single call.
+func NewLoadedLoginDefs(opts ...Option) (*LoginDefs, error) {
+	result := NewLoginDefs(opts...)
+	if err := result.LoadDefault(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LoadFromPath loads the settings from a file on disk and replaces the existing settings.
+func (d *LoginDefs) LoadFromPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.LoadFromReader(f)
+}
+
+// LoadFromReader loads the settings from login.defs formatted content read from the given
+// reader and replaces the existing settings. Each line holds a name and a value separated by
+// whitespace. Values may be wrapped in double quotes.
+func (d *LoginDefs) LoadFromReader(r io.Reader) error {
+	values := make(map[string]string)
+	err := readLines(r, func(line string) error {
+		fields := strings.Fields(line)
+		value := ""
+		if len(fields) > 1 {
+			value = strings.Join(fields[1:], " ")
+			value = strings.TrimSuffix(strings.TrimPrefix(value, "\""), "\"")
+		}
+		values[fields[0]] = value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.values = values
+	return nil
+}
+
+// LoadDefault loads the settings from the /etc/login.defs file, relative to the root given by WithRoot
+func (d *LoginDefs) LoadDefault() error {
+	return d.LoadFromPath(d.opts.defaultPath("/etc/login.defs"))
+}
+
+// Get returns the raw value of the named setting
+func (d *LoginDefs) Get(name string) (string, bool) {
+	value, ok := d.values[name]
+	return value, ok
+}
+
+// Int returns the named setting as a number or the fallback if it is missing or badly formatted.
+// Like shadow-utils, octal and hex values such as 077 and 0x3f are understood.
+func (d *LoginDefs) Int(name string, fallback int) int {
+	value, ok := d.values[name]
+	if !ok {
+		return fallback
+	}
+	result, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return fallback
+	}
+	return int(result)
+}
+
+// Bool returns true if the named setting is "yes", or the fallback if it is missing.
+func (d *LoginDefs) Bool(name string, fallback bool) bool {
+	value, ok := d.values[name]
+	if !ok {
+		return fallback
+	}
+	return strings.EqualFold(value, "yes")
+}
+
+// UidMin returns UID_MIN, the lowest user id for regular accounts
+func (d *LoginDefs) UidMin() int {
+	return d.Int("UID_MIN", DefaultUidMin)
+}
+
+// UidMax returns UID_MAX, the highest user id for regular accounts
+func (d *LoginDefs) UidMax() int {
+	return d.Int("UID_MAX", DefaultUidMax)
+}
+
+// SysUidMin returns SYS_UID_MIN, the lowest user id for system accounts
+func (d *LoginDefs) SysUidMin() int {
+	return d.Int("SYS_UID_MIN", 101)
+}
+
+// SysUidMax returns SYS_UID_MAX, the highest user id for system accounts
+func (d *LoginDefs) SysUidMax() int {
+	return d.Int("SYS_UID_MAX", d.UidMin()-1)
+}
+
+// GidMin returns GID_MIN, the lowest group id for regular groups
+func (d *LoginDefs) GidMin() int {
+	return d.Int("GID_MIN", 1000)
+}
+
+// GidMax returns GID_MAX, the highest group id for regular groups
+func (d *LoginDefs) GidMax() int {
+	return d.Int("GID_MAX", 60000)
+}
+
+// SysGidMin returns SYS_GID_MIN, the lowest group id for system groups
+func (d *LoginDefs) SysGidMin() int {
+	return d.Int("SYS_GID_MIN", 101)
+}
+
+// SysGidMax returns SYS_GID_MAX, the highest group id for system groups
+func (d *LoginDefs) SysGidMax() int {
+	return d.Int("SYS_GID_MAX", d.GidMin()-1)
+}
+
+// PassMaxDays returns PASS_MAX_DAYS, the maximum number of days a password may be used
+func (d *LoginDefs) PassMaxDays() int {
+	return d.Int("PASS_MAX_DAYS", 99999)
+}
+
+// PassMinDays returns PASS_MIN_DAYS, the minimum number of days between password changes
+func (d *LoginDefs) PassMinDays() int {
+	return d.Int("PASS_MIN_DAYS", 0)
+}
+
+// PassWarnAge returns PASS_WARN_AGE, the number of days of warning before a password expires
+func (d *LoginDefs) PassWarnAge() int {
+	return d.Int("PASS_WARN_AGE", 7)
+}
+
+// AccountRanges returns the account classification policy described by UID_MIN and UID_MAX
+func (d *LoginDefs) AccountRanges() AccountRanges {
+	return AccountRanges{UidMin: d.UidMin(), UidMax: d.UidMax()}
+}
@@ -0,0 +1,60 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+const fakeLoginDefsContent = `
+# Password aging controls
+PASS_MAX_DAYS	90
+PASS_MIN_DAYS	1
+UMASK		077
+
+UID_MIN			 500
+UID_MAX			60000
+SYS_UID_MIN		0x0a
+ENCRYPT_METHOD "SHA512"
+USERGROUPS_ENAB yes
+BROKEN_NUMBER abc
+`
+
+func TestLoginDefs(t *testing.T) {
+	defs := NewLoginDefs()
+	if err := defs.LoadFromReader(strings.NewReader(fakeLoginDefsContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	if defs.PassMaxDays() != 90 || defs.PassMinDays() != 1 {
+		t.Fatalf("password aging was parsed incorrectly")
+	}
+	if defs.PassWarnAge() != 7 {
+		t.Fatalf("%d != 7", defs.PassWarnAge())
+	}
+	if defs.UidMin() != 500 || defs.UidMax() != 60000 {
+		t.Fatalf("uid range was parsed incorrectly")
+	}
+	if defs.SysUidMin() != 10 || defs.SysUidMax() != 499 {
+		t.Fatalf("system uid range was parsed incorrectly %d %d", defs.SysUidMin(), defs.SysUidMax())
+	}
+	if defs.GidMin() != 1000 || defs.SysGidMax() != 999 {
+		t.Fatalf("gid range defaults were incorrect")
+	}
+	if defs.Int("UMASK", 0) != 077 {
+		t.Fatalf("%o != 77", defs.Int("UMASK", 0))
+	}
+	if defs.Int("BROKEN_NUMBER", 3) != 3 {
+		t.Fatalf("bad numbers should fall back")
+	}
+	if method, _ := defs.Get("ENCRYPT_METHOD"); method != "SHA512" {
+		t.Fatalf("%s != SHA512", method)
+	}
+	if !defs.Bool("USERGROUPS_ENAB", false) || defs.Bool("MISSING", false) {
+		t.Fatalf("booleans were parsed incorrectly")
+	}
+
+	ranges := defs.AccountRanges()
+	if !ranges.IsRegularAccount(&EtcPasswdEntry{uid: 600}) {
+		t.Fatalf("600 should be a regular account")
+	}
+}
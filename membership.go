@@ -0,0 +1,74 @@
+package etcpwdparse
+
+import (
+	"fmt"
+)
+
+// GroupsWithMember returns the groups that list the username as a supplementary member, in
+// file order.
+func (e *EtcGroupCache) GroupsWithMember(username string) []*EtcGroupEntry {
+	results := make([]*EtcGroupEntry, 0)
+	for _, entry := range e.entries {
+		for _, member := range entry.members {
+			if member == username {
+				results = append(results, entry)
+				break
+			}
+		}
+	}
+	return results
+}
+
+// GroupsForUser returns the primary group of the user followed by its supplementary groups in
+// file order, the same groups as `id -Gn` reports from files. A group is only listed once even
+// if the user is also a supplementary member of its primary group, and a primary group id that
+// is missing from the group file is left out.
+func GroupsForUser(passwd *EtcPasswdCache, groups *EtcGroupCache, username string) ([]*EtcGroupEntry, error) {
+	entry, ok := passwd.LookupUserByName(username)
+	if !ok {
+		return nil, sentinelf(ErrUserNotFound, "No such user with username '%s'", username)
+	}
+	results := make([]*EtcGroupEntry, 0)
+	primary, hasPrimary := groups.LookupGroupByGid(entry.gid)
+	if hasPrimary {
+		results = append(results, primary)
+	}
+	for _, group := range groups.GroupsWithMember(username) {
+		if group != primary {
+			results = append(results, group)
+		}
+	}
+	return results, nil
+}
+
+// GroupsForUser returns the primary and supplementary groups of the user, see GroupsForUser.
+func (a *OsUserAdapter) GroupsForUser(username string) ([]*EtcGroupEntry, error) {
+	if a.Groups == nil {
+		return nil, fmt.Errorf("No group cache to look up groups for '%s'", username)
+	}
+	return GroupsForUser(a.Passwd, a.Groups, username)
+}
+
+// PrimaryGroupName returns the name of the group matching the primary group id of the entry.
+func (e *EtcGroupCache) PrimaryGroupName(entry *EtcPasswdEntry) (string, error) {
+	group, ok := e.LookupGroupByGid(entry.gid)
+	if !ok {
+		return "", sentinelf(ErrGroupNotFound, "No such group with gid %d", entry.gid)
+	}
+	return group.name, nil
+}
+
+// InitGroups returns the group id list a process for the user should run with, like
+// initgroups(3): the base group id followed by the ids of every group that lists the user as a
+// member, in file order and without repeats. The result can be passed straight to setgroups.
+func (e *EtcGroupCache) InitGroups(username string, baseGid int) []int {
+	results := []int{baseGid}
+	seen := map[int]bool{baseGid: true}
+	for _, group := range e.GroupsWithMember(username) {
+		if !seen[group.gid] {
+			seen[group.gid] = true
+			results = append(results, group.gid)
+		}
+	}
+	return results
+}
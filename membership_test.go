@@ -0,0 +1,107 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func groupNames(groups []*EtcGroupEntry) string {
+	names := make([]string, 0, len(groups))
+	for _, g := range groups {
+		names = append(names, g.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func TestGroupsForUser(t *testing.T) {
+	passwd := NewEtcPasswdCache(false)
+	if err := passwd.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	groups := NewEtcGroupCache(false)
+	if err := groups.LoadFromReader(strings.NewReader(fakeGroupContent + "extra:x:200:bin,bin\nadmins:x:201:root\nstaff:x:50:ftp\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	if names := groupNames(groups.GroupsWithMember("root")); names != "bin,daemon,admins" {
+		t.Fatalf("%s != bin,daemon,admins", names)
+	}
+
+	result, err := GroupsForUser(passwd, groups, "root")
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if names := groupNames(result); names != "root,bin,daemon,admins" {
+		t.Fatalf("%s != root,bin,daemon,admins", names)
+	}
+
+	// extra lists bin twice but is only returned once
+	result, _ = GroupsForUser(passwd, groups, "bin")
+	if names := groupNames(result); names != "bin,daemon,extra" {
+		t.Fatalf("%s != bin,daemon,extra", names)
+	}
+
+	// ftp is listed as a member of its own primary group
+	result, _ = GroupsForUser(passwd, groups, "ftp")
+	if names := groupNames(result); names != "staff" {
+		t.Fatalf("%s != staff", names)
+	}
+
+	// mail has primary gid 12 which is not in the group file
+	result, err = GroupsForUser(passwd, groups, "mail")
+	if err != nil || len(result) != 0 {
+		t.Fatalf("mail should have no groups: %v %v", result, err)
+	}
+
+	if _, err := GroupsForUser(passwd, groups, "nosuchuser"); err == nil {
+		t.Fatalf("Should have failed for an unknown user")
+	}
+
+	adapter := NewOsUserAdapter(passwd, groups)
+	result, err = adapter.GroupsForUser("daemon")
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if names := groupNames(result); names != "daemon,bin" {
+		t.Fatalf("%s != daemon,bin", names)
+	}
+	if _, err := NewOsUserAdapter(passwd, nil).GroupsForUser("daemon"); err == nil {
+		t.Fatalf("Should have failed without a group cache")
+	}
+}
+
+func TestPrimaryGroupName(t *testing.T) {
+	groups := NewEtcGroupCache(false)
+	if err := groups.LoadFromReader(strings.NewReader(fakeGroupContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	entry, _ := ParsePasswdLine("alice:x:1000:10:Alice:/home/alice:/bin/bash")
+	name, err := groups.PrimaryGroupName(&entry)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if name != "wheel" {
+		t.Fatalf("%s != wheel", name)
+	}
+
+	entry, _ = ParsePasswdLine("bob:x:1001:1001:Bob:/home/bob:/bin/bash")
+	if _, err := groups.PrimaryGroupName(&entry); err == nil {
+		t.Fatalf("Should have failed for a missing gid")
+	}
+}
+
+func TestInitGroups(t *testing.T) {
+	groups := NewEtcGroupCache(false)
+	if err := groups.LoadFromReader(strings.NewReader(fakeGroupContent + "wheel2:x:10:root\nstaff:x:50:root\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	gids := groups.InitGroups("root", 50)
+	if fmt.Sprint(gids) != "[50 1 2 10]" {
+		t.Fatalf("%v != [50 1 2 10]", gids)
+	}
+	gids = groups.InitGroups("nosuchuser", 1000)
+	if fmt.Sprint(gids) != "[1000]" {
+		t.Fatalf("%v != [1000]", gids)
+	}
+}
@@ -0,0 +1,148 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"iter"
+	"math/rand"
+	"sync"
+)
+
+// MemorySource is a UserSource that holds entries in memory. It is intended for unit tests and
+// benchmarks that need lookups without writing a passwd file to disk. It is safe for concurrent use.
+type MemorySource struct {
+	mu      sync.RWMutex
+	entries []*EtcPasswdEntry
+	namemap map[string]*EtcPasswdEntry
+	idmap   map[int]*EtcPasswdEntry
+}
+
+// NewMemorySource returns a source holding the given entries.
+func NewMemorySource(entries ...EtcPasswdEntry) *MemorySource {
+	result := &MemorySource{
+		entries: make([]*EtcPasswdEntry, 0, len(entries)),
+		namemap: make(map[string]*EtcPasswdEntry),
+		idmap:   make(map[int]*EtcPasswdEntry),
+	}
+	for _, entry := range entries {
+		result.Add(entry)
+	}
+	return result
+}
+
+// Add adds the entry to the source. Like EtcPasswdCache.AddEntry, lookups return the entry added last.
+func (m *MemorySource) Add(entry EtcPasswdEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, &entry)
+	m.namemap[entry.username] = &entry
+	m.idmap[entry.uid] = &entry
+}
+
+// LookupByName returns the entry for the given username
+func (m *MemorySource) LookupByName(name string) (*EtcPasswdEntry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.namemap[name]
+	return entry, ok
+}
+
+// LookupByUid returns the entry for the given user id
+func (m *MemorySource) LookupByUid(uid int) (*EtcPasswdEntry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.idmap[uid]
+	return entry, ok
+}
+
+// All returns an iterator over the entries in the order they were added
+func (m *MemorySource) All() iter.Seq[*EtcPasswdEntry] {
+	return func(yield func(*EtcPasswdEntry) bool) {
+		m.mu.RLock()
+		entries := m.entries
+		m.mu.RUnlock()
+		for _, entry := range entries {
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+}
+
+// FakeEntryOptions controls the entries produced by GenerateFakeEntries.
+type FakeEntryOptions struct {
+	// Count is the number of entries to generate
+	Count int
+	// UidMin and UidMax are the inclusive range that user ids are picked from. When both are
+	// zero the default regular account range is used.
+	UidMin int
+	UidMax int
+	// Shells are picked from at random. When empty every entry gets /bin/bash.
+	Shells []string
+	// Seed makes the output reproducible
+	Seed int64
+}
+
+var fakeFirstNames = []string{
+	"alice", "bob", "carol", "dave", "eve", "frank", "grace", "heidi", "ivan", "judy",
+	"mallory", "nia", "olivia", "peggy", "quinn", "rupert", "sybil", "trent", "uma", "victor",
+}
+
+var fakeLastNames = []string{
+	"smith", "jones", "taylor", "brown", "williams", "wilson", "johnson", "davies", "patel", "nguyen",
+}
+
+// GenerateFakeEntries returns realistic looking entries with unique usernames and user ids,
+// for use in tests and benchmarks. Each entry has a primary group matching its user id, a
+// full name in the info field, and a home directory under /home.
+func GenerateFakeEntries(opts FakeEntryOptions) ([]EtcPasswdEntry, error) {
+	if opts.UidMin == 0 && opts.UidMax == 0 {
+		opts.UidMin, opts.UidMax = DefaultUidMin, DefaultUidMax
+	}
+	if opts.Count < 0 || opts.UidMax < opts.UidMin {
+		return nil, fmt.Errorf("Fake entry options were invalid")
+	}
+	if size := opts.UidMax - opts.UidMin + 1; opts.Count > size {
+		return nil, fmt.Errorf("Cannot generate %d entries in a uid range of size %d", opts.Count, size)
+	}
+	shells := opts.Shells
+	if len(shells) == 0 {
+		shells = []string{"/bin/bash"}
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	usedUids := make(map[int]bool, opts.Count)
+	usedNames := make(map[string]bool, opts.Count)
+	results := make([]EtcPasswdEntry, 0, opts.Count)
+	for len(results) < opts.Count {
+		uid := opts.UidMin + rng.Intn(opts.UidMax-opts.UidMin+1)
+		if usedUids[uid] {
+			continue
+		}
+		first := fakeFirstNames[rng.Intn(len(fakeFirstNames))]
+		last := fakeLastNames[rng.Intn(len(fakeLastNames))]
+		name := first + string(last[0])
+		for i := 2; usedNames[name]; i++ {
+			name = fmt.Sprintf("%s%c%d", first, last[0], i)
+		}
+		usedUids[uid] = true
+		usedNames[name] = true
+		results = append(results, EtcPasswdEntry{
+			username: name,
+			password: "x",
+			uid:      uid,
+			gid:      uid,
+			info:     fmt.Sprintf("%s %s", capitalise(first), capitalise(last)),
+			homedir:  "/home/" + name,
+			shell:    shells[rng.Intn(len(shells))],
+		})
+	}
+	return results, nil
+}
+
+// capitalise upper-cases the first letter of an ascii word.
+func capitalise(word string) string {
+	if len(word) == 0 || word[0] < 'a' || word[0] > 'z' {
+		return word
+	}
+	return string(word[0]-'a'+'A') + word[1:]
+}
@@ -0,0 +1,65 @@
+package etcpwdparse
+
+import (
+	"testing"
+)
+
+func TestMemorySource(t *testing.T) {
+	entries, err := GenerateFakeEntries(FakeEntryOptions{Count: 500, UidMin: 2000, UidMax: 2999, Shells: []string{"/bin/bash", "/bin/zsh"}, Seed: 1})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(entries) != 500 {
+		t.Fatalf("%d != 500", len(entries))
+	}
+	names := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Uid() < 2000 || entry.Uid() > 2999 {
+			t.Fatalf("uid %d was out of range", entry.Uid())
+		}
+		if entry.Shell() != "/bin/bash" && entry.Shell() != "/bin/zsh" {
+			t.Fatalf("unexpected shell %s", entry.Shell())
+		}
+		names[entry.Username()] = true
+	}
+	if len(names) != 500 {
+		t.Fatalf("usernames should be unique")
+	}
+
+	again, _ := GenerateFakeEntries(FakeEntryOptions{Count: 500, UidMin: 2000, UidMax: 2999, Shells: []string{"/bin/bash", "/bin/zsh"}, Seed: 1})
+	if again[42] != entries[42] {
+		t.Fatalf("the same seed should produce the same entries")
+	}
+
+	source := NewMemorySource(entries...)
+	var _ UserSource = source
+	if entry, ok := source.LookupByName(entries[10].Username()); !ok || entry.Uid() != entries[10].Uid() {
+		t.Fatalf("entry should be found by name")
+	}
+	if entry, ok := source.LookupByUid(entries[20].Uid()); !ok || entry.Username() != entries[20].Username() {
+		t.Fatalf("entry should be found by uid")
+	}
+	count := 0
+	for range source.All() {
+		count++
+	}
+	if count != 500 {
+		t.Fatalf("%d != 500", count)
+	}
+
+	if _, err := GenerateFakeEntries(FakeEntryOptions{Count: 11, UidMin: 10, UidMax: 19}); err == nil {
+		t.Fatalf("Should have failed when the range is too small")
+	}
+}
+
+func BenchmarkLookupUserByName(b *testing.B) {
+	entries, _ := GenerateFakeEntries(FakeEntryOptions{Count: 10000})
+	cache := NewEtcPasswdCache(false)
+	for _, entry := range entries {
+		cache.AddEntry(entry)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.LookupUserByName(entries[i%len(entries)].Username())
+	}
+}
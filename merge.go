@@ -0,0 +1,82 @@
+package etcpwdparse
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// MergePrecedence decides which file wins when LoadAndMerge finds the same username in more than
+// one file.
+type MergePrecedence int
+
+const (
+	// MergeFirstWins keeps the entry from the earliest path, so /etc/passwd listed before
+	// /usr/lib/passwd overrides it
+	MergeFirstWins MergePrecedence = iota
+	// MergeLastWins keeps the entry from the latest path, so a layer listed after a base image
+	// file overrides it
+	MergeLastWins
+)
+
+// LoadAndMerge loads each of the paths and replaces the cached content with the union of their
+// entries, resolving usernames found in several files with the given precedence. Usernames are
+// compared the same way as lookups, see WithCaseInsensitiveNames. Paths that do not exist are
+// skipped so that optional layers can always be listed. Entries keep the position of the first
+// file that mentioned them. The merged content does not come from a single file so comments are
+// not kept and the cache cannot be watched. The merge is reported to the metrics sink as a
+// single load. Duplicates within one file are resolved with the duplicate policy of the cache,
+// see WithDuplicatePolicy, and with WithCollectErrors the bad lines of every file are returned
+// together once the merged content is in place.
+func (e *EtcPasswdCache) LoadAndMerge(precedence MergePrecedence, paths ...string) error {
+	start := time.Now()
+	merged := make([]EtcPasswdEntry, 0)
+	positions := make(map[string]int)
+	badLines := 0
+	collected := make([]error, 0)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			e.reportLoad(path, nil, start, err)
+			return err
+		}
+		layer, err := e.parse(f, path)
+		f.Close()
+		if layer == nil {
+			e.reportLoad(path, nil, start, err)
+			return err
+		} else if err != nil {
+			collected = append(collected, err)
+		}
+		badLines += layer.badLines
+		for _, entry := range layer.entries {
+			key := e.opts.nameKey(entry.username)
+			// only the entry that a lookup in this file returns takes part
+			if layer.namemap[key] != entry {
+				continue
+			}
+			pos, seen := positions[key]
+			if !seen {
+				positions[key] = len(merged)
+				merged = append(merged, *entry)
+			} else if precedence == MergeLastWins {
+				merged[pos] = *entry
+			}
+		}
+	}
+
+	next := e.newLoadTarget("")
+	for _, entry := range merged {
+		if err := next.addWithPolicy(entry, ""); err != nil {
+			e.reportLoad("", nil, start, err)
+			return err
+		}
+	}
+	next.badLines = badLines
+	e.replaceContent(next)
+	err := errors.Join(collected...)
+	e.reportLoad("", next, start, err)
+	return err
+}
@@ -0,0 +1,103 @@
+package etcpwdparse
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLoadAndMerge(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	base := path.Join(tempDir, "base")
+	local := path.Join(tempDir, "local")
+	ioutil.WriteFile(base, []byte("root:x:0:0:base root:/root:/bin/sh\nbin:x:1:1:bin:/bin:/sbin/nologin\n"), 0644)
+	ioutil.WriteFile(local, []byte("# local overrides\nroot:x:0:0:local root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\n"), 0644)
+
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadAndMerge(MergeFirstWins, local, path.Join(tempDir, "missing"), base); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	names := ""
+	for entry := range cache.All() {
+		names += entry.Username() + ","
+	}
+	if names != "root,alice,bin," {
+		t.Fatalf("unexpected order %s", names)
+	}
+	if root, _ := cache.LookupUserByName("root"); root.Info() != "local root" {
+		t.Fatalf("%s != local root", root.Info())
+	}
+
+	if err := cache.LoadAndMerge(MergeLastWins, local, base); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if root, _ := cache.LookupUserByName("root"); root.Info() != "base root" {
+		t.Fatalf("%s != base root", root.Info())
+	}
+	if len(cache.ListEntries()) != 3 {
+		t.Fatalf("%d != 3", len(cache.ListEntries()))
+	}
+
+	ioutil.WriteFile(base, []byte("broken\n"), 0644)
+	if err := cache.LoadAndMerge(MergeFirstWins, local, base); err == nil {
+		t.Fatalf("Should have failed on a broken layer")
+	}
+}
+
+func TestLoadAndMergeMetricsAndNameKey(t *testing.T) {
+	tempDir := t.TempDir()
+	base := path.Join(tempDir, "base")
+	local := path.Join(tempDir, "local")
+	ioutil.WriteFile(base, []byte("Root:x:0:0:base root:/root:/bin/sh\nbroken\nbin:x:1:1:bin:/bin:/sbin/nologin\n"), 0644)
+	ioutil.WriteFile(local, []byte("root:x:0:0:local root:/root:/bin/bash\n"), 0644)
+
+	sink := &recordingSink{}
+	cache := NewEtcPasswdCache(true, WithMetrics(sink), WithCaseInsensitiveNames())
+	if err := cache.LoadAndMerge(MergeFirstWins, local, base); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(cache.ListEntries()) != 2 {
+		t.Fatalf("%d != 2", len(cache.ListEntries()))
+	}
+	if root, _ := cache.LookupUserByName("ROOT"); root.Info() != "local root" {
+		t.Fatalf("%s != local root", root.Info())
+	}
+	if len(sink.entries) != 1 || sink.entries[0] != 2 || sink.badLines[0] != 1 || sink.failures != 0 {
+		t.Fatalf("expected a single load, got %v %v with %d failures", sink.entries, sink.badLines, sink.failures)
+	}
+}
+
+func TestLoadAndMergeCollectErrorsAndDuplicates(t *testing.T) {
+	tempDir := t.TempDir()
+	base := path.Join(tempDir, "base")
+	local := path.Join(tempDir, "local")
+	ioutil.WriteFile(base, []byte("root:x:0:0:root:/root:/bin/sh\nbroken\nbin:x:1:1:bin:/bin:/sbin/nologin\n"), 0644)
+	ioutil.WriteFile(local, []byte("alice:x:1000:1000:first:/home/alice:/bin/bash\nalice:x:1000:1000:second:/home/alice:/bin/bash\n"), 0644)
+
+	cache := NewEtcPasswdCache(false, WithCollectErrors())
+	err := cache.LoadAndMerge(MergeFirstWins, local, base)
+	if !errors.Is(err, ErrBadLine) {
+		t.Fatalf("the bad line should have been reported: %v", err)
+	}
+	if len(cache.ListEntries()) != 3 {
+		t.Fatalf("the usable content should have been merged: %d != 3", len(cache.ListEntries()))
+	}
+
+	// within a file the merge picks the same duplicate as a lookup in that file does
+	for _, policy := range []DuplicatePolicy{DuplicateKeepBoth, DuplicateFirstWins, DuplicateLastWins} {
+		single := NewEtcPasswdCache(false, WithDuplicatePolicy(policy))
+		single.LoadFromPath(local)
+		merged := NewEtcPasswdCache(false, WithDuplicatePolicy(policy))
+		if err := merged.LoadAndMerge(MergeFirstWins, local); err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		want, _ := single.LookupUserByName("alice")
+		got, _ := merged.LookupUserByName("alice")
+		if got.Info() != want.Info() {
+			t.Fatalf("policy %d: %s != %s", policy, got.Info(), want.Info())
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package etcpwdparse
+
+import (
+	"time"
+)
+
+// MetricsSink receives measurements from a passwd cache so that services can export them to
+// their metrics system of choice, such as Prometheus or statsd, without this package depending
+// on any of them. The methods are called synchronously, so they must be cheap and safe to call
+// from several goroutines at once.
+type MetricsSink interface {
+	// Loaded is called after the content is loaded or reloaded with the number of entries, the
+	// number of bad lines that were skipped, and how long the load took
+	Loaded(entries, badLines int, duration time.Duration)
+	// LoadFailed is called when loading fails and the current content is kept
+	LoadFailed(err error)
+	// Reloaded is called each time stale content is reloaded because of WithTTL or WithStatCheck
+	Reloaded()
+	// Lookup is called for each lookup by username or uid with whether an entry was found
+	Lookup(hit bool)
+}
+
+// WithMetrics sets the sink that the cache reports its measurements to.
+func WithMetrics(sink MetricsSink) Option {
+	return func(o *options) {
+		o.metrics = sink
+	}
+}
+
+// countLookup reports a lookup to the metrics sink if there is one.
+func (o *options) countLookup(hit bool) {
+	if o.metrics != nil {
+		o.metrics.Lookup(hit)
+	}
+}
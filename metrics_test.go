@@ -0,0 +1,89 @@
+package etcpwdparse
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingSink struct {
+	mu       sync.Mutex
+	entries  []int
+	badLines []int
+	failures int
+	reloads  int
+	hits     int
+	misses   int
+}
+
+func (s *recordingSink) Loaded(entries, badLines int, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries)
+	s.badLines = append(s.badLines, badLines)
+}
+
+func (s *recordingSink) LoadFailed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures++
+}
+
+func (s *recordingSink) Reloaded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloads++
+}
+
+func (s *recordingSink) Lookup(hit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if hit {
+		s.hits++
+	} else {
+		s.misses++
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwd")
+	os.WriteFile(path, []byte("root:x:0:0:root:/root:/bin/bash\nbroken\nbob:x:1000:1000::/home/bob:/bin/sh\n"), 0644)
+
+	sink := &recordingSink{}
+	cache := NewEtcPasswdCache(true, WithMetrics(sink))
+	if err := cache.LoadFromPath(path); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := cache.LoadFromPath(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatalf("Should have failed")
+	}
+	cache.LookupUserByName("root")
+	cache.LookupUserByUid(1000)
+	cache.LookupUserByName("nobody")
+
+	if len(sink.entries) != 1 || sink.entries[0] != 2 || sink.badLines[0] != 1 {
+		t.Fatalf("unexpected loads %v %v", sink.entries, sink.badLines)
+	}
+	if sink.failures != 1 {
+		t.Fatalf("expected 1 failure, got %d", sink.failures)
+	}
+	if sink.hits != 2 || sink.misses != 1 {
+		t.Fatalf("expected 2 hits and 1 miss, got %d and %d", sink.hits, sink.misses)
+	}
+
+	// a stale file is reloaded and counted
+	sink = &recordingSink{}
+	cache = NewEtcPasswdCache(true, WithMetrics(sink), WithTTL(time.Nanosecond))
+	if err := cache.LoadFromPath(path); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.LookupUserByName("root"); !ok {
+		t.Fatalf("Should have found root")
+	}
+	if sink.reloads != 1 || len(sink.entries) != 2 {
+		t.Fatalf("expected 1 reload, got %d with %d loads", sink.reloads, len(sink.entries))
+	}
+}
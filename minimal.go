@@ -0,0 +1,134 @@
+package etcpwdparse
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// NonrootUid is the uid of the "nonroot" user of distroless images.
+const NonrootUid = 65532
+
+// MinimalAccounts builds the smallest valid /etc/passwd and /etc/group for an image assembled
+// FROM scratch, holding just the users the image needs. It starts with root and nobody, both
+// without a login shell.
+type MinimalAccounts struct {
+	users  []EtcPasswdEntry
+	groups []EtcGroupEntry
+}
+
+// NewMinimalAccounts returns a builder holding root and nobody.
+func NewMinimalAccounts() *MinimalAccounts {
+	result := &MinimalAccounts{}
+	result.mustAdd("root", 0, "/root")
+	result.mustAdd("nobody", nobodyUid, "/nonexistent")
+	return result
+}
+
+// mustAdd adds a user that is known to be valid.
+func (m *MinimalAccounts) mustAdd(name string, uid int, homedir string) {
+	if err := m.add(name, uid, homedir); err != nil {
+		panic(err)
+	}
+}
+
+// add adds the user along with a group of the same name and id.
+func (m *MinimalAccounts) add(name string, uid int, homedir string) error {
+	if err := ValidateUsername(name); err != nil {
+		return err
+	}
+	for _, u := range m.users {
+		if u.username == name {
+			return sentinelf(ErrDuplicateUser, "User with username '%s' already exists", name)
+		}
+		if u.uid == uid {
+			return sentinelf(ErrDuplicateUser, "User with uid %d already exists", uid)
+		}
+	}
+	for _, g := range m.groups {
+		if g.name == name || g.gid == uid {
+			return sentinelf(ErrDuplicateGroup, "Group '%s' or gid %d already exists", name, uid)
+		}
+	}
+	entry, err := NewEtcPasswdEntry(name, "x", uid, uid, name, homedir, "/sbin/nologin")
+	if err != nil {
+		return err
+	}
+	m.users = append(m.users, entry)
+	m.groups = append(m.groups, EtcGroupEntry{name: name, password: "x", gid: uid, members: []string{}})
+	return nil
+}
+
+// AddUser adds a user with a primary group of the same name and id, a home directory of
+// /home/<name>, and no login shell.
+func (m *MinimalAccounts) AddUser(name string, uid int) error {
+	return m.add(name, uid, "/home/"+name)
+}
+
+// AddGroup adds a supplementary group with the given members, which must have been added with
+// AddUser.
+func (m *MinimalAccounts) AddGroup(name string, gid int, members ...string) error {
+	for _, g := range m.groups {
+		if g.name == name || g.gid == gid {
+			return sentinelf(ErrDuplicateGroup, "Group '%s' or gid %d already exists", name, gid)
+		}
+	}
+	for _, member := range members {
+		found := false
+		for _, u := range m.users {
+			found = found || u.username == member
+		}
+		if !found {
+			return sentinelf(ErrUserNotFound, "No such user with username '%s'", member)
+		}
+	}
+	m.groups = append(m.groups, EtcGroupEntry{name: name, password: "x", gid: gid, members: members})
+	return nil
+}
+
+// WritePasswd writes the users in /etc/passwd format
+func (m *MinimalAccounts) WritePasswd(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, u := range m.users {
+		fmt.Fprintln(bw, u.String())
+	}
+	return bw.Flush()
+}
+
+// WriteGroup writes the groups in /etc/group format
+func (m *MinimalAccounts) WriteGroup(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, g := range m.groups {
+		fmt.Fprintln(bw, g.String())
+	}
+	return bw.Flush()
+}
+
+// WriteToRoot writes etc/passwd and etc/group below the given root directory, creating the etc
+// directory if needed.
+func (m *MinimalAccounts) WriteToRoot(root string) error {
+	etc := filepath.Join(root, "etc")
+	if err := os.MkdirAll(etc, 0755); err != nil {
+		return err
+	}
+	files := []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"passwd", m.WritePasswd},
+		{"group", m.WriteGroup},
+	}
+	for _, f := range files {
+		buf := new(bytes.Buffer)
+		if err := f.write(buf); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(etc, f.name), buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
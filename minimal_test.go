@@ -0,0 +1,68 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMinimalAccounts(t *testing.T) {
+	accounts := NewMinimalAccounts()
+	if err := accounts.AddUser("nonroot", NonrootUid); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := accounts.AddGroup("tty", 5, "nonroot"); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := accounts.AddUser("nonroot", 1000); err == nil {
+		t.Fatalf("Should have failed on a duplicate username")
+	}
+	if err := accounts.AddUser("other", 0); err == nil {
+		t.Fatalf("Should have failed on a duplicate uid")
+	}
+	if err := accounts.AddUser("bad:name", 1000); err == nil {
+		t.Fatalf("Should have failed on a bad username")
+	}
+	if err := accounts.AddGroup("staff", 50, "nosuchuser"); err == nil {
+		t.Fatalf("Should have failed on an unknown member")
+	}
+
+	buf := new(bytes.Buffer)
+	accounts.WritePasswd(buf)
+	expected := "root:x:0:0:root:/root:/sbin/nologin\n" +
+		"nobody:x:65534:65534:nobody:/nonexistent:/sbin/nologin\n" +
+		"nonroot:x:65532:65532:nonroot:/home/nonroot:/sbin/nologin\n"
+	if buf.String() != expected {
+		t.Fatalf("%q != %q", buf.String(), expected)
+	}
+	buf.Reset()
+	accounts.WriteGroup(buf)
+	expected = "root:x:0:\nnobody:x:65534:\nnonroot:x:65532:\ntty:x:5:nonroot\n"
+	if buf.String() != expected {
+		t.Fatalf("%q != %q", buf.String(), expected)
+	}
+
+	root, _ := os.MkdirTemp("", "minimal")
+	defer os.RemoveAll(root)
+	if err := accounts.WriteToRoot(root); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	passwd := NewEtcPasswdCache(false, WithRoot(root))
+	if err := passwd.LoadDefault(); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if errs := passwd.Validate(); len(errs) != 0 {
+		t.Fatalf("the minimal passwd should be valid: %v", errs)
+	}
+	groups := NewEtcGroupCache(false, WithRoot(root))
+	if err := groups.LoadDefault(); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if tty, ok := groups.LookupGroupByName("tty"); !ok || tty.Members()[0] != "nonroot" {
+		t.Fatalf("tty should have been written")
+	}
+	if _, err := os.Stat(filepath.Join(root, "etc", "group")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+}
@@ -0,0 +1,144 @@
+package etcpwdparse
+
+import (
+	"strings"
+)
+
+// UsernameNormalization is a Unicode normalization form applied to usernames.
+type UsernameNormalization int
+
+const (
+	// NormalizeNone compares usernames byte by byte. This is the default.
+	NormalizeNone UsernameNormalization = iota
+	// NormalizeNFC composes characters, so that "e" followed by a combining acute accent
+	// matches the precomposed "é".
+	NormalizeNFC
+	// NormalizeNFKC also folds compatibility characters, such as fullwidth letters and
+	// ligatures, to their plain equivalents.
+	NormalizeNFKC
+)
+
+// WithUsernameNormalization makes the passwd and shadow caches index and look up usernames by
+// their normalized form, so that names that look the same but are encoded differently resolve
+// to the same entry. Entries keep the username as written in the file, and names that only
+// differ in their encoding are duplicates handled as set by WithDuplicatePolicy. See
+// NormalizeUsername for the limits of the normalization.
+func WithUsernameNormalization(form UsernameNormalization) Option {
+	return func(o *options) {
+		o.normalization = form
+	}
+}
+
+// canonicalCompositions maps a base character and combining mark to their precomposed form.
+var canonicalCompositions = func() map[[2]rune]rune {
+	result := make(map[[2]rune]rune, len(canonicalPairs))
+	for composed, pair := range canonicalPairs {
+		result[pair] = composed
+	}
+	return result
+}()
+
+// NormalizeUsername returns the name in the given normalization form. Since the standard library
+// carries no normalization tables, only the characters of the Latin, Greek, and Cyrillic blocks,
+// their combining marks, the Angstrom, Kelvin, and Ohm signs, and on NFKC the fullwidth ASCII
+// forms and Latin ligatures are normalized. Characters of other scripts, such as Hangul or the
+// CJK compatibility ideographs, are passed through unchanged, so for those the result can differ
+// from a complete implementation such as golang.org/x/text/unicode/norm.
+func NormalizeUsername(name string, form UsernameNormalization) string {
+	if form == NormalizeNone || isASCII(name) {
+		return name
+	}
+	runes := make([]rune, 0, len(name))
+	for _, r := range name {
+		runes = decomposeRune(runes, r, form == NormalizeNFKC)
+	}
+	reorderMarks(runes)
+	return string(composeRunes(runes))
+}
+
+// isASCII returns true if the string only holds ASCII characters, which no form changes.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// decomposeRune appends the full canonical, or with compat the compatibility, decomposition of
+// the character.
+func decomposeRune(runes []rune, r rune, compat bool) []rune {
+	if compat {
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E:
+			return append(runes, r-0xFEE0)
+		case r == 0x3000:
+			return append(runes, ' ')
+		}
+		if decomposed, ok := compatibilityDecompositions[r]; ok {
+			return append(runes, []rune(decomposed)...)
+		}
+	}
+	if target, ok := canonicalSingletons[r]; ok {
+		return decomposeRune(runes, target, compat)
+	}
+	pair, ok := canonicalPairs[r]
+	if !ok {
+		pair, ok = excludedPairs[r]
+	}
+	if ok {
+		runes = decomposeRune(runes, pair[0], compat)
+		return decomposeRune(runes, pair[1], compat)
+	}
+	return append(runes, r)
+}
+
+// reorderMarks puts each run of combining marks in canonical order, sorting them stably by their
+// combining class.
+func reorderMarks(runes []rune) {
+	for i := 1; i < len(runes); i++ {
+		for j := i; j > 0; j-- {
+			a, b := combiningClasses[runes[j-1]], combiningClasses[runes[j]]
+			if a <= b || b == 0 {
+				break
+			}
+			runes[j-1], runes[j] = runes[j], runes[j-1]
+		}
+	}
+}
+
+// composeRunes applies canonical composition to a decomposed and reordered string.
+func composeRunes(runes []rune) []rune {
+	result := make([]rune, 0, len(runes))
+	starter := -1
+	// lastClass is the combining class of the last mark kept since the starter, or -1 if none
+	lastClass := -1
+	for _, r := range runes {
+		class := int(combiningClasses[r])
+		if starter >= 0 && (lastClass == -1 || (lastClass != 0 && lastClass < class)) {
+			if composed, ok := canonicalCompositions[[2]rune{result[starter], r}]; ok {
+				result[starter] = composed
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(result)
+			lastClass = -1
+		} else {
+			lastClass = class
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// normalizedCollisionKey returns the key used by Validate to find usernames that only differ in
+// their encoding.
+func (o *options) normalizedCollisionKey(name string) string {
+	name = NormalizeUsername(name, NormalizeNFKC)
+	if o.caseInsensitiveNames {
+		return strings.ToLower(name)
+	}
+	return name
+}
@@ -0,0 +1,72 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	cases := []struct {
+		input string
+		nfc   string
+		nfkc  string
+	}{
+		{"alice", "alice", "alice"},
+		{"jos\u00e9", "jos\u00e9", "jos\u00e9"},
+		{"jose\u0301", "jos\u00e9", "jos\u00e9"},
+		// the marks are put in canonical order before composing
+		{"s\u0307\u0323", "\u1e69", "\u1e69"},
+		{"q\u0307\u0323", "q\u0323\u0307", "q\u0323\u0307"},
+		{"\u212bngstr\u00f6m", "\u00c5ngstr\u00f6m", "\u00c5ngstr\u00f6m"},
+		{"\uff41\uff4c\uff49\uff43\uff45", "\uff41\uff4c\uff49\uff43\uff45", "alice"},
+		{"\ufb01ona", "\ufb01ona", "fiona"},
+		{"\u0430\u0308", "\u04d3", "\u04d3"},
+		// unsupported scripts are left alone
+		{"\u1100\u1161", "\u1100\u1161", "\u1100\u1161"},
+	}
+	for _, c := range cases {
+		if got := NormalizeUsername(c.input, NormalizeNFC); got != c.nfc {
+			t.Fatalf("NFC of %+q: %+q != %+q", c.input, got, c.nfc)
+		}
+		if got := NormalizeUsername(c.input, NormalizeNFKC); got != c.nfkc {
+			t.Fatalf("NFKC of %+q: %+q != %+q", c.input, got, c.nfkc)
+		}
+		if got := NormalizeUsername(c.input, NormalizeNone); got != c.input {
+			t.Fatalf("%+q != %+q", got, c.input)
+		}
+	}
+}
+
+func TestUsernameNormalization(t *testing.T) {
+	content := "jos\u00e9:x:1000:1000::/home/jose:/bin/bash\n"
+	cache := NewEtcPasswdCache(false, WithUsernameNormalization(NormalizeNFC))
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	entry, ok := cache.LookupUserByName("jose\u0301")
+	if !ok || entry.Username() != "jos\u00e9" {
+		t.Fatalf("the decomposed name should have matched")
+	}
+
+	plain := NewEtcPasswdCache(false)
+	plain.LoadFromReader(strings.NewReader(content))
+	if _, ok := plain.LookupUserByName("jose\u0301"); ok {
+		t.Fatalf("the decomposed name should not match without normalization")
+	}
+}
+
+func TestValidateNormalizedUsernames(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader("jos\u00e9:x:1000:1000::/home/jose:/bin/bash\njose\u0301:x:1001:1001::/home/jose2:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	found := false
+	for _, f := range cache.Validate() {
+		if f.Kind == FindingNormalizedUsername {
+			found = f.LineNumber == 2 && strings.Contains(f.Message, "line 1")
+		}
+	}
+	if !found {
+		t.Fatalf("expected a normalized username finding for line 2: %v", cache.Validate())
+	}
+}
@@ -0,0 +1,101 @@
+package etcpwdparse
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// serveFakeNscd answers passwd requests on a unix socket from fakePwdContent.
+func serveFakeNscd(t *testing.T, listener net.Listener, cache *EtcPasswdCache) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			var header [3]int32
+			if err := binary.Read(conn, binary.NativeEndian, &header); err != nil {
+				return
+			}
+			key := make([]byte, header[2])
+			if _, err := io.ReadFull(conn, key); err != nil {
+				return
+			}
+			name := strings.TrimSuffix(string(key), "\x00")
+			var entry *EtcPasswdEntry
+			var ok bool
+			if header[1] == nscdGetpwbyuid {
+				uid, _ := strconv.Atoi(name)
+				entry, ok = cache.LookupUserByUid(uid)
+			} else {
+				entry, ok = cache.LookupUserByName(name)
+			}
+			if !ok {
+				binary.Write(conn, binary.NativeEndian, nscdPasswdHeader{Version: nscdVersion})
+				return
+			}
+			fields := []string{entry.Username(), entry.Password(), entry.Info(), entry.Homedir(), entry.Shell()}
+			binary.Write(conn, binary.NativeEndian, nscdPasswdHeader{
+				Version:   nscdVersion,
+				Found:     1,
+				NameLen:   int32(len(fields[0]) + 1),
+				PasswdLen: int32(len(fields[1]) + 1),
+				Uid:       uint32(entry.Uid()),
+				Gid:       uint32(entry.Gid()),
+				GecosLen:  int32(len(fields[2]) + 1),
+				DirLen:    int32(len(fields[3]) + 1),
+				ShellLen:  int32(len(fields[4]) + 1),
+			})
+			for _, field := range fields {
+				conn.Write(append([]byte(field), 0))
+			}
+		}()
+	}
+}
+
+func TestNscdSource(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	tempDir, _ := os.MkdirTemp("", "nscd")
+	defer os.RemoveAll(tempDir)
+	socket := filepath.Join(tempDir, "socket")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not available: %s", err)
+	}
+	defer listener.Close()
+	go serveFakeNscd(t, listener, cache)
+
+	source := &NscdSource{Socket: socket}
+	ftp, ok := source.LookupByName("ftp")
+	if !ok {
+		t.Fatalf("ftp should have been found")
+	}
+	if ftp.Uid() != 14 || ftp.Gid() != 50 || ftp.Info() != "FTP User" || ftp.Homedir() != "/var/ftp" || ftp.Shell() != "/sbin/nologin" {
+		t.Fatalf("unexpected entry %+v", ftp)
+	}
+	nobody, ok := source.LookupByUid(99)
+	if !ok || nobody.Username() != "nobody" {
+		t.Fatalf("nobody should have been found")
+	}
+	if _, ok := source.LookupByName("nosuchuser"); ok {
+		t.Fatalf("nosuchuser should not have been found")
+	}
+	for range source.All() {
+		t.Fatalf("nscd should not enumerate users")
+	}
+
+	missing := &NscdSource{Socket: filepath.Join(tempDir, "missing")}
+	if _, ok := missing.LookupByName("ftp"); ok {
+		t.Fatalf("ftp should not have been found without a socket")
+	}
+}
@@ -0,0 +1,192 @@
+package etcpwdparse
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// NsswitchAction is a single "[STATUS=action]" criterion that follows a source in the
+// nsswitch.conf file, such as NOTFOUND=return. Status and Action are upper and lower case
+// respectively, and Negated is set for the "!STATUS=action" form.
+type NsswitchAction struct {
+	Status  string
+	Action  string
+	Negated bool
+}
+
+// NsswitchSource is a single service listed for a database, such as "files" or "sss", along
+// with the actions that follow it.
+type NsswitchSource struct {
+	Name    string
+	Actions []NsswitchAction
+}
+
+// NsswitchConfig holds the databases configured in the etc nsswitch.conf file which controls
+// where the C library looks up users and groups.
+type NsswitchConfig struct {
+	databases map[string][]NsswitchSource
+	opts      options
+}
+
+// defaultNsswitchSources is what glibc uses for a database that is not configured.
+var defaultNsswitchSources = []NsswitchSource{{Name: "files"}}
+
+// NewNsswitchConfig returns an empty config which reports the defaults for every database.
+func NewNsswitchConfig(opts ...Option) *NsswitchConfig {
+	return &NsswitchConfig{databases: make(map[string][]NsswitchSource), opts: applyOptions(opts)}
+}
+
+// NewLoadedNsswitchConfig returns the config loaded from /etc/nsswitch.conf in a single call.
+func NewLoadedNsswitchConfig(opts ...Option) (*NsswitchConfig, error) {
+	result := NewNsswitchConfig(opts...)
+	if err := result.LoadDefault(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LoadFromPath loads the config from a file on disk and replaces the existing databases.
+func (c *NsswitchConfig) LoadFromPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.LoadFromReader(f)
+}
+
+// LoadFromReader loads the config from nsswitch.conf formatted content read from the given
+// reader and replaces the existing databases. Errors are returned as a *ParseError.
+func (c *NsswitchConfig) LoadFromReader(r io.Reader) error {
+	databases := make(map[string][]NsswitchSource)
+	err := readLines(r, func(line string) error {
+		database, sources, err := parseNsswitchLine(line)
+		if err != nil {
+			return err
+		}
+		databases[database] = sources
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.databases = databases
+	return nil
+}
+
+// LoadDefault loads the config from the /etc/nsswitch.conf file, relative to the root given by
+// WithRoot. The ETCPWDPARSE_NSSWITCH_CONF environment variable overrides the location.
+func (c *NsswitchConfig) LoadDefault() error {
+	return c.LoadFromPath(c.opts.defaultPath("/etc/nsswitch.conf"))
+}
+
+// parseNsswitchLine parses a "database: source [STATUS=action] source" line.
+func parseNsswitchLine(line string) (string, []NsswitchSource, error) {
+	content, _, _ := strings.Cut(line, "#")
+	database, rest, ok := strings.Cut(content, ":")
+	database = strings.TrimSpace(database)
+	if !ok || len(database) == 0 {
+		return "", nil, newParseError(line, "", "Nsswitch line must start with a database name and ':'")
+	}
+	sources := make([]NsswitchSource, 0)
+	for rest = strings.TrimSpace(rest); len(rest) > 0; rest = strings.TrimSpace(rest) {
+		if rest[0] != '[' {
+			end := strings.IndexAny(rest, " \t[")
+			if end < 0 {
+				end = len(rest)
+			}
+			sources = append(sources, NsswitchSource{Name: rest[:end]})
+			rest = rest[end:]
+			continue
+		}
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return "", nil, newParseError(line, "", "Nsswitch line had an unterminated '['")
+		}
+		if len(sources) == 0 {
+			return "", nil, newParseError(line, "", "Nsswitch line had actions before the first source")
+		}
+		for _, criterion := range strings.Fields(rest[1:end]) {
+			status, action, ok := strings.Cut(criterion, "=")
+			if !ok {
+				return "", nil, newParseError(line, "", "Nsswitch line had badly formatted action %s", criterion)
+			}
+			result := NsswitchAction{Action: strings.ToLower(action)}
+			if strings.HasPrefix(status, "!") {
+				result.Negated = true
+				status = status[1:]
+			}
+			result.Status = strings.ToUpper(status)
+			last := &sources[len(sources)-1]
+			last.Actions = append(last.Actions, result)
+		}
+		rest = rest[end+1:]
+	}
+	return database, sources, nil
+}
+
+// Sources returns the sources configured for the named database in lookup order. A database
+// that is not configured reports the glibc default of "files".
+func (c *NsswitchConfig) Sources(database string) []NsswitchSource {
+	sources, ok := c.databases[database]
+	if !ok {
+		sources = defaultNsswitchSources
+	}
+	results := make([]NsswitchSource, len(sources))
+	copy(results, sources)
+	return results
+}
+
+// Passwd returns the sources configured for the passwd database
+func (c *NsswitchConfig) Passwd() []NsswitchSource {
+	return c.Sources("passwd")
+}
+
+// Group returns the sources configured for the group database
+func (c *NsswitchConfig) Group() []NsswitchSource {
+	return c.Sources("group")
+}
+
+// Shadow returns the sources configured for the shadow database
+func (c *NsswitchConfig) Shadow() []NsswitchSource {
+	return c.Sources("shadow")
+}
+
+// UsesSource returns true if the named source is configured for the database
+func (c *NsswitchConfig) UsesSource(database, source string) bool {
+	for _, s := range c.Sources(database) {
+		if s.Name == source {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesCompat returns true if the database uses "compat" mode, in which NIS compat lines in the
+// file are meaningful. See CompatEntry.
+func (c *NsswitchConfig) UsesCompat(database string) bool {
+	return c.UsesSource(database, "compat")
+}
+
+// CompatSource returns the service that compat mode looks up included users in, as set with
+// "<database>_compat" such as "sss", or the glibc default of "nis".
+func (c *NsswitchConfig) CompatSource(database string) string {
+	sources := c.databases[database+"_compat"]
+	if len(sources) == 0 {
+		return "nis"
+	}
+	return sources[0].Name
+}
+
+// HasNonFileSources returns true if the database is configured with any source other than
+// "files", meaning that the files alone may not hold every user. This is the case where a
+// getent fallback is useful. Compat mode counts since it reads the files and CompatSource.
+func (c *NsswitchConfig) HasNonFileSources(database string) bool {
+	for _, s := range c.Sources(database) {
+		if s.Name != "files" {
+			return true
+		}
+	}
+	return false
+}
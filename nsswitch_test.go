@@ -0,0 +1,60 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+const fakeNsswitchContent = `
+# /etc/nsswitch.conf
+passwd:         compat systemd
+group:          files [SUCCESS=merge] sss
+shadow:         files   # local only
+hosts:          files mdns4_minimal [NOTFOUND=return !UNAVAIL=Continue] dns
+passwd_compat:  sss
+`
+
+func TestNsswitchConfig(t *testing.T) {
+	config := NewNsswitchConfig()
+	if err := config.LoadFromReader(strings.NewReader(fakeNsswitchContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	passwd := config.Passwd()
+	if len(passwd) != 2 || passwd[0].Name != "compat" || passwd[1].Name != "systemd" {
+		t.Fatalf("unexpected passwd sources %+v", passwd)
+	}
+	group := config.Group()
+	if len(group) != 2 || len(group[0].Actions) != 1 || group[0].Actions[0] != (NsswitchAction{Status: "SUCCESS", Action: "merge"}) {
+		t.Fatalf("unexpected group sources %+v", group)
+	}
+	if shadow := config.Shadow(); len(shadow) != 1 || shadow[0].Name != "files" {
+		t.Fatalf("unexpected shadow sources %+v", shadow)
+	}
+	hosts := config.Sources("hosts")
+	if len(hosts) != 3 || len(hosts[1].Actions) != 2 || hosts[1].Actions[1] != (NsswitchAction{Status: "UNAVAIL", Action: "continue", Negated: true}) {
+		t.Fatalf("unexpected hosts sources %+v", hosts)
+	}
+	if netgroup := config.Sources("netgroup"); len(netgroup) != 1 || netgroup[0].Name != "files" {
+		t.Fatalf("unconfigured databases should default to files %+v", netgroup)
+	}
+
+	if !config.UsesCompat("passwd") || config.UsesCompat("group") {
+		t.Fatalf("only passwd should use compat")
+	}
+	if config.CompatSource("passwd") != "sss" || config.CompatSource("group") != "nis" {
+		t.Fatalf("unexpected compat sources %s %s", config.CompatSource("passwd"), config.CompatSource("group"))
+	}
+	if !config.UsesSource("group", "sss") || config.UsesSource("shadow", "sss") {
+		t.Fatalf("UsesSource gave the wrong answer")
+	}
+	if !config.HasNonFileSources("passwd") || config.HasNonFileSources("shadow") {
+		t.Fatalf("HasNonFileSources gave the wrong answer")
+	}
+
+	for _, line := range []string{"passwd files", "passwd: [NOTFOUND=return] files", "passwd: files [NOTFOUND", "passwd: files [NOTFOUND]"} {
+		if err := NewNsswitchConfig().LoadFromReader(strings.NewReader(line)); err == nil {
+			t.Fatalf("Should have failed to parse %q", line)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package etcpwdparse
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is the prefix of the environment variables that override the location of the system
+// files, for example ETCPWDPARSE_PASSWD, ETCPWDPARSE_SHADOW, ETCPWDPARSE_GROUP, and
+// ETCPWDPARSE_LOGIN_DEFS.
+const EnvPrefix = "ETCPWDPARSE_"
+
+// Option configures optional behaviour of a cache. Options are passed to the cache constructors.
+type Option func(*options)
+
+// options holds the settings applied by Option values.
+type options struct {
+	duplicatePolicy      DuplicatePolicy
+	dialect              Dialect
+	getentCommand        string
+	getentTimeout        time.Duration
+	root                 string
+	noLocking            bool
+	lockTimeout          time.Duration
+	strictUsernames      bool
+	idOverflow           IDOverflowPolicy
+	compact              bool
+	ttl                  time.Duration
+	statCheck            bool
+	writeOrder           WriteOrder
+	caseInsensitiveNames bool
+	normalization        UsernameNormalization
+	verbatim             bool
+	lenient              bool
+	collectErrors        bool
+	badLineHandler       func(lineNumber int, line string, err error)
+	logger               *slog.Logger
+	metrics              MetricsSink
+	reloadDiff           bool
+	// dryRun makes WriteToPath skip writing, it is only set on the copies used by DryRun
+	dryRun bool
+}
+
+// applyOptions returns the settings described by the given options.
+func applyOptions(opts []Option) options {
+	result := options{lockTimeout: DefaultLockTimeout}
+	for _, opt := range opts {
+		opt(&result)
+	}
+	return result
+}
+
+// WithRoot makes LoadDefault and the NewLoaded constructors read their files relative to the
+// given root directory instead of /, so that the databases of a mounted disk image or
+// container filesystem can be queried.
+func WithRoot(root string) Option {
+	return func(o *options) {
+		o.root = root
+	}
+}
+
+// WithoutLocking stops WriteToPath from taking the shadow-utils compatible locks, for example
+// when the caller already holds them with LockFile or the directory is not writable.
+func WithoutLocking() Option {
+	return func(o *options) {
+		o.noLocking = true
+	}
+}
+
+// WithLockTimeout changes how long WriteToPath waits for the locks, the default is
+// DefaultLockTimeout.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.lockTimeout = timeout
+	}
+}
+
+// defaultPath returns the location of the given system file. An environment variable named after
+// the file, such as ETCPWDPARSE_PASSWD for /etc/passwd, takes priority and is used exactly as
+// given. Otherwise the root option is applied.
+func (o options) defaultPath(path string) string {
+	name := strings.ToUpper(strings.Replace(filepath.Base(path), ".", "_", -1))
+	if override := os.Getenv(EnvPrefix + name); len(override) > 0 {
+		return override
+	}
+	if len(o.root) == 0 {
+		return path
+	}
+	return filepath.Join(o.root, path)
+}
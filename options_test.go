@@ -0,0 +1,75 @@
+package etcpwdparse
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestWithRoot(t *testing.T) {
+	root, _ := ioutil.TempDir("", "sysroot")
+	defer os.RemoveAll(root)
+	os.MkdirAll(path.Join(root, "etc"), 0755)
+	ioutil.WriteFile(path.Join(root, "etc", "passwd"), []byte("imageuser:x:4242:4242::/home/imageuser:/bin/sh\n"), 0644)
+	ioutil.WriteFile(path.Join(root, "etc", "group"), []byte("imagegroup:x:4242:imageuser\n"), 0644)
+	ioutil.WriteFile(path.Join(root, "etc", "subuid"), []byte("imageuser:100000:65536\n"), 0644)
+
+	passwd, err := NewLoadedEtcPasswdCache(WithRoot(root))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if uid, _ := passwd.UidForUsername("imageuser"); uid != 4242 {
+		t.Fatalf("%d != 4242", uid)
+	}
+
+	groups, err := NewLoadedEtcGroupCache(WithRoot(root))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, ok := groups.LookupGroupByName("imagegroup"); !ok {
+		t.Fatalf("imagegroup should have been found")
+	}
+
+	subuids, err := NewLoadedSubuidCache(WithRoot(root))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(subuids.RangesForOwner("imageuser")) != 1 {
+		t.Fatalf("imageuser should have a subuid range")
+	}
+
+	if _, err := NewLoadedEtcShadowCache(WithRoot(root)); !os.IsNotExist(err) {
+		t.Fatalf("shadow should not exist under the root: %v", err)
+	}
+}
+
+func TestEnvironmentOverride(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "custom-passwd")
+	ioutil.WriteFile(pwFile, []byte("envuser:x:777:777::/:/bin/sh\n"), 0644)
+	defsFile := path.Join(tempDir, "custom-defs")
+	ioutil.WriteFile(defsFile, []byte("UID_MIN 2000\n"), 0644)
+
+	os.Setenv("ETCPWDPARSE_PASSWD", pwFile)
+	defer os.Unsetenv("ETCPWDPARSE_PASSWD")
+	os.Setenv("ETCPWDPARSE_LOGIN_DEFS", defsFile)
+	defer os.Unsetenv("ETCPWDPARSE_LOGIN_DEFS")
+
+	cache, err := NewLoadedEtcPasswdCache(WithRoot("/does/not/exist"))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if uid, _ := cache.UidForUsername("envuser"); uid != 777 {
+		t.Fatalf("%d != 777", uid)
+	}
+
+	defs, err := NewLoadedLoginDefs()
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if defs.UidMin() != 2000 {
+		t.Fatalf("%d != 2000", defs.UidMin())
+	}
+}
@@ -0,0 +1,58 @@
+package etcpwdparse
+
+import (
+	"sort"
+)
+
+// WriteOrder sets the order in which WriteTo writes the entries.
+type WriteOrder int
+
+const (
+	// WritePreserveOrder writes the entries in the order they were loaded and added. This is
+	// the default.
+	WritePreserveOrder WriteOrder = iota
+	// WriteSortByUid writes the entries by ascending user id, keeping file order for equal ids.
+	WriteSortByUid
+	// WriteSortByUsername writes the entries by username, compared byte by byte.
+	WriteSortByUsername
+)
+
+// WithWriteOrder sets the order in which WriteTo, and so WriteToPath and Save, write the entries.
+// Only the entry lines are reordered: comments, blank lines, and NIS compat lines keep their
+// positions and the sorted entries fill the remaining lines, so a file that is already sorted
+// is written without changes.
+func WithWriteOrder(order WriteOrder) Option {
+	return func(o *options) {
+		o.writeOrder = order
+	}
+}
+
+// orderedLines returns the lines in the order they are written. The caller must hold the lock.
+func (e *EtcPasswdCache) orderedLines() []passwdLine {
+	if e.opts.writeOrder == WritePreserveOrder {
+		return e.lines
+	}
+	sorted := make([]passwdLine, 0, len(e.entries))
+	for _, l := range e.lines {
+		if l.entry >= 0 {
+			sorted = append(sorted, l)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := e.entries[sorted[i].entry], e.entries[sorted[j].entry]
+		if e.opts.writeOrder == WriteSortByUid {
+			return a.uid < b.uid
+		}
+		return a.username < b.username
+	})
+	result := make([]passwdLine, len(e.lines))
+	next := 0
+	for i, l := range e.lines {
+		if l.entry >= 0 {
+			l = sorted[next]
+			next++
+		}
+		result[i] = l
+	}
+	return result
+}
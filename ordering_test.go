@@ -0,0 +1,82 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const fakeOrderingContent = `# local accounts
+root:x:0:0:root:/root:/bin/bash
+carol:x:1002:1002::/home/carol:/bin/bash
+daemon:x:2:2:daemon:/sbin:/sbin/nologin
+
+alice:x:1000:1000::/home/alice:/bin/bash
++@admins
+`
+
+func TestWriteOrder(t *testing.T) {
+	cases := []struct {
+		order    WriteOrder
+		expected string
+	}{
+		{WritePreserveOrder, fakeOrderingContent},
+		{WriteSortByUid, `# local accounts
+root:x:0:0:root:/root:/bin/bash
+daemon:x:2:2:daemon:/sbin:/sbin/nologin
+alice:x:1000:1000::/home/alice:/bin/bash
+
+carol:x:1002:1002::/home/carol:/bin/bash
++@admins
+`},
+		{WriteSortByUsername, `# local accounts
+alice:x:1000:1000::/home/alice:/bin/bash
+carol:x:1002:1002::/home/carol:/bin/bash
+daemon:x:2:2:daemon:/sbin:/sbin/nologin
+
+root:x:0:0:root:/root:/bin/bash
++@admins
+`},
+	}
+	for _, c := range cases {
+		cache := NewEtcPasswdCache(false, WithWriteOrder(c.order))
+		if err := cache.LoadFromReader(strings.NewReader(fakeOrderingContent)); err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		buf := new(bytes.Buffer)
+		if _, err := cache.WriteTo(buf); err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		if buf.String() != c.expected {
+			t.Fatalf("order %d: %q != %q", c.order, buf.String(), c.expected)
+		}
+
+		// writing sorted content again changes nothing
+		again := NewEtcPasswdCache(false, WithWriteOrder(c.order))
+		if err := again.LoadFromReader(strings.NewReader(c.expected)); err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		second := new(bytes.Buffer)
+		again.WriteTo(second)
+		if second.String() != c.expected {
+			t.Fatalf("order %d: %q != %q", c.order, second.String(), c.expected)
+		}
+	}
+}
+
+func TestWriteOrderNewEntries(t *testing.T) {
+	cache := NewEtcPasswdCache(false, WithWriteOrder(WriteSortByUid))
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nbob:x:1001:1001::/home/bob:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	gid := 1000
+	if _, err := cache.AddUser(UserSpec{Username: "alice", Uid: 1000, Gid: &gid, Homedir: "/home/alice", Shell: "/bin/bash"}); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	expected := "root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\nbob:x:1001:1001::/home/bob:/bin/bash\n"
+	if buf.String() != expected {
+		t.Fatalf("%q != %q", buf.String(), expected)
+	}
+}
@@ -0,0 +1,104 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// ToOsUser converts the entry into the type used by the os/user package. The Name field is
+// taken from the first comma separated part of the info field as the standard library does.
+func (e *EtcPasswdEntry) ToOsUser() *user.User {
+	return &user.User{
+		Uid:      strconv.Itoa(e.uid),
+		Gid:      strconv.Itoa(e.gid),
+		Username: e.username,
+		Name:     strings.SplitN(e.info, ",", 2)[0],
+		HomeDir:  e.homedir,
+	}
+}
+
+// FromOsUser converts a user from the os/user package into an entry. The os/user type does not
+// carry a password or shell so the password is set to "x" and the shell is left empty. An error
+// is returned if the ids are not numeric, as is the case on Windows.
+func FromOsUser(u *user.User) (EtcPasswdEntry, error) {
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return EtcPasswdEntry{}, fmt.Errorf("User had non numeric uid %s", u.Uid)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return EtcPasswdEntry{}, fmt.Errorf("User had non numeric gid %s", u.Gid)
+	}
+	return NewEtcPasswdEntry(u.Username, "x", uid, gid, u.Name, u.HomeDir, "")
+}
+
+// OsUserAdapter provides the lookup functions of the os/user package backed by this package's
+// caches so that code written against the standard library can run without cgo. The group
+// cache is optional; group lookups fail when it is nil.
+type OsUserAdapter struct {
+	Passwd *EtcPasswdCache
+	Groups *EtcGroupCache
+}
+
+// NewOsUserAdapter returns an adapter over the given caches.
+func NewOsUserAdapter(passwd *EtcPasswdCache, groups *EtcGroupCache) *OsUserAdapter {
+	return &OsUserAdapter{Passwd: passwd, Groups: groups}
+}
+
+// Current returns the user entry for the current process uid, like user.Current.
+func (a *OsUserAdapter) Current() (*user.User, error) {
+	return a.LookupId(strconv.Itoa(os.Getuid()))
+}
+
+// Lookup looks up a user by username, like user.Lookup.
+func (a *OsUserAdapter) Lookup(username string) (*user.User, error) {
+	entry, ok := a.Passwd.LookupUserByName(username)
+	if !ok {
+		return nil, user.UnknownUserError(username)
+	}
+	return entry.ToOsUser(), nil
+}
+
+// LookupId looks up a user by user id, like user.LookupId.
+func (a *OsUserAdapter) LookupId(uid string) (*user.User, error) {
+	id, err := strconv.Atoi(uid)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := a.Passwd.LookupUserByUid(id)
+	if !ok {
+		return nil, user.UnknownUserIdError(id)
+	}
+	return entry.ToOsUser(), nil
+}
+
+// LookupGroup looks up a group by name, like user.LookupGroup.
+func (a *OsUserAdapter) LookupGroup(name string) (*user.Group, error) {
+	if a.Groups == nil {
+		return nil, user.UnknownGroupError(name)
+	}
+	entry, ok := a.Groups.LookupGroupByName(name)
+	if !ok {
+		return nil, user.UnknownGroupError(name)
+	}
+	return &user.Group{Gid: strconv.Itoa(entry.Gid()), Name: entry.Name()}, nil
+}
+
+// LookupGroupId looks up a group by group id, like user.LookupGroupId.
+func (a *OsUserAdapter) LookupGroupId(gid string) (*user.Group, error) {
+	id, err := strconv.Atoi(gid)
+	if err != nil {
+		return nil, err
+	}
+	if a.Groups == nil {
+		return nil, user.UnknownGroupIdError(gid)
+	}
+	entry, ok := a.Groups.LookupGroupByGid(id)
+	if !ok {
+		return nil, user.UnknownGroupIdError(gid)
+	}
+	return &user.Group{Gid: strconv.Itoa(entry.Gid()), Name: entry.Name()}, nil
+}
@@ -0,0 +1,62 @@
+package etcpwdparse
+
+import (
+	"os/user"
+	"strings"
+	"testing"
+)
+
+func TestOsUserConversion(t *testing.T) {
+	entry, _ := ParsePasswdLine("alice:x:1000:100:Alice Smith,Room 1,,:/home/alice:/bin/bash")
+	u := entry.ToOsUser()
+	if u.Uid != "1000" || u.Gid != "100" || u.Username != "alice" || u.Name != "Alice Smith" || u.HomeDir != "/home/alice" {
+		t.Fatalf("unexpected user %+v", u)
+	}
+
+	back, err := FromOsUser(u)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if back.Uid() != 1000 || back.Info() != "Alice Smith" || back.Password() != "x" {
+		t.Fatalf("unexpected entry %+v", back)
+	}
+	if _, err := FromOsUser(&user.User{Uid: "S-1-5-21", Gid: "1", Username: "win"}); err == nil {
+		t.Fatalf("Should have failed on a non numeric uid")
+	}
+}
+
+func TestOsUserAdapter(t *testing.T) {
+	passwd := NewEtcPasswdCache(false)
+	if err := passwd.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	groups := NewEtcGroupCache(false)
+	if err := groups.LoadFromReader(strings.NewReader(fakeGroupContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	adapter := NewOsUserAdapter(passwd, groups)
+
+	if u, err := adapter.Lookup("mail"); err != nil || u.Uid != "8" {
+		t.Fatalf("mail should have been found: %v", err)
+	}
+	if _, err := adapter.Lookup("missing"); err == nil {
+		t.Fatalf("Should have failed on a missing user")
+	} else if _, ok := err.(user.UnknownUserError); !ok {
+		t.Fatalf("%T should have been user.UnknownUserError", err)
+	}
+	if u, err := adapter.LookupId("99"); err != nil || u.Username != "nobody" {
+		t.Fatalf("nobody should have been found: %v", err)
+	}
+	if _, err := adapter.LookupId("12345"); err == nil {
+		t.Fatalf("Should have failed on a missing uid")
+	}
+	if g, err := adapter.LookupGroup("wheel"); err != nil || g.Gid != "10" {
+		t.Fatalf("wheel should have been found: %v", err)
+	}
+	if g, err := adapter.LookupGroupId("100"); err != nil || g.Name != "users" {
+		t.Fatalf("users should have been found: %v", err)
+	}
+	if _, err := NewOsUserAdapter(passwd, nil).LookupGroup("wheel"); err == nil {
+		t.Fatalf("Should have failed without a group cache")
+	}
+}
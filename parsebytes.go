@@ -0,0 +1,80 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// MaxParseErrors is the most bad lines that ParsePasswd reports individually. Any further bad
+// lines are counted in a single final error.
+const MaxParseErrors = 100
+
+// utf8BOM is the byte order mark some editors write at the start of a UTF-8 file.
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// ParsePasswd parses passwd content held in memory, such as a file read from a disk image,
+// without trusting it to be well formed. It never stops early: every line that parses is
+// returned in file order, and every bad line is skipped and reported. The input is handled as
+// follows.
+//
+//   - A UTF-8 byte order mark at the start of the content is dropped.
+//   - Lines may end in "\n" or "\r\n", and the last line need not have a line ending.
+//   - A line containing a NUL byte is a bad line, rather than being cut short at the NUL as C
+//     code reading the file would.
+//   - A line longer than MaxLineLength is a bad line with no RawLine in its error.
+//   - Comments, blank lines, and NIS compat lines are skipped.
+//
+// The error is nil if every line parsed, otherwise it joins a *ParseError for each of the
+// first MaxParseErrors bad lines, followed by one error counting the rest. Each error matches
+// ErrBadLine with errors.Is.
+func ParsePasswd(data []byte) ([]EtcPasswdEntry, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	// the slice grows with the entries found rather than being sized from the line count, which
+	// would let content of bare newlines ask for far more memory than its own size
+	entries := make([]EtcPasswdEntry, 0)
+	errs := make([]error, 0)
+	dropped := 0
+	report := func(err error) {
+		if len(errs) < MaxParseErrors {
+			errs = append(errs, err)
+		} else {
+			dropped++
+		}
+	}
+
+	for lineNumber := 1; len(data) > 0; lineNumber++ {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+
+		if len(line) > MaxLineLength {
+			report(&ParseError{LineNumber: lineNumber, Err: fmt.Errorf("Line is longer than %d bytes", MaxLineLength)})
+			continue
+		}
+		raw := string(line)
+		if bytes.IndexByte(line, 0) >= 0 {
+			report(&ParseError{LineNumber: lineNumber, RawLine: raw, Err: fmt.Errorf("Line contains a NUL byte")})
+			continue
+		}
+		trimmed := string(bytes.TrimSpace(line))
+		if isSkippedLine(trimmed) || IsCompatLine(trimmed) {
+			continue
+		}
+		entry, err := ParsePasswdLine(trimmed)
+		if err != nil {
+			report(withLineNumber(err, lineNumber, raw))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if dropped > 0 {
+		errs = append(errs, &ParseError{Err: fmt.Errorf("%d more bad lines were not reported", dropped)})
+	}
+	return entries, errors.Join(errs...)
+}
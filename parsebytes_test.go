@@ -0,0 +1,91 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParsePasswd(t *testing.T) {
+	data := "\xef\xbb\xbfroot:x:0:0:root:/root:/bin/bash\r\n" +
+		"# comment\r\n" +
+		"\r\n" +
+		"+@admins\r\n" +
+		"evil:x:0:0::/root:/bin/sh\x00:x:1:1::/:/bin/sh\r\n" +
+		"broken\r\n" +
+		"long:x:1:1:" + strings.Repeat("a", MaxLineLength) + ":/:/bin/sh\n" +
+		"bob:x:1000:1000::/home/bob:/bin/sh"
+	entries, err := ParsePasswd([]byte(data))
+	if len(entries) != 2 || entries[0].Username() != "root" || entries[1].Username() != "bob" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+	if entries[0].Shell() != "/bin/bash" {
+		t.Fatalf("expected the carriage return to be dropped, got %q", entries[0].Shell())
+	}
+	if !errors.Is(err, ErrBadLine) {
+		t.Fatalf("expected bad lines, got %v", err)
+	}
+	lines := strings.Split(err.Error(), "\n")
+	expected := []string{
+		"line 5: Line contains a NUL byte",
+		"line 6: Passwd line had wrong number of parts 1 != 7",
+		"line 7: Line is longer than 1048576 bytes",
+	}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Fatalf("unexpected errors %q", lines)
+	}
+
+	entries, err = ParsePasswd(nil)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected nothing from empty content, got %v %v", entries, err)
+	}
+}
+
+func TestParsePasswdBoundsErrors(t *testing.T) {
+	data := strings.Repeat("broken\n", MaxParseErrors+5) + "root:x:0:0:root:/root:/bin/bash\n"
+	entries, err := ParsePasswd([]byte(data))
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != MaxParseErrors+1 {
+		t.Fatalf("unexpected errors %v", err)
+	}
+	if last := joined.Unwrap()[MaxParseErrors].Error(); last != "5 more bad lines were not reported" {
+		t.Fatalf("unexpected last error %q", last)
+	}
+}
+
+func FuzzParsePasswd(f *testing.F) {
+	f.Add([]byte("root:x:0:0:root:/root:/bin/bash\n"))
+	f.Add([]byte("\xef\xbb\xbfroot:x:0:0::/:\r\n+\n-@x\n#\n"))
+	f.Add([]byte("a:b:99999999999999999999:0:::\x00\n:::::::\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entries, err := ParsePasswd(data)
+		for _, entry := range entries {
+			// every entry must survive a write and parse cycle
+			if _, err := ParsePasswdLine(entry.String()); err != nil {
+				t.Fatalf("entry %q did not parse again: %s", entry.String(), err)
+			}
+		}
+		if err != nil && !errors.Is(err, ErrBadLine) {
+			t.Fatalf("unexpected error %s", err)
+		}
+	})
+}
+
+func TestParsePasswdBlankLinesAllocation(t *testing.T) {
+	data := bytes.Repeat([]byte{'\n'}, 1<<20)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	entries, err := ParsePasswd(data)
+	runtime.ReadMemStats(&after)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected no entries and no error: %d %v", len(entries), err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Fatalf("blank lines should not have allocated %d bytes", allocated)
+	}
+}
@@ -0,0 +1,73 @@
+package etcpwdparse
+
+import (
+	"strings"
+)
+
+// PasswordMarker classifies the content of a password field, covering the markers used by
+// Linux shadow-utils as well as the Solaris and AIX conventions.
+type PasswordMarker int
+
+const (
+	// PasswordHash means the field holds what looks like a real password hash
+	PasswordHash PasswordMarker = iota
+	// PasswordEmpty means the field is empty so no password is needed to log in
+	PasswordEmpty
+	// PasswordShadowed means the field is "x" and the real value lives in the shadow file
+	PasswordShadowed
+	// PasswordLocked means the account is locked with a "!" prefix or the Solaris "*LK*" marker
+	PasswordLocked
+	// PasswordNoLogin means password login is disabled with "*" or the Solaris "NP" marker
+	PasswordNoLogin
+)
+
+// ClassifyPassword returns the marker describing the given password field.
+func ClassifyPassword(password string) PasswordMarker {
+	switch {
+	case len(password) == 0:
+		return PasswordEmpty
+	case password == "x":
+		return PasswordShadowed
+	case strings.HasPrefix(password, "!") || strings.HasPrefix(password, "*LK*"):
+		return PasswordLocked
+	case strings.HasPrefix(password, "*") || password == "NP":
+		return PasswordNoLogin
+	}
+	return PasswordHash
+}
+
+// IsLocked returns true if the password field of the entry is locked with a "!", "!!", or
+// "*LK*" prefix
+func (e *EtcPasswdEntry) IsLocked() bool {
+	return ClassifyPassword(e.password) == PasswordLocked
+}
+
+// HasNoPassword returns true if the password field of the entry is empty so no password is
+// needed to log in
+func (e *EtcPasswdEntry) HasNoPassword() bool {
+	return ClassifyPassword(e.password) == PasswordEmpty
+}
+
+// PasswordInShadow returns true if the password field of the entry is "x" and the real value
+// lives in the shadow file
+func (e *EtcPasswdEntry) PasswordInShadow() bool {
+	return ClassifyPassword(e.password) == PasswordShadowed
+}
+
+// IsLocked returns true if the password field of the entry is locked with a "!", "!!", or
+// "*LK*" prefix
+func (e *EtcShadowEntry) IsLocked() bool {
+	return ClassifyPassword(e.password) == PasswordLocked
+}
+
+// HasNoPassword returns true if the password field of the entry is empty so no password is
+// needed to log in
+func (e *EtcShadowEntry) HasNoPassword() bool {
+	return ClassifyPassword(e.password) == PasswordEmpty
+}
+
+// PasswordInShadow returns true if the password field of the entry is "x". This is unusual in
+// the shadow file itself and usually points at a misconfigured system.
+func (e *EtcShadowEntry) PasswordInShadow() bool {
+	return ClassifyPassword(e.password) == PasswordShadowed
+}
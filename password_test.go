@@ -0,0 +1,57 @@
+package etcpwdparse
+
+import (
+	"testing"
+)
+
+func TestClassifyPassword(t *testing.T) {
+	cases := map[string]PasswordMarker{
+		"":                 PasswordEmpty,
+		"x":                PasswordShadowed,
+		"!":                PasswordLocked,
+		"!!":               PasswordLocked,
+		"!$6$salt$hash":    PasswordLocked,
+		"*LK*":             PasswordLocked,
+		"*LK*$5$salt$hash": PasswordLocked,
+		"*":                PasswordNoLogin,
+		"NP":               PasswordNoLogin,
+		"$6$salt$hash":     PasswordHash,
+	}
+	for password, expected := range cases {
+		if ClassifyPassword(password) != expected {
+			t.Fatalf("%q was classified as %d not %d", password, ClassifyPassword(password), expected)
+		}
+	}
+}
+
+func TestPasswordFieldHelpers(t *testing.T) {
+	cases := []struct {
+		password string
+		locked   bool
+		empty    bool
+		shadowed bool
+	}{
+		{"x", false, false, true},
+		{"", false, true, false},
+		{"!!", true, false, false},
+		{"*LK*", true, false, false},
+		{"*", false, false, false},
+		{"$6$salt$hash", false, false, false},
+	}
+	for _, c := range cases {
+		entry, err := ParsePasswdLine("alice:" + c.password + ":1000:1000::/home/alice:/bin/bash")
+		if err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		if entry.IsLocked() != c.locked || entry.HasNoPassword() != c.empty || entry.PasswordInShadow() != c.shadowed {
+			t.Fatalf("passwd entry with %q gave %v %v %v", c.password, entry.IsLocked(), entry.HasNoPassword(), entry.PasswordInShadow())
+		}
+		shadow, err := ParseShadowLine("alice:" + c.password + ":18000:0:99999:7:::")
+		if err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		if shadow.IsLocked() != c.locked || shadow.HasNoPassword() != c.empty || shadow.PasswordInShadow() != c.shadowed {
+			t.Fatalf("shadow entry with %q gave %v %v %v", c.password, shadow.IsLocked(), shadow.HasNoPassword(), shadow.PasswordInShadow())
+		}
+	}
+}
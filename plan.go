@@ -0,0 +1,66 @@
+package etcpwdparse
+
+// ChangeOperation is the kind of a planned change.
+type ChangeOperation string
+
+const (
+	// OperationAdd creates a new entry
+	OperationAdd ChangeOperation = "add"
+	// OperationModify changes the fields of an existing entry
+	OperationModify ChangeOperation = "modify"
+	// OperationRemove deletes an existing entry
+	OperationRemove ChangeOperation = "remove"
+)
+
+// PlannedChange is a single operation in a ChangePlan. Before is nil for additions and After is
+// nil for removals.
+type PlannedChange struct {
+	Operation ChangeOperation `json:"operation"`
+	Username  string          `json:"username"`
+	// Fields lists the names of the changed fields of a modification, as in FieldChange
+	Fields []string        `json:"fields,omitempty"`
+	Before *EtcPasswdEntry `json:"before,omitempty"`
+	After  *EtcPasswdEntry `json:"after,omitempty"`
+}
+
+// ChangePlan is a machine readable list of the changes needed to go from one passwd snapshot to
+// another, meant to be serialized with encoding/json and reviewed in approval workflows before
+// the changes are applied. Removals come first, then modifications, then additions.
+type ChangePlan struct {
+	// Path is the file the changes apply to, empty if the cache was not loaded from a path
+	Path       string          `json:"path,omitempty"`
+	Operations []PlannedChange `json:"operations"`
+}
+
+// Empty returns true if the plan has no operations.
+func (p *ChangePlan) Empty() bool {
+	return len(p.Operations) == 0
+}
+
+// Plan returns the differences as a list of operations.
+func (d *PasswdDiff) Plan() ChangePlan {
+	plan := ChangePlan{Operations: make([]PlannedChange, 0, len(d.Removed)+len(d.Modified)+len(d.Added))}
+	for i := range d.Removed {
+		entry := d.Removed[i]
+		plan.Operations = append(plan.Operations, PlannedChange{Operation: OperationRemove, Username: entry.username, Before: &entry})
+	}
+	for i := range d.Modified {
+		modified := d.Modified[i]
+		fields := make([]string, 0, len(modified.Changes))
+		for _, change := range modified.Changes {
+			fields = append(fields, change.Field)
+		}
+		plan.Operations = append(plan.Operations, PlannedChange{
+			Operation: OperationModify,
+			Username:  modified.Old.username,
+			Fields:    fields,
+			Before:    &modified.Old,
+			After:     &modified.New,
+		})
+	}
+	for i := range d.Added {
+		entry := d.Added[i]
+		plan.Operations = append(plan.Operations, PlannedChange{Operation: OperationAdd, Username: entry.username, After: &entry})
+	}
+	return plan
+}
@@ -0,0 +1,51 @@
+package etcpwdparse
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestChangePlan(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakeReconcileContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	result, err := cache.DryRun(func(c *EtcPasswdCache) error {
+		regular := func(entry *EtcPasswdEntry) bool { return entry.IsRegularAccount() }
+		users := 100
+		_, err := c.Reconcile([]UserSpec{
+			{Username: "alice", Shell: "/bin/zsh", Gid: &users},
+			{Username: "carol", Info: "Carol"},
+		}, ReconcileOptions{Prune: regular})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	data, err := json.Marshal(result.Plan)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	expected := `{"operations":[` +
+		`{"operation":"remove","username":"bob","before":{"username":"bob","password":"x","uid":1001,"gid":1001,"info":"Bob","homedir":"/home/bob","shell":"/bin/bash"}},` +
+		`{"operation":"modify","username":"alice","fields":["gid","shell"],"before":{"username":"alice","password":"x","uid":1000,"gid":1000,"info":"Alice","homedir":"/home/alice","shell":"/bin/bash"},"after":{"username":"alice","password":"x","uid":1000,"gid":100,"info":"Alice","homedir":"/home/alice","shell":"/bin/zsh"}},` +
+		`{"operation":"add","username":"carol","after":{"username":"carol","password":"x","uid":1002,"gid":1002,"info":"Carol","homedir":"/home/carol","shell":"/bin/sh"}}` +
+		`]}`
+	if string(data) != expected {
+		t.Fatalf("%s != %s", data, expected)
+	}
+
+	var decoded ChangePlan
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(decoded.Operations) != 3 || decoded.Operations[1].After.Shell() != "/bin/zsh" || decoded.Operations[0].After != nil {
+		t.Fatalf("unexpected decoded plan %+v", decoded)
+	}
+
+	empty := PasswdDiff{}
+	if plan := empty.Plan(); !plan.Empty() {
+		t.Fatalf("plan should be empty")
+	}
+}
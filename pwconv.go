@@ -0,0 +1,207 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Pwconv moves the password hashes from the passwd cache into the shadow cache and replaces them
+// with "x", like pwconv. Shadow entries without a passwd entry are dropped. A passwd entry that
+// already holds "x" and has a shadow entry is left alone. Otherwise the hash is copied into the
+// shadow entry and its last change field is set to the day of now. An entry with no shadow
+// entry gets a new one, using PASS_MIN_DAYS, PASS_MAX_DAYS, and PASS_WARN_AGE from defs for the
+// aging fields. Like pwconv those fields are left empty when the settings are missing, and defs
+// may be nil. Call PwconvFiles to convert the files on disk.
+func Pwconv(passwd *EtcPasswdCache, shadow *EtcShadowCache, defs *LoginDefs, now time.Time) {
+	if defs == nil {
+		defs = NewLoginDefs()
+	}
+	lastChange := epochDay(now)
+	if lastChange == 0 {
+		// pwconv disables aging rather than forcing a password change
+		lastChange = -1
+	}
+	passwd.mu.Lock()
+	defer passwd.mu.Unlock()
+
+	entries := make([]*EtcShadowEntry, 0, len(passwd.entries))
+	index := make(map[string]int)
+	for _, entry := range shadow.entries {
+		if _, ok := passwd.namemap[passwd.opts.nameKey(entry.username)]; ok {
+			index[shadow.opts.nameKey(entry.username)] = len(entries)
+			entries = append(entries, entry)
+		}
+	}
+	updated := make([]*EtcPasswdEntry, len(passwd.entries))
+	copy(updated, passwd.entries)
+	for i, entry := range passwd.entries {
+		key := shadow.opts.nameKey(entry.username)
+		moved := EtcShadowEntry{
+			username: entry.username,
+			min:      defs.Int("PASS_MIN_DAYS", -1),
+			max:      defs.Int("PASS_MAX_DAYS", -1),
+			warn:     defs.Int("PASS_WARN_AGE", -1),
+			inactive: -1,
+			expire:   -1,
+		}
+		j, ok := index[key]
+		if ok {
+			if entry.password == "x" {
+				continue
+			}
+			moved = *entries[j]
+		} else {
+			j = len(entries)
+			index[key] = j
+			entries = append(entries, nil)
+		}
+		moved.password = entry.password
+		moved.lastchange = lastChange
+		entries[j] = &moved
+
+		shadowed := *entry
+		shadowed.password = "x"
+		updated[i] = &shadowed
+	}
+	passwd.rebuildEntries(updated, func(*EtcPasswdEntry) bool { return false }, true)
+	shadow.replaceEntries(entries)
+}
+
+// Pwunconv moves the password hashes from the shadow cache back into the passwd cache, like
+// pwunconv, and empties the shadow cache. Passwd entries without a shadow entry keep their
+// password field. The aging information in the shadow entries is lost. Call PwunconvFiles to
+// convert the files on disk.
+func Pwunconv(passwd *EtcPasswdCache, shadow *EtcShadowCache) {
+	passwd.mu.Lock()
+	defer passwd.mu.Unlock()
+	updated := make([]*EtcPasswdEntry, len(passwd.entries))
+	copy(updated, passwd.entries)
+	for i, entry := range passwd.entries {
+		if hash, ok := shadow.namemap[shadow.opts.nameKey(entry.username)]; ok {
+			restored := *entry
+			restored.password = hash.password
+			updated[i] = &restored
+		}
+	}
+	passwd.rebuildEntries(updated, func(*EtcPasswdEntry) bool { return false }, true)
+	shadow.replaceEntries(make([]*EtcShadowEntry, 0))
+}
+
+// conversionFile is the original content of a file changed by PwconvFiles or PwunconvFiles
+type conversionFile struct {
+	path     string
+	original []byte
+	exists   bool
+	perm     os.FileMode
+	info     os.FileInfo
+}
+
+// readConversionFile reads the original content of the file, which may not exist.
+func readConversionFile(path string, defaultPerm os.FileMode) (*conversionFile, error) {
+	result := &conversionFile{path: path, perm: defaultPerm}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return result, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if result.original, err = ioutil.ReadFile(path); err != nil {
+		return nil, err
+	}
+	result.exists, result.perm, result.info = true, info.Mode().Perm(), info
+	return result, nil
+}
+
+// backup writes the original content to the path with BackupSuffix appended
+func (f *conversionFile) backup() error {
+	if !f.exists {
+		return nil
+	}
+	return writeFileAtomicLike(f.path+BackupSuffix, f.original, f.perm, f.info)
+}
+
+// install backs up the original content and then writes the new content in its place
+func (f *conversionFile) install(content io.WriterTo) error {
+	buf := new(bytes.Buffer)
+	if _, err := content.WriteTo(buf); err != nil {
+		return err
+	}
+	if err := f.backup(); err != nil {
+		return err
+	}
+	return writeFileAtomic(f.path, buf.Bytes(), f.perm)
+}
+
+// convertFiles locks the passwd and shadow files, loads them into caches, and calls fn with
+// them. The locks are held until fn returns.
+func convertFiles(passwdPath, shadowPath string, opts []Option, fn func(pw, sp *conversionFile, passwd *EtcPasswdCache, shadow *EtcShadowCache) error) error {
+	passwd := NewEtcPasswdCache(false, append(opts, WithoutLocking())...)
+	lock, err := lockFiles(passwd.opts.lockTimeout, passwdPath, shadowPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	pw, err := readConversionFile(passwdPath, 0644)
+	if err != nil {
+		return err
+	}
+	if !pw.exists {
+		return &os.PathError{Op: "open", Path: passwdPath, Err: os.ErrNotExist}
+	}
+	sp, err := readConversionFile(shadowPath, 0600)
+	if err != nil {
+		return err
+	}
+	if err := passwd.load(bytes.NewReader(pw.original), passwdPath); err != nil {
+		return err
+	}
+	shadow := NewEtcShadowCache(false, opts...)
+	if err := shadow.LoadFromReader(bytes.NewReader(sp.original)); err != nil {
+		return err
+	}
+	return fn(pw, sp, passwd, shadow)
+}
+
+// PwconvFiles runs Pwconv on the passwd and shadow files at the given paths, creating the shadow
+// file readable only by its owner if it does not exist. Both files are locked with the same
+// locks as LockFile while they change, and the aging settings are read from login.defs relative
+// to the root given by WithRoot. Each original file is first saved with BackupSuffix appended,
+// and the shadow file is written before the passwd file so that a failure part way never loses
+// a hash. Comments and blank lines in the shadow file are not kept.
+func PwconvFiles(passwdPath, shadowPath string, opts ...Option) error {
+	defs := NewLoginDefs(opts...)
+	if err := defs.LoadDefault(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return convertFiles(passwdPath, shadowPath, opts, func(pw, sp *conversionFile, passwd *EtcPasswdCache, shadow *EtcShadowCache) error {
+		Pwconv(passwd, shadow, defs, time.Now())
+		if err := sp.install(shadow); err != nil {
+			return err
+		}
+		return pw.install(passwd)
+	})
+}
+
+// PwunconvFiles runs Pwunconv on the passwd and shadow files at the given paths and then removes
+// the shadow file like pwunconv, doing nothing if there is no shadow file. Both files are locked
+// with the same locks as LockFile while they change. Each original file is first saved with
+// BackupSuffix appended, so the aging information can still be recovered from the shadow backup.
+func PwunconvFiles(passwdPath, shadowPath string, opts ...Option) error {
+	return convertFiles(passwdPath, shadowPath, opts, func(pw, sp *conversionFile, passwd *EtcPasswdCache, shadow *EtcShadowCache) error {
+		if !sp.exists {
+			return nil
+		}
+		Pwunconv(passwd, shadow)
+		if err := pw.install(passwd); err != nil {
+			return err
+		}
+		if err := sp.backup(); err != nil {
+			return err
+		}
+		return os.Remove(shadowPath)
+	})
+}
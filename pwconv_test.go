@@ -0,0 +1,155 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const pwconvPasswd = `root:$6$salt$roothash:0:0:root:/root:/bin/bash
+# service accounts
+daemon:x:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+bob:$6$salt$bobhash:1000:1000::/home/bob:/bin/sh
+`
+
+const pwconvShadow = `root:$6$salt$oldhash:19000:0:99999:7:::
+daemon:*:19000:0:99999:7:::
+gone:*:19000:0:99999:7:::
+`
+
+func TestPwconv(t *testing.T) {
+	passwd := NewEtcPasswdCache(false)
+	if err := passwd.LoadFromReader(strings.NewReader(pwconvPasswd)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	shadow := NewEtcShadowCache(false)
+	if err := shadow.LoadFromReader(strings.NewReader(pwconvShadow)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	defs := NewLoginDefs()
+	defs.LoadFromReader(strings.NewReader("PASS_MAX_DAYS 90\n"))
+
+	Pwconv(passwd, shadow, defs, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	buf := new(bytes.Buffer)
+	passwd.WriteTo(buf)
+	expected := `root:x:0:0:root:/root:/bin/bash
+# service accounts
+daemon:x:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+bob:x:1000:1000::/home/bob:/bin/sh
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected passwd %q", buf.String())
+	}
+	buf.Reset()
+	shadow.WriteTo(buf)
+	expected = `root:$6$salt$roothash:19723:0:99999:7:::
+daemon:*:19000:0:99999:7:::
+bob:$6$salt$bobhash:19723::90::::
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected shadow %q", buf.String())
+	}
+	if entry, ok := passwd.LookupUserByName("bob"); !ok || entry.Password() != "x" {
+		t.Fatalf("expected bob to be shadowed")
+	}
+	if _, ok := shadow.LookupUserByName("gone"); ok {
+		t.Fatalf("expected gone to be dropped")
+	}
+
+	Pwunconv(passwd, shadow)
+	buf.Reset()
+	passwd.WriteTo(buf)
+	expected = `root:$6$salt$roothash:0:0:root:/root:/bin/bash
+# service accounts
+daemon:*:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+bob:$6$salt$bobhash:1000:1000::/home/bob:/bin/sh
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected passwd %q", buf.String())
+	}
+	if len(shadow.ListEntries()) != 0 {
+		t.Fatalf("expected an empty shadow cache")
+	}
+}
+
+func TestPwconvFiles(t *testing.T) {
+	dir := t.TempDir()
+	passwdPath, shadowPath := filepath.Join(dir, "passwd"), filepath.Join(dir, "shadow")
+	os.WriteFile(passwdPath, []byte(pwconvPasswd), 0644)
+
+	// the shadow file is created when missing
+	if err := PwconvFiles(passwdPath, shadowPath, WithRoot(dir)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	shadow := NewEtcShadowCache(false)
+	if err := shadow.LoadFromPath(shadowPath); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry, ok := shadow.LookupUserByName("root"); !ok || entry.Password() != "$6$salt$roothash" {
+		t.Fatalf("expected the root hash in shadow")
+	}
+	if info, _ := os.Stat(shadowPath); info.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected shadow permissions %v", info.Mode().Perm())
+	}
+	if backup, _ := os.ReadFile(passwdPath + BackupSuffix); string(backup) != pwconvPasswd {
+		t.Fatalf("unexpected passwd backup %q", backup)
+	}
+	if _, err := os.Stat(shadowPath + BackupSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected no shadow backup for a new shadow file")
+	}
+	for _, name := range []string{"passwd.lock", "shadow.lock"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be released", name)
+		}
+	}
+
+	if err := PwunconvFiles(passwdPath, shadowPath, WithRoot(dir)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if content, _ := os.ReadFile(passwdPath); string(content) != pwconvPasswd {
+		t.Fatalf("unexpected passwd %q", content)
+	}
+	if _, err := os.Stat(shadowPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the shadow file to be removed")
+	}
+	if _, err := os.Stat(shadowPath + BackupSuffix); err != nil {
+		t.Fatalf("expected a shadow backup: %s", err)
+	}
+
+	// without a shadow file there is nothing to do
+	if err := PwunconvFiles(passwdPath, shadowPath, WithRoot(dir)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+}
+
+func TestPwconvFilesKeepsOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of a file needs root")
+	}
+	dir := t.TempDir()
+	passwdPath, shadowPath := filepath.Join(dir, "passwd"), filepath.Join(dir, "shadow")
+	os.WriteFile(passwdPath, []byte(pwconvPasswd), 0644)
+	os.WriteFile(shadowPath, []byte("root:*:18000:0:99999:7:::\n"), 0640)
+	if err := os.Chown(shadowPath, 0, 42); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	if err := PwconvFiles(passwdPath, shadowPath, WithRoot(dir)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	for _, path := range []string{shadowPath, shadowPath + BackupSuffix} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		if uid, gid, ok := fileOwner(info); ok && (uid != 0 || gid != 42) {
+			t.Fatalf("expected %s to be owned by 0:42, got %d:%d", path, uid, gid)
+		}
+		if info.Mode().Perm() != 0640 {
+			t.Fatalf("unexpected %s permissions %v", path, info.Mode().Perm())
+		}
+	}
+}
@@ -6,10 +6,17 @@
 package etcpwdparse
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // EtcPasswdEntry is a parsed line from the etc passwd file. It contains all 7 parts of the structure.
@@ -22,6 +29,11 @@ type EtcPasswdEntry struct {
 	info     string
 	homedir  string
 	shell    string
+
+	// BSD master.passwd fields
+	class  string
+	change int64
+	expire int64
 }
 
 // Username function returns the username string for the entry
@@ -60,119 +72,636 @@ func (e *EtcPasswdEntry) Shell() string {
 }
 
 // EtcPasswdCache is an object that stores a set of entries from the passwd file and
-// has quick lookup functions.
+// has quick lookup functions. It is safe for concurrent use; loading replaces the content
+// in a single step so lookups running alongside a load see either the old or new content.
+//
+// Every accessor returns pointers to the same entry objects, so an entry found by name is the
+// same object found by uid, in ListEntries, or through the iterators. These entries are shared
+// and must be treated as read-only. Changes such as ModifyUser never modify an entry in place,
+// they store a new entry object instead, so a pointer held by the caller keeps describing the
+// entry as it was when it was looked up.
 type EtcPasswdCache struct {
-	entries        []EtcPasswdEntry
+	mu             sync.RWMutex
+	entries        []*EtcPasswdEntry
 	namemap        map[string]*EtcPasswdEntry
 	idmap          map[int]*EtcPasswdEntry
+	gidmap         map[int][]*EtcPasswdEntry
+	shellmap       map[string][]*EtcPasswdEntry
+	homedirmap     map[string]*EtcPasswdEntry
+	uidindex       []*EtcPasswdEntry
+	lines          []passwdLine
+	duplicates     []Duplicate
+	compat         []*CompatEntry
+	badLines       int
+	path           string
 	ignoreBadLines bool
+	opts           options
+
+	watchMu   sync.Mutex
+	watchStop chan struct{}
+	watchDone chan struct{}
+
+	reloadMu       sync.Mutex
+	loadedAt       time.Time
+	loadedInfo     os.FileInfo
+	failedInfo     os.FileInfo
+	lastReloadDiff PasswdDiff
+	// loadingLine is the number of the line being parsed into a load target, recorded on the
+	// entries added from it
+	loadingLine int
+
+	subMu       sync.Mutex
+	subscribers []*subscriber
+}
+
+// passwdLine records a line of the loaded file so that comments, blank lines, and ignored bad
+// lines survive a load and write cycle in their original order. Lines that are not entries have
+// an entry index of -1. Entries added after loading have no raw content.
+type passwdLine struct {
+	raw   string
+	entry int
+	// number is the 1-based line the entry was loaded from, 0 for entries added later
+	number int
+}
+
+// splitFields splits the line on ':' into parts without allocating. It returns the number of
+// fields in the line, which is only stored in parts if it equals len(parts).
+func splitFields(line string, parts []string) int {
+	n := strings.Count(line, ":") + 1
+	if n != len(parts) {
+		return n
+	}
+	for i := 0; i < n-1; i++ {
+		j := strings.IndexByte(line, ':')
+		parts[i], line = line[:j], line[j+1:]
+	}
+	parts[n-1] = line
+	return n
+}
+
+// ParsePasswdLineBytes is like ParsePasswdLine but takes the line as bytes, for example a line
+// returned by bufio.Reader.ReadSlice. The line is copied into a single string that all the
+// fields share, so parsing costs one allocation regardless of the number of fields.
+func ParsePasswdLineBytes(line []byte) (EtcPasswdEntry, error) {
+	return parsePasswdLine(string(line), &options{})
 }
 
 // ParsePasswdLine is a function used to parse a 7 entry /etc/passwd line formatted line
-// into a EtcPasswdEntry object.
+// into a EtcPasswdEntry object. Errors are returned as a *ParseError.
 func ParsePasswdLine(line string) (EtcPasswdEntry, error) {
+	return parsePasswdLine(line, &options{})
+}
+
+// parsePasswdLine implements ParsePasswdLine with the id overflow policy, field trimming, and
+// lenient field counting of the given options.
+func parsePasswdLine(line string, o *options) (EtcPasswdEntry, error) {
 	result := EtcPasswdEntry{}
-	parts := strings.Split(strings.TrimSpace(line), ":")
-	if len(parts) != 7 {
-		return result, fmt.Errorf("Passwd line had wrong number of parts %d != 7", len(parts))
+	var parts [7]string
+	n := splitFields(o.trimLine(line), parts[:])
+	if n != 7 && o.lenient {
+		n = splitLenientFields(o.trimLine(line), parts[:])
 	}
-	result.username = strings.TrimSpace(parts[0])
-	result.password = strings.TrimSpace(parts[1])
+	if n != 7 {
+		return result, newParseError(line, "", "Passwd line had wrong number of parts %d != 7", n)
+	}
+	result.username = o.trimField(parts[0])
+	result.password = o.trimField(parts[1])
 
-	uid, err := strconv.Atoi(parts[2])
+	uid, err := parseID(parts[2], o.idOverflow)
 	if err != nil {
-		return result, fmt.Errorf("Passwd line had badly formatted uid %s", parts[2])
+		return result, newParseError(line, "uid", "Passwd line had badly formatted uid %s: %w", parts[2], err)
 	}
 	result.uid = uid
 
-	gid, err := strconv.Atoi(parts[3])
+	gid, err := parseID(parts[3], o.idOverflow)
 	if err != nil {
-		return result, fmt.Errorf("Passwd line had badly formatted gid %s", parts[2])
+		return result, newParseError(line, "gid", "Passwd line had badly formatted gid %s: %w", parts[3], err)
 	}
 	result.gid = gid
 
-	result.info = strings.TrimSpace(parts[4])
-	result.homedir = strings.TrimSpace(parts[5])
-	result.shell = strings.TrimSpace(parts[6])
+	result.info = o.trimField(parts[4])
+	result.homedir = o.trimField(parts[5])
+	result.shell = o.trimField(parts[6])
 	return result, nil
 }
 
 // AddEntry adds an entry object to the cache object and links it into the lookup maps.
-// Overrides any existing item in the lookup maps.
-func (e *EtcPasswdCache) AddEntry(entry EtcPasswdEntry) {
+// By default it overrides any existing item in the lookup maps, see WithDuplicatePolicy for the
+// alternatives. An error matching ErrDuplicateUser is only returned when the policy is DuplicateError.
+func (e *EtcPasswdCache) AddEntry(entry EtcPasswdEntry) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.addEntry(entry)
+}
+
+// UpsertEntry replaces the entry that LookupUserByName returns for the username of the given
+// entry, keeping its line in the file, or adds it like AddEntry if there is none. The uid index
+// follows a changed uid, but a uid that another entry already uses is rejected. It returns true
+// if an existing entry was replaced.
+func (e *EtcPasswdCache) UpsertEntry(entry EtcPasswdEntry) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	current, ok := e.namemap[e.opts.nameKey(entry.username)]
+	if !ok {
+		return false, e.addEntry(entry)
+	}
+	if _, ok := e.idmap[entry.uid]; ok && entry.uid != current.uid {
+		return false, sentinelf(ErrDuplicateUser, "User with uid %d already exists", entry.uid)
+	}
+	e.replaceEntry(current, &entry)
+	return true, nil
+}
+
+// addEntry implements AddEntry. The caller must hold the write lock.
+func (e *EtcPasswdCache) addEntry(entry EtcPasswdEntry) error {
+	if e.namemap == nil {
+		e.reset()
+	}
+	if err := e.addUnparsedWithPolicy(entry); err != nil {
+		return err
+	}
+	// a replaced duplicate leaves the index the same length, so always settle the last entry,
+	// which does nothing when it is already in place
+	if len(e.uidindex) > 0 {
+		e.settleLastUidIndexEntry()
+	}
+	return nil
+}
+
+// newLoadTarget returns an empty cache with the same settings to load fresh content into.
+func (e *EtcPasswdCache) newLoadTarget(path string) *EtcPasswdCache {
+	next := &EtcPasswdCache{path: path, ignoreBadLines: e.ignoreBadLines, opts: e.opts}
+	next.reset()
+	return next
+}
+
+// reset replaces the content with empty structures. The caller must hold the write lock.
+func (e *EtcPasswdCache) reset() {
+	e.entries = make([]*EtcPasswdEntry, 0)
+	e.namemap = make(map[string]*EtcPasswdEntry)
+	e.idmap = make(map[int]*EtcPasswdEntry)
+	e.gidmap = make(map[int][]*EtcPasswdEntry)
+	e.shellmap = make(map[string][]*EtcPasswdEntry)
+	e.homedirmap = make(map[string]*EtcPasswdEntry)
+	e.uidindex = make([]*EtcPasswdEntry, 0)
+	e.lines = make([]passwdLine, 0)
+	e.duplicates = make([]Duplicate, 0)
+	e.compat = make([]*CompatEntry, 0)
+}
+
+// replaceContent swaps in the content of a freshly loaded cache under the write lock.
+func (e *EtcPasswdCache) replaceContent(next *EtcPasswdCache) {
+	next.sortUidIndex()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	previous, reloading := e.entries, !e.loadedAt.IsZero()
+	e.setContent(next)
+	e.path = next.path
+	e.loadedAt = time.Now()
+	e.loadedInfo = next.loadedInfo
+	// the diff copies every changed entry, so it is only worked out when someone wants it
+	keep := e.opts.reloadDiff && reloading
+	e.lastReloadDiff = PasswdDiff{}
+	if keep || e.hasSubscribers() {
+		d := diffEntries(previous, e.entries)
+		if keep {
+			e.lastReloadDiff = d
+		}
+		e.publishChanges(d)
+	}
+}
+
+// setContent takes the entries, lines, and indexes of the other cache. The caller must hold the
+// write lock.
+func (e *EtcPasswdCache) setContent(next *EtcPasswdCache) {
+	e.entries = next.entries
+	e.namemap = next.namemap
+	e.idmap = next.idmap
+	e.gidmap = next.gidmap
+	e.shellmap = next.shellmap
+	e.homedirmap = next.homedirmap
+	e.uidindex = next.uidindex
+	e.lines = next.lines
+	e.duplicates = next.duplicates
+	e.compat = next.compat
+}
+
+// workingCopy returns a copy of the content that can be changed without affecting the cache,
+// sharing the unchanged entries. The caller must hold the lock.
+func (e *EtcPasswdCache) workingCopy() *EtcPasswdCache {
+	work := e.newLoadTarget(e.path)
+	work.lines, work.duplicates, work.compat = e.lines, e.duplicates, e.compat
+	work.rebuildEntries(e.entries, func(*EtcPasswdEntry) bool { return false }, true)
+	return work
+}
+
+// replaceEntry swaps the current entry for the updated one in the same position and rebuilds the
+// indexes. The caller must hold the write lock.
+func (e *EtcPasswdCache) replaceEntry(current, updated *EtcPasswdEntry) {
+	// replace the entry in a copy so that iterators holding the old slice are not affected
+	entries := make([]*EtcPasswdEntry, len(e.entries))
+	copy(entries, e.entries)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i] == current {
+			entries[i] = updated
+			break
+		}
+	}
+	e.rebuildEntries(entries, func(*EtcPasswdEntry) bool { return false }, true)
+}
+
+// addEntryLine adds the entry to the cache along with the raw line it was parsed from and the
+// number of that line, which is 0 when it was not loaded.
+func (e *EtcPasswdCache) addEntryLine(entry *EtcPasswdEntry, raw string, number int) {
 	e.entries = append(e.entries, entry)
-	e.namemap[entry.username] = &entry
-	e.idmap[entry.uid] = &entry
+	e.lines = append(e.lines, passwdLine{raw: raw, entry: len(e.entries) - 1, number: number})
+	e.namemap[e.opts.nameKey(entry.username)] = entry
+	e.idmap[entry.uid] = entry
+	e.gidmap[entry.gid] = append(e.gidmap[entry.gid], entry)
+	e.uidindex = append(e.uidindex, entry)
+	e.shellmap[entry.shell] = append(e.shellmap[entry.shell], entry)
+	// home directories are often shared by system accounts so the first entry keeps the dir
+	if homedir := cleanHomedir(entry.homedir); len(homedir) > 0 {
+		if _, ok := e.homedirmap[homedir]; !ok {
+			e.homedirmap[homedir] = entry
+		}
+	}
 }
 
 // LoadFromPath loads the struct from a file on disk and replaces the cached content.
 func (e *EtcPasswdCache) LoadFromPath(path string) error {
-	content, err := ioutil.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
+		e.reportLoad(path, nil, time.Now(), err)
 		return err
 	}
-	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
-	e.entries = make([]EtcPasswdEntry, 0)
-	e.namemap = make(map[string]*EtcPasswdEntry)
-	e.idmap = make(map[int]*EtcPasswdEntry)
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		// skip commented or empty lines
-		if len(line) == 0 || strings.HasPrefix(line, "#") {
-			continue
+	defer f.Close()
+	return e.load(f, path)
+}
+
+// LoadFromReader loads the struct from passwd formatted content read from the given reader
+// and replaces the cached content. Useful for content that does not live in a file on disk.
+// The existing content is left untouched if loading fails.
+func (e *EtcPasswdCache) LoadFromReader(r io.Reader) error {
+	return e.load(r, "")
+}
+
+// load parses the content from the reader and replaces the cached content, remembering the
+// path that the content came from so that it can be reloaded later.
+func (e *EtcPasswdCache) load(r io.Reader, path string) error {
+	start := time.Now()
+	next, err := e.parse(r, path)
+	if next != nil {
+		e.replaceContent(next)
+	}
+	e.reportLoad(path, next, start, err)
+	return err
+}
+
+// reportLoad logs the result of a load that started at the given time and reports it to the
+// metrics sink.
+func (e *EtcPasswdCache) reportLoad(path string, next *EtcPasswdCache, start time.Time, err error) {
+	if err != nil && next == nil {
+		e.opts.warn("Failed to load passwd content", "path", path, "error", err)
+		if e.opts.metrics != nil {
+			e.opts.metrics.LoadFailed(err)
+		}
+		return
+	}
+	duration := time.Since(start)
+	e.opts.debug("Loaded passwd content", "path", path, "entries", len(next.entries), "duration", duration)
+	if e.opts.metrics != nil {
+		e.opts.metrics.Loaded(len(next.entries), next.badLines, duration)
+	}
+}
+
+// parse reads the content from the reader into a new cache with the same settings without
+// touching the current content. With WithCollectErrors the new content is returned along with
+// the joined errors of the bad lines, otherwise it is nil when there is an error.
+func (e *EtcPasswdCache) parse(r io.Reader, path string) (*EtcPasswdCache, error) {
+	// build the new content separately so that lookups are not blocked while parsing
+	next := e.newLoadTarget(path)
+	next.loadedInfo = statReader(r)
+	var in *interner
+	if e.opts.compact {
+		in = newInterner()
+	}
+	ignoreBadLines := e.ignoreBadLines && !e.opts.collectErrors
+	lineNumber := 0
+	parseRaw := func(raw string) error {
+		line := strings.TrimSpace(raw)
+		// keep commented or empty lines so that they can be written back
+		if isSkippedLine(line) {
+			next.lines = append(next.lines, passwdLine{raw: raw, entry: -1})
+			return nil
+		}
+		// NIS compat lines are kept separately and written back as they are
+		if IsCompatLine(line) {
+			compat, err := ParseCompatLine(line)
+			if err != nil {
+				if ignoreBadLines {
+					next.badLines++
+					e.opts.badLine(lineNumber, raw, err)
+					next.addRawLine(raw)
+					return nil
+				}
+				return err
+			}
+			next.compat = append(next.compat, &compat)
+			next.addRawLine(raw)
+			return nil
 		}
 		// parse the current line
-		entry, err := ParsePasswdLine(line)
+		if e.opts.verbatim {
+			line = raw
+		}
+		entry, err := e.parseLine(line)
+		if err == nil && e.opts.strictUsernames {
+			if uerr := ValidateUsername(entry.username); uerr != nil {
+				err = &ParseError{RawLine: line, Field: "username", Err: uerr}
+			}
+		}
 		if err != nil {
-			if e.ignoreBadLines {
-				continue
+			if ignoreBadLines {
+				next.badLines++
+				e.opts.badLine(lineNumber, raw, err)
+				next.lines = append(next.lines, passwdLine{raw: raw, entry: -1})
+				return nil
 			}
 			return err
 		}
-		e.AddEntry(entry)
+		if in != nil {
+			// duplicates keep their line since some policies write it back in place of the entry
+			_, dupName := next.namemap[next.opts.nameKey(entry.username)]
+			_, dupUid := next.idmap[entry.uid]
+			if !dupName && !dupUid {
+				return next.addWithPolicy(in.compactEntry(entry), "")
+			}
+		}
+		return next.addWithPolicy(entry, raw)
+	}
+	collected := make([]error, 0)
+	err := readRawLines(r, func(raw string) error {
+		lineNumber++
+		next.loadingLine = lineNumber
+		err := parseRaw(raw)
+		if _, ok := err.(*ParseError); ok && e.opts.collectErrors {
+			// the bad line is kept like an ignored one and reported once the load is done
+			next.badLines++
+			e.opts.badLine(lineNumber, raw, err)
+			collected = append(collected, withLineNumber(err, lineNumber, raw))
+			next.addRawLine(raw)
+			return nil
+		}
+		return err
+	})
+	next.loadingLine = 0
+	if err != nil {
+		return nil, err
+	}
+	if in != nil {
+		next.compactIndexes()
+	}
+	return next, errors.Join(collected...)
+}
+
+// MaxLineLength is the longest line accepted while reading a file. Longer lines stop the load
+// with a *ParseError rather than being read into memory.
+const MaxLineLength = 1 << 20
+
+// readRawLines reads the content from the reader one line at a time and calls fn with each line
+// exactly as it appears, minus the "\n" line ending, so memory use does not grow with the size
+// of the file. It stops at the first error returned by fn, attaching the line number and raw
+// line if it is a ParseError.
+func readRawLines(r io.Reader, fn func(raw string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), MaxLineLength)
+	scanner.Split(scanRawLines)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		raw := scanner.Text()
+		if err := fn(raw); err != nil {
+			return withLineNumber(err, lineNumber, raw)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return &ParseError{LineNumber: lineNumber + 1, Err: fmt.Errorf("Line is longer than %d bytes", MaxLineLength)}
+		}
+		return err
 	}
 	return nil
 }
 
-// NewEtcPasswdCache returns an empty passwd cache.
-func NewEtcPasswdCache(ignoreBadLines bool) *EtcPasswdCache {
+// scanRawLines is a bufio.SplitFunc like bufio.ScanLines except that a '\r' before the line
+// ending is kept so that the line can be written back exactly as it was read.
+func scanRawLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// isSkippedLine returns true if the trimmed line is empty or commented.
+func isSkippedLine(line string) bool {
+	return len(line) == 0 || strings.HasPrefix(line, "#")
+}
+
+// readLines reads all the content from the reader and calls fn with each trimmed line,
+// skipping commented and empty lines. It stops at the first error returned by fn.
+func readLines(r io.Reader, fn func(line string) error) error {
+	return readNumberedLines(r, func(lineNumber int, raw, line string) error {
+		return fn(line)
+	})
+}
+
+// readNumberedLines is like readLines but also passes the line number and raw line to fn.
+func readNumberedLines(r io.Reader, fn func(lineNumber int, raw, line string) error) error {
+	lineNumber := 0
+	return readRawLines(r, func(raw string) error {
+		lineNumber++
+		line := strings.TrimSpace(raw)
+		if isSkippedLine(line) {
+			return nil
+		}
+		return fn(lineNumber, raw, line)
+	})
+}
+
+// formatPasswdLine formats the entry as a 7 part /etc/passwd line without a line ending.
+func formatPasswdLine(entry *EtcPasswdEntry) string {
+	return strings.Join([]string{
+		entry.username,
+		entry.password,
+		strconv.Itoa(entry.uid),
+		strconv.Itoa(entry.gid),
+		entry.info,
+		entry.homedir,
+		entry.shell,
+	}, ":")
+}
+
+// WriteTo serializes all the entries in the cache to the writer in /etc/passwd format.
+// Comments, blank lines, and the original order of a loaded file are preserved unless another
+// order is given with WithWriteOrder, and entries that have not changed since loading are
+// written exactly as they were read.
+func (e *EtcPasswdCache) WriteTo(w io.Writer) (int64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var total int64
+	for _, l := range e.orderedLines() {
+		line := l.raw
+		if l.entry >= 0 {
+			entry := e.entries[l.entry]
+			if original, err := e.parseLine(l.raw); l.raw == "" || err != nil || original != *entry {
+				line = e.formatLine(entry)
+			}
+		}
+		n, err := io.WriteString(w, line+"\n")
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// WriteToPath serializes all the entries in the cache to a file on disk. The content is written
+// to a temporary file next to the target which is then atomically renamed into place, so readers
+// never see a partially written file. The permissions of an existing file are preserved. The
+// file is locked with LockFile while it is replaced unless WithoutLocking was given.
+func (e *EtcPasswdCache) WriteToPath(path string) error {
+	buf := new(bytes.Buffer)
+	if _, err := e.WriteTo(buf); err != nil {
+		return err
+	}
+	if e.opts.dryRun {
+		return nil
+	}
+	if !e.opts.noLocking {
+		lock, err := LockFile(path, e.opts.lockTimeout)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+	}
+	return writeFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// Save writes the cache back to the file it was loaded from using WriteToPath.
+func (e *EtcPasswdCache) Save() error {
+	e.mu.RLock()
+	path := e.path
+	e.mu.RUnlock()
+	if path == "" {
+		return fmt.Errorf("Cache was not loaded from a path and cannot be saved")
+	}
+	return e.WriteToPath(path)
+}
+
+// NewEtcPasswdCache returns an empty passwd cache configured with the given options.
+func NewEtcPasswdCache(ignoreBadLines bool, opts ...Option) *EtcPasswdCache {
 	return &EtcPasswdCache{
 		ignoreBadLines: ignoreBadLines,
+		opts:           applyOptions(opts),
 	}
 }
 
 // NewLoadedEtcPasswdCache returns a loaded passwd cache in a single call.
-func NewLoadedEtcPasswdCache() (*EtcPasswdCache, error) {
-	result := NewEtcPasswdCache(false)
+func NewLoadedEtcPasswdCache(opts ...Option) (*EtcPasswdCache, error) {
+	result := NewEtcPasswdCache(false, opts...)
 	if err := result.LoadDefault(); err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-// LoadDefault loads the struct from the /etc/passwd file
+// LoadDefault loads the struct from the /etc/passwd file, relative to the root given by WithRoot.
+// The ETCPWDPARSE_PASSWD environment variable overrides the location.
 func (e *EtcPasswdCache) LoadDefault() error {
-	return e.LoadFromPath("/etc/passwd")
+	return e.LoadFromPath(e.opts.defaultPath("/etc/passwd"))
 }
 
 // LookupUserByName returns the entry for the given username
 func (e *EtcPasswdCache) LookupUserByName(name string) (*EtcPasswdEntry, bool) {
-	entry, ok := e.namemap[name]
+	entry, _, ok := e.LookupUserByNameWithSource(name)
 	return entry, ok
 }
 
 // LookupUserByUid returns the entry for the given userid
 func (e *EtcPasswdCache) LookupUserByUid(id int) (*EtcPasswdEntry, bool) {
-	entry, ok := e.idmap[id]
+	entry, _, ok := e.LookupUserByUidWithSource(id)
 	return entry, ok
 }
 
+// LookupUsersByGid returns the entries that have the given group id as their primary group,
+// in file order.
+func (e *EtcPasswdCache) LookupUsersByGid(gid int) []*EtcPasswdEntry {
+	e.refresh()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	results := make([]*EtcPasswdEntry, len(e.gidmap[gid]))
+	copy(results, e.gidmap[gid])
+	return results
+}
+
+// LookupUsersByShell returns the entries that have the given login shell, in file order.
+func (e *EtcPasswdCache) LookupUsersByShell(shell string) []*EtcPasswdEntry {
+	e.refresh()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	results := make([]*EtcPasswdEntry, len(e.shellmap[shell]))
+	copy(results, e.shellmap[shell])
+	return results
+}
+
+// cleanHomedir normalises a home directory for use as a lookup key.
+func cleanHomedir(homedir string) string {
+	if len(homedir) == 0 {
+		return ""
+	}
+	return filepath.Clean(homedir)
+}
+
+// LookupUserByHomedir returns the first entry in file order with the given home directory.
+// The path is cleaned before the lookup so trailing slashes are ignored.
+func (e *EtcPasswdCache) LookupUserByHomedir(path string) (*EtcPasswdEntry, bool) {
+	e.refresh()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	entry, ok := e.homedirmap[cleanHomedir(path)]
+	return entry, ok
+}
+
+// LookupUserByHomedirPrefix returns the entry whose home directory contains the given path,
+// preferring the deepest matching home directory. The root directory is never matched since
+// it is the home directory of many system accounts. Useful for finding which user should own
+// a file somewhere under /home.
+func (e *EtcPasswdCache) LookupUserByHomedirPrefix(path string) (*EtcPasswdEntry, bool) {
+	e.refresh()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for p := cleanHomedir(path); len(p) > 0 && p != "/" && p != "."; p = filepath.Dir(p) {
+		if entry, ok := e.homedirmap[p]; ok {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
 // UidForUsername is a shortcut function to get the user id for the given username.
 // Useful when needing to chown a file.
 func (e *EtcPasswdCache) UidForUsername(name string) (int, error) {
 	entry, ok := e.LookupUserByName(name)
 	if !ok {
-		return 0, fmt.Errorf("No such user with username '%s'", name)
+		return 0, sentinelf(ErrUserNotFound, "No such user with username '%s'", name)
 	}
 	return entry.Uid(), nil
 }
@@ -182,16 +711,18 @@ func (e *EtcPasswdCache) UidForUsername(name string) (int, error) {
 func (e *EtcPasswdCache) HomeDirForUsername(name string) (string, error) {
 	entry, ok := e.LookupUserByName(name)
 	if !ok {
-		return "", fmt.Errorf("No such user with username '%s'", name)
+		return "", sentinelf(ErrUserNotFound, "No such user with username '%s'", name)
 	}
 	return entry.Homedir(), nil
 }
 
-// ListEntries returns a slice containing references to all the entry objects
+// ListEntries returns a slice containing references to all the entry objects. The entries are
+// the same objects returned by the lookups, the slice itself is a copy.
 func (e *EtcPasswdCache) ListEntries() []*EtcPasswdEntry {
+	e.refresh()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 	results := make([]*EtcPasswdEntry, len(e.entries))
-	for i, e := range e.entries {
-		results[i] = &e
-	}
+	copy(results, e.entries)
 	return results
 }
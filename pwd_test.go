@@ -1,10 +1,15 @@
 package etcpwdparse
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -81,6 +86,315 @@ func TestFull(t *testing.T) {
 	}
 }
 
+func TestLookupUsersByGid(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	rootGroup := cache.LookupUsersByGid(0)
+	names := make([]string, len(rootGroup))
+	for i, entry := range rootGroup {
+		names[i] = entry.Username()
+	}
+	if strings.Join(names, ",") != "root,sync,shutdown,halt,operator" {
+		t.Fatalf("%v != [root sync shutdown halt operator]", names)
+	}
+	if len(cache.LookupUsersByGid(12345)) != 0 {
+		t.Fatalf("no users should have gid 12345")
+	}
+}
+
+func TestLookupUsersByShell(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	if len(cache.LookupUsersByShell("/sbin/nologin")) != 9 {
+		t.Fatalf("%d != 9", len(cache.LookupUsersByShell("/sbin/nologin")))
+	}
+	bash := cache.LookupUsersByShell("/bin/bash")
+	if len(bash) != 1 || bash[0].Username() != "root" {
+		t.Fatalf("only root should have /bin/bash")
+	}
+	if len(cache.LookupUsersByShell("/bin/csh")) != 0 {
+		t.Fatalf("no users should have /bin/csh")
+	}
+}
+
+func TestLookupUserByHomedir(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	content := fakePwdContent + "alice:x:1000:1000::/home/alice:/bin/bash\nbuild:x:1001:1000::/home/alice/build:/bin/bash\n"
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	if entry, ok := cache.LookupUserByHomedir("/root/"); !ok || entry.Username() != "root" {
+		t.Fatalf("root should own /root")
+	}
+	if _, ok := cache.LookupUserByHomedir("/home"); ok {
+		t.Fatalf("nobody should have /home")
+	}
+
+	if entry, ok := cache.LookupUserByHomedirPrefix("/home/alice/docs/notes.txt"); !ok || entry.Username() != "alice" {
+		t.Fatalf("alice should own her docs")
+	}
+	if entry, ok := cache.LookupUserByHomedirPrefix("/home/alice/build/out"); !ok || entry.Username() != "build" {
+		t.Fatalf("build should own its build output")
+	}
+	if _, ok := cache.LookupUserByHomedirPrefix("/etc/passwd"); ok {
+		t.Fatalf("nobody should own /etc/passwd through the root directory")
+	}
+}
+
+func TestLoadFromReader(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	err := cache.LoadFromReader(strings.NewReader(fakePwdContent))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	ftpEntry, ok := cache.LookupUserByName("ftp")
+	if !ok {
+		t.Fatalf("ftp user should have been found")
+	}
+	if ftpEntry.Info() != "FTP User" {
+		t.Fatalf("%s != FTP User", ftpEntry.Info())
+	}
+	if len(cache.ListEntries()) != 13 {
+		t.Fatalf("%d != 13", len(cache.ListEntries()))
+	}
+
+	err = cache.LoadFromReader(strings.NewReader("bad:line"))
+	if err == nil {
+		t.Fatalf("Should have failed on a bad line")
+	}
+}
+
+func TestWriteToPath(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	err := ioutil.WriteFile(pwFile, []byte("old:x:1:1::/:/bin/sh\n"), 0600)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := cache.WriteToPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	info, err := os.Stat(pwFile)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("%o != 600", info.Mode().Perm())
+	}
+	// the lckpwdf lock file is expected to stay behind, like it does with shadow-utils
+	files, _ := ioutil.ReadDir(tempDir)
+	if len(files) != 2 || files[0].Name() != ".pwd.lock" {
+		t.Fatalf("temp file was left behind: %d files", len(files))
+	}
+
+	content, _ := ioutil.ReadFile(pwFile)
+	if string(content) != fakePwdContent {
+		t.Fatalf("unexpected content %q", string(content))
+	}
+
+	reloaded := NewEtcPasswdCache(false)
+	if err := reloaded.LoadFromPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(reloaded.ListEntries()) != 13 {
+		t.Fatalf("%d != 13", len(reloaded.ListEntries()))
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	content := "# header\nroot:x:0:0:root:/root:/bin/bash\n\n  bin : x:1:1:bin:/bin:/sbin/nologin\nbroken\n# footer\n"
+
+	cache := NewEtcPasswdCache(true)
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	if _, err := cache.WriteTo(buf); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if buf.String() != content {
+		t.Fatalf("%q != %q", buf.String(), content)
+	}
+
+	cache.AddEntry(EtcPasswdEntry{username: "alice", password: "x", uid: 1000, gid: 1000, homedir: "/home/alice", shell: "/bin/bash"})
+	buf.Reset()
+	if _, err := cache.WriteTo(buf); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	expected := content + "alice:x:1000:1000::/home/alice:/bin/bash\n"
+	if buf.String() != expected {
+		t.Fatalf("%q != %q", buf.String(), expected)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+					t.Errorf("Should not have failed: %s", err)
+				}
+				cache.AddEntry(EtcPasswdEntry{username: "extra", uid: 5000 + j})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, ok := cache.LookupUserByName("root"); !ok {
+					t.Errorf("root should always be found")
+				}
+				cache.ListEntries()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestParseError(t *testing.T) {
+	content := "root:x:0:0:root:/root:/bin/bash\n# comment\nbin:x:one:1:bin:/bin:/sbin/nologin\n"
+
+	cache := NewEtcPasswdCache(false)
+	err := cache.LoadFromReader(strings.NewReader(content))
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("%v should have been a ParseError", err)
+	}
+	if pe.LineNumber != 3 {
+		t.Fatalf("%d != 3", pe.LineNumber)
+	}
+	if pe.RawLine != "bin:x:one:1:bin:/bin:/sbin/nologin" {
+		t.Fatalf("%s != bin:x:one:1:bin:/bin:/sbin/nologin", pe.RawLine)
+	}
+	if pe.Field != "uid" {
+		t.Fatalf("%s != uid", pe.Field)
+	}
+	var ne *strconv.NumError
+	if !errors.As(err, &ne) {
+		t.Fatalf("cause should have been a NumError")
+	}
+	if !strings.HasPrefix(err.Error(), "line 3: Passwd line had badly formatted uid one") {
+		t.Fatalf("unexpected message %s", err.Error())
+	}
+
+	_, err = ParsePasswdLine("root:x:0:0")
+	if !errors.As(err, &pe) || pe.LineNumber != 0 || pe.Field != "" {
+		t.Fatalf("%v should have been a ParseError without a line number", err)
+	}
+}
+
+func TestLoadLongAndCRLFLines(t *testing.T) {
+	content := "root:x:0:0:root:/root:/bin/bash\r\nbin:x:1:1:bin:/bin:/sbin/nologin\r\n"
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	if buf.String() != content {
+		t.Fatalf("line endings should have been preserved: %q", buf.String())
+	}
+
+	long := "root:x:0:0:root:/root:/bin/bash\nbig:x:1:1:" + strings.Repeat("a", MaxLineLength) + ":/:/bin/sh\n"
+	err := NewEtcPasswdCache(true).LoadFromReader(strings.NewReader(long))
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.LineNumber != 2 {
+		t.Fatalf("expected an error on line 2, got %v", err)
+	}
+}
+
+func TestSharedEntryPointers(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	cache.AddEntry(EtcPasswdEntry{username: "alice", uid: 1000, gid: 1000, homedir: "/home/alice", shell: "/bin/bash"})
+
+	byName, _ := cache.LookupUserByName("alice")
+	byUid, _ := cache.LookupUserByUid(1000)
+	byHome, _ := cache.LookupUserByHomedir("/home/alice")
+	if byName != byUid || byName != byHome || byName != cache.LookupUsersByGid(1000)[0] {
+		t.Fatalf("lookups should return the same entry object")
+	}
+	entries := cache.ListEntries()
+	if entries[len(entries)-1] != byName {
+		t.Fatalf("ListEntries should return the same entry objects as the lookups")
+	}
+	if entries[0] != cache.ListEntries()[0] {
+		t.Fatalf("ListEntries should return the same entry objects each time")
+	}
+	for entry := range cache.ByUid() {
+		if entry.Username() == "alice" && entry != byName {
+			t.Fatalf("iterators should return the same entry objects as the lookups")
+		}
+	}
+
+	// modifications store a new object and leave the one held by the caller alone
+	cache.ModifyUser("alice", func(b *EtcPasswdEntryBuilder) { b.Shell("/bin/zsh") })
+	if byName.Shell() != "/bin/bash" {
+		t.Fatalf("%s != /bin/bash", byName.Shell())
+	}
+	if current, _ := cache.LookupUserByName("alice"); current.Shell() != "/bin/zsh" {
+		t.Fatalf("%s != /bin/zsh", current.Shell())
+	}
+}
+
+func TestUpsertEntry(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\nbob:x:1001:1001::/home/bob:/bin/sh\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	replaced, err := cache.UpsertEntry(EtcPasswdEntry{username: "alice", password: "x", uid: 1005, gid: 1000, homedir: "/home/alice", shell: "/bin/zsh"})
+	if err != nil || !replaced {
+		t.Fatalf("Should have replaced alice: %v", err)
+	}
+	if len(cache.ListEntries()) != 3 {
+		t.Fatalf("upsert should not add a duplicate entry")
+	}
+	if entry, ok := cache.LookupUserByUid(1005); !ok || entry.Shell() != "/bin/zsh" {
+		t.Fatalf("the uid index should follow the new uid")
+	}
+	if _, ok := cache.LookupUserByUid(1000); ok {
+		t.Fatalf("the old uid should no longer be indexed")
+	}
+	if _, err := cache.UpsertEntry(EtcPasswdEntry{username: "alice", uid: 1001}); err == nil {
+		t.Fatalf("Should have failed to take the uid of bob")
+	}
+
+	replaced, err = cache.UpsertEntry(EtcPasswdEntry{username: "carol", password: "x", uid: 1002, gid: 1002, homedir: "/home/carol", shell: "/bin/sh"})
+	if err != nil || replaced {
+		t.Fatalf("Should have added carol: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	expected := "root:x:0:0:root:/root:/bin/bash\nalice:x:1005:1000::/home/alice:/bin/zsh\nbob:x:1001:1001::/home/bob:/bin/sh\ncarol:x:1002:1002::/home/carol:/bin/sh\n"
+	if buf.String() != expected {
+		t.Fatalf("%q != %q", buf.String(), expected)
+	}
+}
+
 func Example() {
 	// load the cache from the /etc/passwd file
 	cache, err := NewLoadedEtcPasswdCache()
@@ -94,3 +408,40 @@ func Example() {
 	// print some result
 	fmt.Printf("Your current user is %s and your homedir is %s\n", entry.Username(), entry.Homedir())
 }
+
+func TestParsePasswdLineBytes(t *testing.T) {
+	line := "operator:x:11:0:operator:/root:/sbin/nologin"
+	entry, err := ParsePasswdLineBytes([]byte(line))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if expected, _ := ParsePasswdLine(line); entry != expected {
+		t.Fatalf("%v != %v", entry, expected)
+	}
+	if _, err := ParsePasswdLineBytes([]byte("root:x:0:0")); err == nil {
+		t.Fatalf("Should have failed on a short line")
+	}
+}
+
+func BenchmarkParsePasswdLine(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParsePasswdLine("operator:x:11:0:operator:/root:/sbin/nologin")
+	}
+}
+
+func BenchmarkParsePasswdLineBytes(b *testing.B) {
+	line := []byte("operator:x:11:0:operator:/root:/sbin/nologin")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParsePasswdLineBytes(line)
+	}
+}
+
+func BenchmarkLoadFromReader(b *testing.B) {
+	content := strings.Repeat("operator:x:11:0:operator:/root:/sbin/nologin\n", 1000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewEtcPasswdCache(false).LoadFromReader(strings.NewReader(content))
+	}
+}
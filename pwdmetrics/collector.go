@@ -0,0 +1,180 @@
+// Package pwdmetrics collects the statistics of an etcpwdparse cache and exposes them as an
+// expvar map and in the Prometheus text exposition format, for services that embed the cache.
+//
+// The package only uses the standard library, so it does not implement prometheus.Collector
+// from the Prometheus client library itself. Serve a Collector as its own scrape target with
+// ServeHTTP, or wrap the values returned by Stats in client library metrics.
+package pwdmetrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AstromechZA/etcpwdparse"
+)
+
+// Stats is a snapshot of the statistics gathered by a Collector.
+type Stats struct {
+	// Entries is the number of entries in the most recently loaded content
+	Entries int
+	// ParseErrors is the number of bad lines skipped by the most recent load
+	ParseErrors int
+	// Loads is the number of successful loads, including reloads
+	Loads uint64
+	// LoadFailures is the number of loads that failed
+	LoadFailures uint64
+	// Reloads is the number of reloads of stale content triggered by WithTTL or WithStatCheck
+	Reloads uint64
+	// LastLoad is the time of the most recent successful load, zero if there has been none
+	LastLoad time.Time
+	// LastLoadDuration is how long the most recent successful load took
+	LastLoadDuration time.Duration
+	// Hits is the number of lookups that found an entry
+	Hits uint64
+	// Misses is the number of lookups that did not find an entry
+	Misses uint64
+}
+
+// MissRate returns the fraction of lookups that did not find an entry, 0 if there were none.
+func (s Stats) MissRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Misses) / float64(s.Hits+s.Misses)
+}
+
+// Collector is an etcpwdparse.MetricsSink that keeps the statistics of a cache. Pass it to the
+// cache with etcpwdparse.WithMetrics. It is safe to use from several goroutines at once.
+type Collector struct {
+	mu    sync.Mutex
+	stats Stats
+	now   func() time.Time
+}
+
+// New returns an empty collector.
+func New() *Collector {
+	return &Collector{now: time.Now}
+}
+
+var _ etcpwdparse.MetricsSink = (*Collector)(nil)
+
+// Loaded records a successful load.
+func (c *Collector) Loaded(entries, badLines int, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Entries = entries
+	c.stats.ParseErrors = badLines
+	c.stats.Loads++
+	c.stats.LastLoad = c.now()
+	c.stats.LastLoadDuration = duration
+}
+
+// LoadFailed records a failed load.
+func (c *Collector) LoadFailed(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.LoadFailures++
+}
+
+// Reloaded records a reload of stale content.
+func (c *Collector) Reloaded() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Reloads++
+}
+
+// Lookup records a lookup by username or uid.
+func (c *Collector) Lookup(hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hit {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+}
+
+// Stats returns a snapshot of the statistics.
+func (c *Collector) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Var returns an expvar.Var that reports the statistics as a JSON object each time it is read.
+func (c *Collector) Var() expvar.Var {
+	return expvar.Func(func() interface{} {
+		s := c.Stats()
+		values := map[string]interface{}{
+			"entries":                    s.Entries,
+			"parse_errors":               s.ParseErrors,
+			"loads":                      s.Loads,
+			"load_failures":              s.LoadFailures,
+			"reloads":                    s.Reloads,
+			"last_load_duration_seconds": s.LastLoadDuration.Seconds(),
+			"lookup_hits":                s.Hits,
+			"lookup_misses":              s.Misses,
+			"lookup_miss_rate":           s.MissRate(),
+		}
+		if !s.LastLoad.IsZero() {
+			values["last_load"] = s.LastLoad.UTC().Format(time.RFC3339)
+		}
+		return values
+	})
+}
+
+// Publish publishes the statistics as an expvar variable with the given name, such as
+// "etcpwdparse". Like expvar.Publish it panics if the name is already in use.
+func (c *Collector) Publish(name string) {
+	expvar.Publish(name, c.Var())
+}
+
+// prometheusMetric is a single metric in the text exposition format
+type prometheusMetric struct {
+	name  string
+	kind  string
+	help  string
+	value float64
+}
+
+// WritePrometheus writes the statistics in the Prometheus text exposition format with every
+// metric name starting with the given namespace, such as "etcpwdparse".
+func (c *Collector) WritePrometheus(w io.Writer, namespace string) error {
+	s := c.Stats()
+	lastLoad := 0.0
+	if !s.LastLoad.IsZero() {
+		lastLoad = float64(s.LastLoad.UnixNano()) / 1e9
+	}
+	metrics := []prometheusMetric{
+		{"entries", "gauge", "Number of entries in the most recently loaded content.", float64(s.Entries)},
+		{"parse_errors", "gauge", "Number of bad lines skipped by the most recent load.", float64(s.ParseErrors)},
+		{"loads_total", "counter", "Number of successful loads.", float64(s.Loads)},
+		{"load_failures_total", "counter", "Number of failed loads.", float64(s.LoadFailures)},
+		{"reloads_total", "counter", "Number of reloads of stale content.", float64(s.Reloads)},
+		{"last_load_timestamp_seconds", "gauge", "Time of the most recent successful load.", lastLoad},
+		{"last_load_duration_seconds", "gauge", "Duration of the most recent successful load.", s.LastLoadDuration.Seconds()},
+		{"lookup_hits_total", "counter", "Number of lookups that found an entry.", float64(s.Hits)},
+		{"lookup_misses_total", "counter", "Number of lookups that did not find an entry.", float64(s.Misses)},
+	}
+	for _, m := range metrics {
+		name := m.name
+		if len(namespace) > 0 {
+			name = namespace + "_" + name
+		}
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, m.help, name, m.kind, name, m.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the statistics in the Prometheus text exposition format under the
+// "etcpwdparse" namespace, so that a Collector can be mounted as a scrape target.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WritePrometheus(w, "etcpwdparse")
+}
@@ -0,0 +1,72 @@
+package pwdmetrics
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AstromechZA/etcpwdparse"
+)
+
+const passwdContent = "root:x:0:0:root:/root:/bin/bash\nbroken\nbob:x:1000:1000::/home/bob:/bin/sh\n"
+
+func loadedCollector(t *testing.T) *Collector {
+	collector := New()
+	collector.now = func() time.Time { return time.Unix(1700000000, 0) }
+	cache := etcpwdparse.NewEtcPasswdCache(true, etcpwdparse.WithMetrics(collector))
+	if err := cache.LoadFromReader(strings.NewReader(passwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	cache.LookupUserByName("root")
+	cache.LookupUserByName("nobody")
+	cache.LookupUserByUid(1000)
+	cache.LookupUserByUid(5000)
+	return collector
+}
+
+func TestCollectorStats(t *testing.T) {
+	stats := loadedCollector(t).Stats()
+	if stats.Entries != 2 || stats.ParseErrors != 1 || stats.Loads != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats.Hits != 2 || stats.Misses != 2 || stats.MissRate() != 0.5 {
+		t.Fatalf("unexpected lookup stats %+v", stats)
+	}
+	if (Stats{}).MissRate() != 0 {
+		t.Fatalf("expected no miss rate without lookups")
+	}
+}
+
+func TestCollectorVar(t *testing.T) {
+	values := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(loadedCollector(t).Var().String()), &values); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if values["entries"] != 2.0 || values["parse_errors"] != 1.0 || values["lookup_miss_rate"] != 0.5 {
+		t.Fatalf("unexpected values %v", values)
+	}
+	if values["last_load"] != "2023-11-14T22:13:20Z" {
+		t.Fatalf("unexpected last load %v", values["last_load"])
+	}
+}
+
+func TestCollectorPrometheus(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	loadedCollector(t).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE etcpwdparse_entries gauge\netcpwdparse_entries 2\n",
+		"etcpwdparse_parse_errors 1\n",
+		"# TYPE etcpwdparse_lookup_misses_total counter\netcpwdparse_lookup_misses_total 2\n",
+		"etcpwdparse_last_load_timestamp_seconds 1.7e+09\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("expected %q in %q", line, body)
+		}
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("unexpected content type %q", recorder.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,22 @@
+package etcpwdparse
+
+// Find returns the first entry in file order for which the predicate returns true.
+func (e *EtcPasswdCache) Find(predicate func(*EtcPasswdEntry) bool) (*EtcPasswdEntry, bool) {
+	for entry := range e.All() {
+		if predicate(entry) {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// Filter returns all the entries in file order for which the predicate returns true.
+func (e *EtcPasswdCache) Filter(predicate func(*EtcPasswdEntry) bool) []*EtcPasswdEntry {
+	results := make([]*EtcPasswdEntry, 0)
+	for entry := range e.All() {
+		if predicate(entry) {
+			results = append(results, entry)
+		}
+	}
+	return results
+}
@@ -0,0 +1,33 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindAndFilter(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	entry, ok := cache.Find(func(e *EtcPasswdEntry) bool {
+		return e.Homedir() == "/root" && e.Uid() > 0
+	})
+	if !ok || entry.Username() != "operator" {
+		t.Fatalf("operator should have been found")
+	}
+	if _, ok := cache.Find(func(e *EtcPasswdEntry) bool { return e.Uid() > 1000 }); ok {
+		t.Fatalf("nothing should have been found")
+	}
+
+	results := cache.Filter(func(e *EtcPasswdEntry) bool {
+		return e.Shell() == "/sbin/nologin" && e.Uid() >= 10
+	})
+	if len(results) != 4 {
+		t.Fatalf("%d != 4", len(results))
+	}
+	if results[0].Username() != "operator" || results[3].Username() != "nobody" {
+		t.Fatalf("unexpected results %v", results)
+	}
+}
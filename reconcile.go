@@ -0,0 +1,144 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// ReconcileOptions controls how Reconcile treats the entries that are not in the desired set.
+type ReconcileOptions struct {
+	// Prune selects the entries that are deleted when they are not in the desired set, such as
+	// the regular accounts managed by the caller. When nil nothing is deleted.
+	Prune func(entry *EtcPasswdEntry) bool
+	// WriteBack saves the cache to the file it was loaded from after the changes are applied
+	WriteBack bool
+}
+
+// Reconcile makes the cache match the desired users and reports the changes made. Users that do
+// not exist are created as AddUser would, including its defaults. For existing users only the
+// fields set in the spec are enforced, so a zero Uid or an empty Shell leaves the current value
+// alone and HomeBase, System, and Ranges only matter when a user is created. Users missing from
+// the desired set are deleted when Prune selects them.
+//
+// The changes are worked out on a copy and applied together under the write lock, so either
+// all of them are made or, on error, none. The WriteBack field of the specs is ignored in favour
+// of the one in opts. With WriteBack the file is locked, reloaded if it changed since it was
+// loaded, and written before the lock is released, so changes made by other processes are not
+// overwritten. The cache is only changed once the file has been written.
+func (e *EtcPasswdCache) Reconcile(desired []UserSpec, opts ReconcileOptions) (PasswdDiff, error) {
+	if !opts.WriteBack {
+		return e.reconcile(desired, opts, nil)
+	}
+	return e.reconcileWriteBack(desired, opts)
+}
+
+// reconcileWriteBack implements Reconcile with WriteBack, holding the file lock from the reload
+// until the file is written like addUserWriteBack.
+func (e *EtcPasswdCache) reconcileWriteBack(desired []UserSpec, opts ReconcileOptions) (PasswdDiff, error) {
+	e.mu.RLock()
+	path, loadedInfo := e.path, e.loadedInfo
+	e.mu.RUnlock()
+	if path == "" {
+		return PasswdDiff{}, fmt.Errorf("Cache was not loaded from a path and cannot be saved")
+	}
+	if e.opts.dryRun {
+		return e.reconcile(desired, opts, nil)
+	}
+	if !e.opts.noLocking {
+		lock, err := LockFile(path, e.opts.lockTimeout)
+		if err != nil {
+			return PasswdDiff{}, err
+		}
+		defer lock.Unlock()
+	}
+	if info, err := os.Stat(path); err == nil && (loadedInfo == nil || fileChanged(loadedInfo, info)) {
+		if err := e.LoadFromPath(path); err != nil {
+			return PasswdDiff{}, err
+		}
+	}
+
+	diff, err := e.reconcile(desired, opts, func(work *EtcPasswdCache) error {
+		buf := new(bytes.Buffer)
+		if _, err := work.WriteTo(buf); err != nil {
+			return err
+		}
+		return writeFileAtomic(path, buf.Bytes(), 0644)
+	})
+	if err != nil {
+		return PasswdDiff{}, err
+	}
+	// the file now holds our content, so the next write back does not need to reload it
+	if info, err := os.Stat(path); err == nil {
+		e.mu.Lock()
+		e.loadedInfo = info
+		e.mu.Unlock()
+	}
+	return diff, nil
+}
+
+// reconcile applies the desired state while holding the write lock. When commit is not nil it is
+// called with the new content before it replaces the current content, and an error from it
+// leaves the cache unchanged.
+func (e *EtcPasswdCache) reconcile(desired []UserSpec, opts ReconcileOptions, commit func(work *EtcPasswdCache) error) (PasswdDiff, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.namemap == nil {
+		e.reset()
+	}
+	work := e.workingCopy()
+
+	wanted := make(map[string]bool)
+	for _, spec := range desired {
+		key := e.opts.nameKey(spec.Username)
+		if wanted[key] {
+			return PasswdDiff{}, fmt.Errorf("User with username '%s' is desired more than once", spec.Username)
+		}
+		wanted[key] = true
+		spec.WriteBack = false
+
+		if _, ok := work.namemap[key]; !ok {
+			if _, err := work.AddUser(spec); err != nil {
+				return PasswdDiff{}, err
+			}
+			continue
+		}
+		_, err := work.ModifyUser(spec.Username, func(b *EtcPasswdEntryBuilder) {
+			if len(spec.Password) > 0 {
+				b.Password(spec.Password)
+			}
+			if spec.Uid != 0 {
+				b.Uid(spec.Uid)
+			}
+			if spec.Gid != nil {
+				b.Gid(*spec.Gid)
+			}
+			if len(spec.Info) > 0 {
+				b.Info(spec.Info)
+			}
+			if len(spec.Homedir) > 0 {
+				b.Homedir(spec.Homedir)
+			}
+			if len(spec.Shell) > 0 {
+				b.Shell(spec.Shell)
+			}
+		})
+		if err != nil {
+			return PasswdDiff{}, err
+		}
+	}
+	if opts.Prune != nil {
+		work.rebuildEntries(work.entries, func(entry *EtcPasswdEntry) bool {
+			return !wanted[e.opts.nameKey(entry.username)] && opts.Prune(entry)
+		}, false)
+	}
+
+	diff := diffEntries(e.entries, work.entries)
+	if commit != nil {
+		if err := commit(work); err != nil {
+			return PasswdDiff{}, err
+		}
+	}
+	e.setContent(work)
+	return diff, nil
+}
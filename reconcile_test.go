@@ -0,0 +1,130 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const fakeReconcileContent = `# managed accounts
+root:x:0:0:root:/root:/bin/bash
+daemon:x:2:2:daemon:/sbin:/sbin/nologin
+alice:x:1000:1000:Alice:/home/alice:/bin/bash
+bob:x:1001:1001:Bob:/home/bob:/bin/bash
+`
+
+func TestReconcile(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakeReconcileContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	desired := []UserSpec{
+		{Username: "alice", Shell: "/bin/zsh"},
+		{Username: "carol", Info: "Carol"},
+	}
+	regular := func(entry *EtcPasswdEntry) bool { return entry.IsRegularAccount() }
+	diff, err := cache.Reconcile(desired, ReconcileOptions{Prune: regular})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Username() != "carol" || diff.Added[0].Uid() != 1002 {
+		t.Fatalf("unexpected additions %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Username() != "bob" {
+		t.Fatalf("unexpected removals %+v", diff.Removed)
+	}
+	if len(diff.Modified) != 1 || diff.Modified[0].New.Shell() != "/bin/zsh" || len(diff.Modified[0].Changes) != 1 {
+		t.Fatalf("unexpected modifications %+v", diff.Modified)
+	}
+
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	expected := `# managed accounts
+root:x:0:0:root:/root:/bin/bash
+daemon:x:2:2:daemon:/sbin:/sbin/nologin
+alice:x:1000:1000:Alice:/home/alice:/bin/zsh
+carol:x:1002:1002:Carol:/home/carol:/bin/sh
+`
+	if buf.String() != expected {
+		t.Fatalf("%q != %q", buf.String(), expected)
+	}
+
+	// a second run has nothing left to do
+	diff, err = cache.Reconcile(desired, ReconcileOptions{Prune: regular})
+	if err != nil || !diff.Empty() {
+		t.Fatalf("a second reconcile should do nothing: %+v %v", diff, err)
+	}
+
+	// without Prune nothing is deleted
+	diff, _ = cache.Reconcile(nil, ReconcileOptions{})
+	if !diff.Empty() {
+		t.Fatalf("nothing should have been deleted: %+v", diff)
+	}
+}
+
+func TestReconcileIsAtomic(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakeReconcileContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	failures := [][]UserSpec{
+		{{Username: "alice", Shell: "/bin/zsh"}, {Username: "bob", Uid: 1000}},
+		{{Username: "alice", Shell: "/bin/zsh"}, {Username: "bad:name"}},
+		{{Username: "alice"}, {Username: "alice"}},
+	}
+	for _, desired := range failures {
+		if _, err := cache.Reconcile(desired, ReconcileOptions{}); err == nil {
+			t.Fatalf("Should have failed for %+v", desired)
+		}
+		alice, _ := cache.LookupUserByName("alice")
+		if alice.Shell() != "/bin/bash" {
+			t.Fatalf("a failed reconcile should not change anything")
+		}
+	}
+}
+
+func TestReconcileWriteBackReloadsChangedFile(t *testing.T) {
+	pwFile := filepath.Join(t.TempDir(), "passwd")
+	os.WriteFile(pwFile, []byte(fakeReconcileContent), 0644)
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	// another writer adds a user after the cache was loaded
+	other := NewEtcPasswdCache(false)
+	other.LoadFromPath(pwFile)
+	if _, err := other.AddUser(UserSpec{Username: "dave", WriteBack: true}); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	diff, err := cache.Reconcile([]UserSpec{{Username: "carol"}}, ReconcileOptions{WriteBack: true})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Uid() != 1003 {
+		t.Fatalf("carol should not reuse dave's uid: %+v", diff.Added)
+	}
+	content, _ := os.ReadFile(pwFile)
+	if !strings.Contains(string(content), "\ndave:") || !strings.Contains(string(content), "\ncarol:x:1003:") {
+		t.Fatalf("the other writer's change should have been kept: %q", content)
+	}
+	if _, ok := cache.LookupUserByName("dave"); !ok {
+		t.Fatalf("the cache should have picked up dave")
+	}
+
+	// a failed write leaves the cache unchanged
+	broken := NewEtcPasswdCache(false, WithoutLocking())
+	brokenDir := t.TempDir()
+	os.WriteFile(filepath.Join(brokenDir, "passwd"), []byte(fakeReconcileContent), 0644)
+	broken.LoadFromPath(filepath.Join(brokenDir, "passwd"))
+	os.RemoveAll(brokenDir)
+	if _, err := broken.Reconcile([]UserSpec{{Username: "erin"}}, ReconcileOptions{WriteBack: true}); err == nil {
+		t.Fatalf("Should have failed to write")
+	}
+	if _, ok := broken.LookupUserByName("erin"); ok {
+		t.Fatalf("erin should not have been added after a failed write")
+	}
+}
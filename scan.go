@@ -0,0 +1,83 @@
+package etcpwdparse
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrStopStream can be returned by the ParsePasswdStream callback to stop reading early without
+// ParsePasswdStream returning an error.
+var ErrStopStream = errors.New("Stop passwd stream")
+
+// LookupUserByNameFromPath scans the passwd file at path line by line and returns the first
+// entry with the given username without building a cache. Scanning stops at the match, so this
+// is cheaper than loading the whole file when only one user is needed. Lines that do not parse
+// are skipped, like the glibc files backend does.
+func LookupUserByNameFromPath(path, name string) (*EtcPasswdEntry, error) {
+	entry, err := findInPath(path, func(entry *EtcPasswdEntry) bool {
+		return entry.username == name
+	})
+	if err == nil && entry == nil {
+		err = sentinelf(ErrUserNotFound, "No such user with username '%s'", name)
+	}
+	return entry, err
+}
+
+// LookupUserByUidFromPath scans the passwd file at path line by line and returns the first
+// entry with the given user id without building a cache.
+func LookupUserByUidFromPath(path string, uid int) (*EtcPasswdEntry, error) {
+	entry, err := findInPath(path, func(entry *EtcPasswdEntry) bool {
+		return entry.uid == uid
+	})
+	if err == nil && entry == nil {
+		err = sentinelf(ErrUserNotFound, "No such user with uid %d", uid)
+	}
+	return entry, err
+}
+
+// findInPath returns the first entry in the file matching the predicate, or nil if none do.
+func findInPath(path string, match func(*EtcPasswdEntry) bool) (*EtcPasswdEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var result *EtcPasswdEntry
+	err = readLines(f, func(line string) error {
+		if IsCompatLine(line) {
+			return nil
+		}
+		if entry, err := ParsePasswdLine(line); err == nil && match(&entry) {
+			result = &entry
+			return ErrStopStream
+		}
+		return nil
+	})
+	if err != nil && err != ErrStopStream {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ParsePasswdStream parses passwd lines from the reader and calls fn with each entry as it is
+// read, without retaining them, so very large files can be processed in constant memory.
+// Comments, blank lines, and NIS compat lines are skipped. A line that fails to parse stops the
+// stream with a *ParseError holding its line number, as does any error returned by fn, except
+// ErrStopStream which ends the stream successfully.
+func ParsePasswdStream(r io.Reader, fn func(EtcPasswdEntry) error) error {
+	err := readLines(r, func(line string) error {
+		if IsCompatLine(line) {
+			return nil
+		}
+		entry, err := ParsePasswdLine(line)
+		if err != nil {
+			return err
+		}
+		return fn(entry)
+	})
+	if err == ErrStopStream {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestLookupUserFromPath(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	ioutil.WriteFile(pwFile, []byte(fakePwdContent+"broken\nalice:x:1000:1000::/home/alice:/bin/bash\nalice:x:1001:1001::/home/alice2:/bin/sh\n"), 0644)
+
+	entry, err := LookupUserByNameFromPath(pwFile, "alice")
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry.Uid() != 1000 {
+		t.Fatalf("%d != 1000", entry.Uid())
+	}
+	if _, err := LookupUserByNameFromPath(pwFile, "bob"); err == nil {
+		t.Fatalf("Should have failed on a missing user")
+	}
+
+	entry, err = LookupUserByUidFromPath(pwFile, 99)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry.Username() != "nobody" {
+		t.Fatalf("%s != nobody", entry.Username())
+	}
+	if _, err := LookupUserByUidFromPath(pwFile, 5000); err == nil {
+		t.Fatalf("Should have failed on a missing uid")
+	}
+	if _, err := LookupUserByNameFromPath(path.Join(tempDir, "missing"), "root"); !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestParsePasswdStream(t *testing.T) {
+	count := 0
+	err := ParsePasswdStream(strings.NewReader(fakePwdContent), func(entry EtcPasswdEntry) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if count != 13 {
+		t.Fatalf("%d != 13", count)
+	}
+
+	names := make([]string, 0)
+	err = ParsePasswdStream(strings.NewReader(fakePwdContent), func(entry EtcPasswdEntry) error {
+		names = append(names, entry.Username())
+		if entry.Username() == "daemon" {
+			return ErrStopStream
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if strings.Join(names, ",") != "root,bin,daemon" {
+		t.Fatalf("unexpected names %v", names)
+	}
+
+	err = ParsePasswdStream(strings.NewReader("# header\nroot:x:0:0:root:/root:/bin/bash\nbroken\n"), func(entry EtcPasswdEntry) error {
+		return nil
+	})
+	if pe, ok := err.(*ParseError); !ok || pe.LineNumber != 3 || pe.RawLine != "broken" {
+		t.Fatalf("expected a parse error on line 3, got %v", err)
+	}
+
+	stop := fmt.Errorf("stop")
+	err = ParsePasswdStream(strings.NewReader(fakePwdContent), func(entry EtcPasswdEntry) error {
+		return stop
+	})
+	if err != stop {
+		t.Fatalf("expected the callback error, got %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package etcpwdparse
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EtcShadowEntry is a parsed line from the etc shadow file. It contains all 9 parts of the structure.
+// The numeric day fields are -1 when the field is empty in the file, meaning the feature is disabled.
+type EtcShadowEntry struct {
+	username   string
+	password   string
+	lastchange int
+	min        int
+	max        int
+	warn       int
+	inactive   int
+	expire     int
+	reserved   string
+}
+
+// Username function returns the username string for the entry
+func (e *EtcShadowEntry) Username() string {
+	return e.username
+}
+
+// Password function returns the encrypted password hash for the entry
+func (e *EtcShadowEntry) Password() string {
+	return e.password
+}
+
+// LastChange function returns the date of the last password change in days since the epoch
+func (e *EtcShadowEntry) LastChange() int {
+	return e.lastchange
+}
+
+// Min function returns the minimum password age in days
+func (e *EtcShadowEntry) Min() int {
+	return e.min
+}
+
+// Max function returns the maximum password age in days
+func (e *EtcShadowEntry) Max() int {
+	return e.max
+}
+
+// Warn function returns the number of days of warning before the password expires
+func (e *EtcShadowEntry) Warn() int {
+	return e.warn
+}
+
+// Inactive function returns the number of days after password expiry that the account is disabled
+func (e *EtcShadowEntry) Inactive() int {
+	return e.inactive
+}
+
+// Expire function returns the account expiration date in days since the epoch
+func (e *EtcShadowEntry) Expire() int {
+	return e.expire
+}
+
+// Reserved function returns the content of the reserved field
+func (e *EtcShadowEntry) Reserved() string {
+	return e.reserved
+}
+
+// formatShadowDays formats an optional day count field, writing -1 as an empty field.
+func formatShadowDays(days int) string {
+	if days < 0 {
+		return ""
+	}
+	return strconv.Itoa(days)
+}
+
+// String returns the entry formatted as a 9 part /etc/shadow line without a line ending. The
+// result can be parsed again with ParseShadowLine.
+func (e EtcShadowEntry) String() string {
+	return strings.Join([]string{
+		e.username,
+		e.password,
+		formatShadowDays(e.lastchange),
+		formatShadowDays(e.min),
+		formatShadowDays(e.max),
+		formatShadowDays(e.warn),
+		formatShadowDays(e.inactive),
+		formatShadowDays(e.expire),
+		e.reserved,
+	}, ":")
+}
+
+// EtcShadowCache is an object that stores a set of entries from the shadow file and
+// has quick lookup functions.
+type EtcShadowCache struct {
+	entries        []*EtcShadowEntry
+	namemap        map[string]*EtcShadowEntry
+	ignoreBadLines bool
+	opts           options
+}
+
+// parseShadowDays parses an optional day count field, returning -1 for an empty field.
+func parseShadowDays(line, field, name string) (int, error) {
+	field = strings.TrimSpace(field)
+	if len(field) == 0 {
+		return -1, nil
+	}
+	days, err := strconv.Atoi(field)
+	if err != nil {
+		return 0, newParseError(line, name, "Shadow line had badly formatted %s %s: %w", name, field, err)
+	}
+	return days, nil
+}
+
+// ParseShadowLine is a function used to parse a 9 entry /etc/shadow line formatted line
+// into a EtcShadowEntry object. Errors are returned as a *ParseError.
+func ParseShadowLine(line string) (EtcShadowEntry, error) {
+	result := EtcShadowEntry{}
+	parts := strings.Split(strings.TrimSpace(line), ":")
+	if len(parts) != 9 {
+		return result, newParseError(line, "", "Shadow line had wrong number of parts %d != 9", len(parts))
+	}
+	result.username = strings.TrimSpace(parts[0])
+	result.password = strings.TrimSpace(parts[1])
+
+	fields := []struct {
+		target *int
+		name   string
+	}{
+		{&result.lastchange, "lastchange"},
+		{&result.min, "min"},
+		{&result.max, "max"},
+		{&result.warn, "warn"},
+		{&result.inactive, "inactive"},
+		{&result.expire, "expire"},
+	}
+	for i, f := range fields {
+		days, err := parseShadowDays(line, parts[i+2], f.name)
+		if err != nil {
+			return result, err
+		}
+		*f.target = days
+	}
+
+	result.reserved = strings.TrimSpace(parts[8])
+	return result, nil
+}
+
+// AddEntry adds an entry object to the cache object and links it into the lookup map.
+// Overrides any existing item in the lookup map.
+func (e *EtcShadowCache) AddEntry(entry EtcShadowEntry) {
+	if e.namemap == nil {
+		e.reset()
+	}
+	e.entries = append(e.entries, &entry)
+	e.namemap[e.opts.nameKey(entry.username)] = &entry
+}
+
+// reset replaces the content with empty structures.
+func (e *EtcShadowCache) reset() {
+	e.entries = make([]*EtcShadowEntry, 0)
+	e.namemap = make(map[string]*EtcShadowEntry)
+}
+
+// replaceEntries replaces the cached entries and rebuilds the lookup map.
+func (e *EtcShadowCache) replaceEntries(entries []*EtcShadowEntry) {
+	e.entries = entries
+	e.namemap = make(map[string]*EtcShadowEntry, len(entries))
+	for _, entry := range entries {
+		e.namemap[e.opts.nameKey(entry.username)] = entry
+	}
+}
+
+// LoadFromPath loads the struct from a file on disk and replaces the cached content.
+func (e *EtcShadowCache) LoadFromPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return e.LoadFromReader(f)
+}
+
+// LoadFromReader loads the struct from shadow formatted content read from the given reader
+// and replaces the cached content. The existing content is left untouched if loading fails.
+func (e *EtcShadowCache) LoadFromReader(r io.Reader) error {
+	next := &EtcShadowCache{ignoreBadLines: e.ignoreBadLines, opts: e.opts}
+	next.reset()
+	err := readNumberedLines(r, func(lineNumber int, raw, line string) error {
+		parse := ParseShadowLine
+		if e.opts.dialect == DialectSolaris {
+			parse = ParseSolarisShadowLine
+		}
+		entry, err := parse(line)
+		if err != nil {
+			if e.ignoreBadLines {
+				e.opts.badLine(lineNumber, raw, err)
+				return nil
+			}
+			return err
+		}
+		next.AddEntry(entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	e.entries, e.namemap = next.entries, next.namemap
+	return nil
+}
+
+// NewEtcShadowCache returns an empty shadow cache configured with the given options.
+func NewEtcShadowCache(ignoreBadLines bool, opts ...Option) *EtcShadowCache {
+	return &EtcShadowCache{
+		ignoreBadLines: ignoreBadLines,
+		opts:           applyOptions(opts),
+	}
+}
+
+// NewLoadedEtcShadowCache returns a loaded shadow cache in a single call.
+// The /etc/shadow file is usually only readable by root.
+func NewLoadedEtcShadowCache(opts ...Option) (*EtcShadowCache, error) {
+	result := NewEtcShadowCache(false, opts...)
+	if err := result.LoadDefault(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LoadDefault loads the struct from the /etc/shadow file, relative to the root given by WithRoot.
+// The ETCPWDPARSE_SHADOW environment variable overrides the location.
+func (e *EtcShadowCache) LoadDefault() error {
+	return e.LoadFromPath(e.opts.defaultPath("/etc/shadow"))
+}
+
+// LookupUserByName returns the entry for the given username
+func (e *EtcShadowCache) LookupUserByName(name string) (*EtcShadowEntry, bool) {
+	entry, ok := e.namemap[e.opts.nameKey(name)]
+	return entry, ok
+}
+
+// WriteTo writes every entry in file order as /etc/shadow lines. Comments and blank lines from
+// the loaded file are not kept.
+func (e *EtcShadowCache) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, entry := range e.entries {
+		n, err := io.WriteString(w, entry.String()+"\n")
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ListEntries returns a slice containing references to all the entry objects
+func (e *EtcShadowCache) ListEntries() []*EtcShadowEntry {
+	results := make([]*EtcShadowEntry, len(e.entries))
+	copy(results, e.entries)
+	return results
+}
+
+// ParseSolarisShadowLine is a function used to parse a Solaris style /etc/shadow line into a
+// EtcShadowEntry object. Unlike ParseShadowLine it accepts lines with fewer than 9 fields,
+// treating the missing trailing fields as empty. Errors are returned as a *ParseError.
+func ParseSolarisShadowLine(line string) (EtcShadowEntry, error) {
+	parts := strings.Split(strings.TrimSpace(line), ":")
+	if len(parts) < 2 || len(parts) > 9 {
+		return EtcShadowEntry{}, newParseError(line, "", "Shadow line had wrong number of parts %d, expected 2 to 9", len(parts))
+	}
+	for len(parts) < 9 {
+		parts = append(parts, "")
+	}
+	result, err := ParseShadowLine(strings.Join(parts, ":"))
+	if pe, ok := err.(*ParseError); ok {
+		pe.RawLine = line
+	}
+	return result, err
+}
+
+// FailedLogins function returns the count of consecutive failed logins that Solaris keeps in the
+// low 4 bits of the last shadow field, or 0 if the field is empty or not a number.
+func (e *EtcShadowEntry) FailedLogins() int {
+	flag, err := strconv.Atoi(e.reserved)
+	if err != nil || flag < 0 {
+		return 0
+	}
+	return flag & 0xf
+}
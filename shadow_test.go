@@ -0,0 +1,114 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+const fakeShadowContent = `
+# commented line
+root:$6$saltsalt$hash:17834:0:99999:7:::
+bin:*:17834:0:99999:7:::
+alice:!:18000:1:90:14:30:19000:
+`
+
+func TestShadowFull(t *testing.T) {
+	cache := NewEtcShadowCache(false)
+	err := cache.LoadFromReader(strings.NewReader(fakeShadowContent))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	rootEntry, _ := cache.LookupUserByName("root")
+	if rootEntry.Password() != "$6$saltsalt$hash" {
+		t.Fatalf("%s != $6$saltsalt$hash", rootEntry.Password())
+	}
+	if rootEntry.LastChange() != 17834 {
+		t.Fatalf("%d != 17834", rootEntry.LastChange())
+	}
+	if rootEntry.Max() != 99999 {
+		t.Fatalf("%d != 99999", rootEntry.Max())
+	}
+	if rootEntry.Inactive() != -1 || rootEntry.Expire() != -1 {
+		t.Fatalf("empty fields should be -1, got %d and %d", rootEntry.Inactive(), rootEntry.Expire())
+	}
+
+	aliceEntry, _ := cache.LookupUserByName("alice")
+	if aliceEntry.Min() != 1 || aliceEntry.Warn() != 14 || aliceEntry.Inactive() != 30 || aliceEntry.Expire() != 19000 {
+		t.Fatalf("alice aging fields were parsed incorrectly: %+v", aliceEntry)
+	}
+
+	if len(cache.ListEntries()) != 3 {
+		t.Fatalf("%d != 3", len(cache.ListEntries()))
+	}
+
+	if _, err := ParseShadowLine("bob:x:abc:0:99999:7:::"); err == nil {
+		t.Fatalf("Should have failed on a bad lastchange")
+	}
+	if _, err := ParseShadowLine("bob:x:0:0"); err == nil {
+		t.Fatalf("Should have failed on a short line")
+	}
+}
+
+func TestSolarisShadow(t *testing.T) {
+	content := "root:$5$salt$hash:6445::::::\nlp:NP:6445\nbob:*LK*:17000::::::3\n"
+
+	if err := NewEtcShadowCache(false).LoadFromReader(strings.NewReader(content)); err == nil {
+		t.Fatalf("Should have failed on a short line with the linux dialect")
+	}
+
+	cache := NewEtcShadowCache(false, WithDialect(DialectSolaris))
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	lp, _ := cache.LookupUserByName("lp")
+	if lp.LastChange() != 6445 || lp.Max() != -1 {
+		t.Fatalf("lp was parsed incorrectly: %+v", lp)
+	}
+	if ClassifyPassword(lp.Password()) != PasswordNoLogin {
+		t.Fatalf("NP should mean no password login")
+	}
+	bob, _ := cache.LookupUserByName("bob")
+	if bob.FailedLogins() != 3 {
+		t.Fatalf("%d != 3", bob.FailedLogins())
+	}
+	if ClassifyPassword(bob.Password()) != PasswordLocked {
+		t.Fatalf("*LK* should mean locked")
+	}
+}
+
+func TestShadowWriteTo(t *testing.T) {
+	content := "root:$6$salt$hash:19000:0:99999:7:::\n# comment\nbob:!:19000::::::\n"
+	cache := NewEtcShadowCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	buf := new(strings.Builder)
+	if _, err := cache.WriteTo(buf); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	expected := "root:$6$salt$hash:19000:0:99999:7:::\nbob:!:19000::::::\n"
+	if buf.String() != expected {
+		t.Fatalf("%q != %q", buf.String(), expected)
+	}
+}
+
+func TestShadowAddEntryAndFailedLoad(t *testing.T) {
+	cache := NewEtcShadowCache(false)
+	entry, _ := ParseShadowLine("alice:!:18000:0:99999:7:::")
+	cache.AddEntry(entry)
+	if _, ok := cache.LookupUserByName("alice"); !ok {
+		t.Fatalf("expected alice to be added to an empty cache")
+	}
+
+	// a failed load leaves the existing content in place
+	if err := cache.LoadFromReader(strings.NewReader("root:*:17834:0:99999:7:::\nbroken:line\n")); err == nil {
+		t.Fatalf("Should have failed on a bad line")
+	}
+	if _, ok := cache.LookupUserByName("root"); ok {
+		t.Fatalf("root should not have been loaded")
+	}
+	if _, ok := cache.LookupUserByName("alice"); !ok || len(cache.ListEntries()) != 1 {
+		t.Fatalf("expected the existing content to be kept")
+	}
+}
@@ -0,0 +1,114 @@
+package etcpwdparse
+
+import (
+	"io"
+	"os"
+)
+
+// DefaultNoLoginShells are the shells which CanLoginShell always treats as refusing logins.
+var DefaultNoLoginShells = []string{
+	"/sbin/nologin",
+	"/usr/sbin/nologin",
+	"/usr/bin/nologin",
+	"/bin/false",
+	"/usr/bin/false",
+}
+
+// ShellsCache is an object that stores the list of valid login shells from the etc shells file.
+type ShellsCache struct {
+	shells []string
+	set    map[string]bool
+	opts   options
+}
+
+// NewShellsCache returns an empty shells cache configured with the given options.
+func NewShellsCache(opts ...Option) *ShellsCache {
+	return &ShellsCache{
+		shells: make([]string, 0),
+		set:    make(map[string]bool),
+		opts:   applyOptions(opts),
+	}
+}
+
+// NewLoadedShellsCache returns a shells cache loaded from /etc/shells in a single call.
+func NewLoadedShellsCache(opts ...Option) (*ShellsCache, error) {
+	result := NewShellsCache(opts...)
+	if err := result.LoadDefault(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LoadFromPath loads the struct from a file on disk and replaces the cached content.
+func (s *ShellsCache) LoadFromPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.LoadFromReader(f)
+}
+
+// LoadFromReader loads the struct from shells formatted content, one shell path per line,
+// read from the given reader and replaces the cached content.
+func (s *ShellsCache) LoadFromReader(r io.Reader) error {
+	shells := make([]string, 0)
+	set := make(map[string]bool)
+	err := readLines(r, func(line string) error {
+		if !set[line] {
+			shells = append(shells, line)
+			set[line] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.shells = shells
+	s.set = set
+	return nil
+}
+
+// LoadDefault loads the struct from the /etc/shells file, relative to the root given by WithRoot
+func (s *ShellsCache) LoadDefault() error {
+	return s.LoadFromPath(s.opts.defaultPath("/etc/shells"))
+}
+
+// Contains returns true if the shell is listed as a valid login shell
+func (s *ShellsCache) Contains(shell string) bool {
+	return s.set[shell]
+}
+
+// ListShells returns the listed shells in file order
+func (s *ShellsCache) ListShells() []string {
+	results := make([]string, len(s.shells))
+	copy(results, s.shells)
+	return results
+}
+
+// HasValidShell returns true if the entry's shell is listed in the given shells cache. An empty
+// shell field means /bin/sh to login, so that is what is checked in that case.
+func (e *EtcPasswdEntry) HasValidShell(shells *ShellsCache) bool {
+	shell := e.shell
+	if len(shell) == 0 {
+		shell = "/bin/sh"
+	}
+	return shells.Contains(shell)
+}
+
+// CanLoginShell returns false if the entry's shell is one of DefaultNoLoginShells or of the given
+// extra shells, which is how service accounts are usually kept from logging in. An empty shell
+// field means /bin/sh so it can log in.
+func (e *EtcPasswdEntry) CanLoginShell(deny ...string) bool {
+	for _, shell := range DefaultNoLoginShells {
+		if e.shell == shell {
+			return false
+		}
+	}
+	for _, shell := range deny {
+		if e.shell == shell {
+			return false
+		}
+	}
+	return true
+}
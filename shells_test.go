@@ -0,0 +1,49 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+const fakeShellsContent = `# /etc/shells: valid login shells
+/bin/sh
+/bin/bash
+/usr/bin/zsh
+/bin/bash
+`
+
+func TestShellsCache(t *testing.T) {
+	shells := NewShellsCache()
+	if err := shells.LoadFromReader(strings.NewReader(fakeShellsContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if strings.Join(shells.ListShells(), ",") != "/bin/sh,/bin/bash,/usr/bin/zsh" {
+		t.Fatalf("unexpected shells %v", shells.ListShells())
+	}
+
+	if !(&EtcPasswdEntry{shell: "/bin/bash"}).HasValidShell(shells) {
+		t.Fatalf("/bin/bash should be valid")
+	}
+	if (&EtcPasswdEntry{shell: "/sbin/nologin"}).HasValidShell(shells) {
+		t.Fatalf("/sbin/nologin should not be valid")
+	}
+	if !(&EtcPasswdEntry{}).HasValidShell(shells) {
+		t.Fatalf("an empty shell should be treated as /bin/sh")
+	}
+}
+
+func TestCanLoginShell(t *testing.T) {
+	for _, shell := range []string{"/bin/bash", "/usr/bin/zsh", ""} {
+		if !(&EtcPasswdEntry{shell: shell}).CanLoginShell() {
+			t.Fatalf("%q should be able to log in", shell)
+		}
+	}
+	for _, shell := range []string{"/sbin/nologin", "/usr/sbin/nologin", "/bin/false"} {
+		if (&EtcPasswdEntry{shell: shell}).CanLoginShell() {
+			t.Fatalf("%q should not be able to log in", shell)
+		}
+	}
+	if (&EtcPasswdEntry{shell: "/bin/sync"}).CanLoginShell("/bin/sync", "/sbin/halt") {
+		t.Fatalf("/bin/sync should have been denied")
+	}
+}
@@ -0,0 +1,191 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"context"
+	"iter"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// UserSource is implemented by anything that can answer user lookups. EtcPasswdCache is the
+// file based implementation; GetentSource and ChainSource allow other sources to be composed
+// behind the same abstraction.
+type UserSource interface {
+	// LookupByName returns the entry for the given username
+	LookupByName(name string) (*EtcPasswdEntry, bool)
+	// LookupByUid returns the entry for the given user id
+	LookupByUid(uid int) (*EtcPasswdEntry, bool)
+	// All returns an iterator over every entry the source knows about
+	All() iter.Seq[*EtcPasswdEntry]
+}
+
+// LookupByName is the same as LookupUserByName and satisfies UserSource
+func (e *EtcPasswdCache) LookupByName(name string) (*EtcPasswdEntry, bool) {
+	return e.LookupUserByName(name)
+}
+
+// LookupByUid is the same as LookupUserByUid and satisfies UserSource
+func (e *EtcPasswdCache) LookupByUid(uid int) (*EtcPasswdEntry, bool) {
+	return e.LookupUserByUid(uid)
+}
+
+// DefaultGetentTimeout is how long the GetentSource lookups without a context wait for getent
+// before giving up.
+const DefaultGetentTimeout = 10 * time.Second
+
+// GetentSource is a UserSource that answers every lookup by running getent, which consults the
+// full NSS configuration of the host. Command defaults to "getent" when empty.
+type GetentSource struct {
+	Command string
+	// Timeout bounds LookupByName and LookupByUid so that a hung NSS backend cannot block them
+	// forever. DefaultGetentTimeout is used when it is zero.
+	Timeout time.Duration
+}
+
+// lookupContext returns the context used by the lookups that do not take one.
+func (s *GetentSource) lookupContext() (context.Context, context.CancelFunc) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultGetentTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// run executes getent with the given arguments after the passwd database name. The process is
+// killed if the context is done before it exits.
+func (s *GetentSource) run(ctx context.Context, args ...string) ([]byte, error) {
+	command := s.Command
+	if len(command) == 0 {
+		command = "getent"
+	}
+	cmd := exec.CommandContext(ctx, command, append([]string{"passwd"}, args...)...)
+	// do not wait forever for output pipes held open by children of a killed process
+	cmd.WaitDelay = time.Second
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return output, err
+}
+
+// lookup runs getent for a single key and returns the first parsed entry. The error is only
+// set when the context is done, a key that getent does not know is not an error.
+func (s *GetentSource) lookup(ctx context.Context, key string) (*EtcPasswdEntry, bool, error) {
+	// end the options so that a key starting with a dash is not taken as one
+	output, err := s.run(ctx, "--", key)
+	if ctx.Err() != nil {
+		return nil, false, err
+	}
+	if err != nil {
+		return nil, false, nil
+	}
+	var result *EtcPasswdEntry
+	readLines(bytes.NewReader(output), func(line string) error {
+		if entry, err := ParsePasswdLine(line); err == nil && result == nil {
+			result = &entry
+		}
+		return nil
+	})
+	return result, result != nil, nil
+}
+
+// LookupByName returns the entry for the given username, giving up after Timeout
+func (s *GetentSource) LookupByName(name string) (*EtcPasswdEntry, bool) {
+	ctx, cancel := s.lookupContext()
+	defer cancel()
+	entry, ok, _ := s.LookupByNameContext(ctx, name)
+	return entry, ok
+}
+
+// LookupByNameContext is like LookupByName but gives up when the context is done, returning
+// the context error, so a hung NSS backend cannot block the caller forever.
+func (s *GetentSource) LookupByNameContext(ctx context.Context, name string) (*EtcPasswdEntry, bool, error) {
+	entry, ok, err := s.lookup(ctx, name)
+	if !ok || entry.username != name {
+		return nil, false, err
+	}
+	return entry, true, nil
+}
+
+// LookupByUid returns the entry for the given user id, giving up after Timeout
+func (s *GetentSource) LookupByUid(uid int) (*EtcPasswdEntry, bool) {
+	ctx, cancel := s.lookupContext()
+	defer cancel()
+	entry, ok, _ := s.LookupByUidContext(ctx, uid)
+	return entry, ok
+}
+
+// LookupByUidContext is like LookupByUid but gives up when the context is done, returning the
+// context error.
+func (s *GetentSource) LookupByUidContext(ctx context.Context, uid int) (*EtcPasswdEntry, bool, error) {
+	entry, ok, err := s.lookup(ctx, strconv.Itoa(uid))
+	if !ok || entry.uid != uid {
+		return nil, false, err
+	}
+	return entry, true, nil
+}
+
+// All enumerates every user with `getent passwd`. Some NSS sources such as LDAP may be
+// configured not to allow enumeration, in which case only the local users are returned.
+// Lines that cannot be parsed are skipped.
+func (s *GetentSource) All() iter.Seq[*EtcPasswdEntry] {
+	return func(yield func(*EtcPasswdEntry) bool) {
+		output, err := s.run(context.Background())
+		if err != nil {
+			return
+		}
+		stopped := false
+		readLines(bytes.NewReader(output), func(line string) error {
+			entry, err := ParsePasswdLine(line)
+			if err == nil && !stopped && !yield(&entry) {
+				stopped = true
+			}
+			return nil
+		})
+	}
+}
+
+// ChainSource is a UserSource that asks each of its sources in turn and returns the first
+// answer, so an in-memory source can override a file which in turn overrides getent.
+type ChainSource []UserSource
+
+// LookupByName returns the entry for the given username from the first source that has it
+func (c ChainSource) LookupByName(name string) (*EtcPasswdEntry, bool) {
+	for _, s := range c {
+		if entry, ok := s.LookupByName(name); ok {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// LookupByUid returns the entry for the given user id from the first source that has it
+func (c ChainSource) LookupByUid(uid int) (*EtcPasswdEntry, bool) {
+	for _, s := range c {
+		if entry, ok := s.LookupByUid(uid); ok {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// All iterates over the entries of every source in order, skipping usernames that an earlier
+// source has already produced.
+func (c ChainSource) All() iter.Seq[*EtcPasswdEntry] {
+	return func(yield func(*EtcPasswdEntry) bool) {
+		seen := make(map[string]bool)
+		for _, s := range c {
+			for entry := range s.All() {
+				if seen[entry.username] {
+					continue
+				}
+				seen[entry.username] = true
+				if !yield(entry) {
+					return
+				}
+			}
+		}
+	}
+}
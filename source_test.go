@@ -0,0 +1,53 @@
+package etcpwdparse
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+const fakeGetentEnumerateScript = `#!/bin/sh
+case "$2$3" in
+  "") echo "root:x:0:0:other root:/root:/bin/sh"; echo "ldapuser:*:5000:5000::/home/ldapuser:/bin/bash" ;;
+  --ldapuser|--5000) echo "ldapuser:*:5000:5000::/home/ldapuser:/bin/bash" ;;
+  *) exit 2 ;;
+esac
+`
+
+func TestChainSource(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "bin")
+	defer os.RemoveAll(tempDir)
+	script := path.Join(tempDir, "getent")
+	if err := ioutil.WriteFile(script, []byte(fakeGetentEnumerateScript), 0755); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	var source UserSource = ChainSource{cache, &GetentSource{Command: script}}
+
+	if entry, ok := source.LookupByName("root"); !ok || entry.Info() != "root" {
+		t.Fatalf("root should come from the file")
+	}
+	if entry, ok := source.LookupByUid(5000); !ok || entry.Username() != "ldapuser" {
+		t.Fatalf("uid 5000 should come from getent")
+	}
+	if _, ok := source.LookupByName("missing"); ok {
+		t.Fatalf("missing should not be found")
+	}
+
+	count := 0
+	for entry := range source.All() {
+		if entry.Username() == "root" && entry.Info() != "root" {
+			t.Fatalf("the file root should shadow the getent root")
+		}
+		count++
+	}
+	if count != 14 {
+		t.Fatalf("%d != 14", count)
+	}
+}
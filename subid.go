@@ -0,0 +1,184 @@
+package etcpwdparse
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SubIDEntry is a parsed line from an etc subuid or subgid file. It describes a range of
+// subordinate ids that the owner is allowed to use in a user namespace.
+type SubIDEntry struct {
+	owner string
+	start int
+	count int
+}
+
+// Owner function returns the owner of the range. This is either a username or a numeric id.
+func (e *SubIDEntry) Owner() string {
+	return e.owner
+}
+
+// Start function returns the first subordinate id in the range
+func (e *SubIDEntry) Start() int {
+	return e.start
+}
+
+// Count function returns the number of subordinate ids in the range
+func (e *SubIDEntry) Count() int {
+	return e.count
+}
+
+// Contains function returns true if the given id falls inside the range
+func (e *SubIDEntry) Contains(id int) bool {
+	return id >= e.start && id < e.start+e.count
+}
+
+// SubIDCache is an object that stores a set of ranges from a subuid or subgid file and
+// has quick lookup functions.
+type SubIDCache struct {
+	entries        []*SubIDEntry
+	ownermap       map[string][]*SubIDEntry
+	ignoreBadLines bool
+	opts           options
+}
+
+// ParseSubIDLine is a function used to parse a 3 entry /etc/subuid or /etc/subgid line
+// formatted line into a SubIDEntry object. Errors are returned as a *ParseError.
+func ParseSubIDLine(line string) (SubIDEntry, error) {
+	result := SubIDEntry{}
+	parts := strings.Split(strings.TrimSpace(line), ":")
+	if len(parts) != 3 {
+		return result, newParseError(line, "", "Subid line had wrong number of parts %d != 3", len(parts))
+	}
+	result.owner = strings.TrimSpace(parts[0])
+
+	start, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return result, newParseError(line, "start", "Subid line had badly formatted start %s: %w", parts[1], err)
+	} else if start < 0 {
+		return result, newParseError(line, "start", "Subid line had negative start %d", start)
+	}
+	result.start = start
+
+	count, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return result, newParseError(line, "count", "Subid line had badly formatted count %s: %w", parts[2], err)
+	} else if count < 0 {
+		return result, newParseError(line, "count", "Subid line had negative count %d", count)
+	}
+	result.count = count
+	return result, nil
+}
+
+// AddEntry adds an entry object to the cache object and links it into the lookup map.
+// An owner may have multiple ranges.
+func (e *SubIDCache) AddEntry(entry SubIDEntry) {
+	if e.ownermap == nil {
+		e.reset()
+	}
+	e.entries = append(e.entries, &entry)
+	e.ownermap[entry.owner] = append(e.ownermap[entry.owner], &entry)
+}
+
+// LoadFromPath loads the struct from a file on disk and replaces the cached content.
+func (e *SubIDCache) LoadFromPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return e.LoadFromReader(f)
+}
+
+// reset replaces the content with empty structures.
+func (e *SubIDCache) reset() {
+	e.entries = make([]*SubIDEntry, 0)
+	e.ownermap = make(map[string][]*SubIDEntry)
+}
+
+// LoadFromReader loads the struct from subuid or subgid formatted content read from the
+// given reader and replaces the cached content. The existing content is left untouched if
+// loading fails.
+func (e *SubIDCache) LoadFromReader(r io.Reader) error {
+	next := &SubIDCache{ignoreBadLines: e.ignoreBadLines, opts: e.opts}
+	next.reset()
+	err := readNumberedLines(r, func(lineNumber int, raw, line string) error {
+		entry, err := ParseSubIDLine(line)
+		if err != nil {
+			if e.ignoreBadLines {
+				e.opts.badLine(lineNumber, raw, err)
+				return nil
+			}
+			return err
+		}
+		next.AddEntry(entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	e.entries, e.ownermap = next.entries, next.ownermap
+	return nil
+}
+
+// NewSubIDCache returns an empty subordinate id cache configured with the given options.
+func NewSubIDCache(ignoreBadLines bool, opts ...Option) *SubIDCache {
+	return &SubIDCache{
+		ignoreBadLines: ignoreBadLines,
+		opts:           applyOptions(opts),
+	}
+}
+
+// NewLoadedSubuidCache returns a cache loaded from the /etc/subuid file in a single call.
+func NewLoadedSubuidCache(opts ...Option) (*SubIDCache, error) {
+	result := NewSubIDCache(false, opts...)
+	if err := result.LoadDefaultSubuid(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// NewLoadedSubgidCache returns a cache loaded from the /etc/subgid file in a single call.
+func NewLoadedSubgidCache(opts ...Option) (*SubIDCache, error) {
+	result := NewSubIDCache(false, opts...)
+	if err := result.LoadDefaultSubgid(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LoadDefaultSubuid loads the struct from the /etc/subuid file, relative to the root given by WithRoot
+func (e *SubIDCache) LoadDefaultSubuid() error {
+	return e.LoadFromPath(e.opts.defaultPath("/etc/subuid"))
+}
+
+// LoadDefaultSubgid loads the struct from the /etc/subgid file, relative to the root given by WithRoot
+func (e *SubIDCache) LoadDefaultSubgid() error {
+	return e.LoadFromPath(e.opts.defaultPath("/etc/subgid"))
+}
+
+// RangesForOwner returns the ranges listed exactly against the given owner string
+func (e *SubIDCache) RangesForOwner(owner string) []*SubIDEntry {
+	results := make([]*SubIDEntry, len(e.ownermap[owner]))
+	copy(results, e.ownermap[owner])
+	return results
+}
+
+// RangesForUser returns the ranges that belong to the given user. Ranges may be listed
+// against either the username or the numeric uid so both are checked.
+func (e *SubIDCache) RangesForUser(name string, uid int) []*SubIDEntry {
+	results := e.RangesForOwner(name)
+	if idOwner := strconv.Itoa(uid); idOwner != name {
+		results = append(results, e.ownermap[idOwner]...)
+	}
+	return results
+}
+
+// ListEntries returns a slice containing references to all the entry objects
+func (e *SubIDCache) ListEntries() []*SubIDEntry {
+	results := make([]*SubIDEntry, len(e.entries))
+	copy(results, e.entries)
+	return results
+}
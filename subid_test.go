@@ -0,0 +1,66 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+const fakeSubIDContent = `
+# commented line
+alice:100000:65536
+bob:165536:65536
+1002:231072:65536
+alice:400000:1000
+`
+
+func TestSubIDFull(t *testing.T) {
+	cache := NewSubIDCache(false)
+	err := cache.LoadFromReader(strings.NewReader(fakeSubIDContent))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	aliceRanges := cache.RangesForUser("alice", 1000)
+	if len(aliceRanges) != 2 {
+		t.Fatalf("%d != 2", len(aliceRanges))
+	}
+	if aliceRanges[0].Start() != 100000 || aliceRanges[0].Count() != 65536 {
+		t.Fatalf("alice range was parsed incorrectly: %+v", aliceRanges[0])
+	}
+	if !aliceRanges[1].Contains(400999) || aliceRanges[1].Contains(401000) {
+		t.Fatalf("alice range contains check was incorrect")
+	}
+
+	carolRanges := cache.RangesForUser("carol", 1002)
+	if len(carolRanges) != 1 || carolRanges[0].Owner() != "1002" {
+		t.Fatalf("carol should have had the numeric range")
+	}
+
+	if len(cache.ListEntries()) != 4 {
+		t.Fatalf("%d != 4", len(cache.ListEntries()))
+	}
+
+	if _, err := ParseSubIDLine("bob:abc:65536"); err == nil {
+		t.Fatalf("Should have failed on a bad start")
+	}
+}
+
+func TestSubIDAddEntryAndFailedLoad(t *testing.T) {
+	cache := NewSubIDCache(false)
+	entry, _ := ParseSubIDLine("alice:100000:65536")
+	cache.AddEntry(entry)
+	if len(cache.RangesForOwner("alice")) != 1 {
+		t.Fatalf("expected alice to be added to an empty cache")
+	}
+
+	// a failed load leaves the existing content in place
+	if err := cache.LoadFromReader(strings.NewReader("bob:165536:65536\nbroken:line\n")); err == nil {
+		t.Fatalf("Should have failed on a bad line")
+	}
+	if len(cache.RangesForOwner("bob")) != 0 {
+		t.Fatalf("bob should not have been loaded")
+	}
+	if len(cache.RangesForOwner("alice")) != 1 || len(cache.ListEntries()) != 1 {
+		t.Fatalf("expected the existing content to be kept")
+	}
+}
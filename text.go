@@ -0,0 +1,25 @@
+package etcpwdparse
+
+// String returns the entry formatted as an /etc/passwd line without a line ending. The result
+// can be parsed again with ParsePasswdLine.
+func (e EtcPasswdEntry) String() string {
+	return formatPasswdLine(&e)
+}
+
+// MarshalText encodes the entry as an /etc/passwd line, the same as String.
+func (e EtcPasswdEntry) MarshalText() ([]byte, error) {
+	if err := validateEntry(&e); err != nil {
+		return nil, err
+	}
+	return []byte(formatPasswdLine(&e)), nil
+}
+
+// UnmarshalText decodes the entry from an /etc/passwd line in the same way as ParsePasswdLine.
+func (e *EtcPasswdEntry) UnmarshalText(text []byte) error {
+	entry, err := ParsePasswdLine(string(text))
+	if err != nil {
+		return err
+	}
+	*e = entry
+	return nil
+}
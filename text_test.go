@@ -0,0 +1,36 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEntryText(t *testing.T) {
+	line := "nobody:x:99:99:Nobody:/:/sbin/nologin"
+	entry, _ := ParsePasswdLine(line)
+	if entry.String() != line {
+		t.Fatalf("%s != %s", entry.String(), line)
+	}
+	if fmt.Sprint(&entry) != line {
+		t.Fatalf("%s != %s", fmt.Sprint(&entry), line)
+	}
+
+	text, err := entry.MarshalText()
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	var decoded EtcPasswdEntry
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if decoded != entry {
+		t.Fatalf("%+v != %+v", decoded, entry)
+	}
+
+	if err := decoded.UnmarshalText([]byte("nobody:x")); err == nil {
+		t.Fatalf("Should have failed on a short line")
+	}
+	if _, err := (EtcPasswdEntry{username: "a:b"}).MarshalText(); err == nil {
+		t.Fatalf("Should have failed on an unencodable entry")
+	}
+}
@@ -0,0 +1,91 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"sort"
+)
+
+// sortUidIndex sorts the uid index by user id, keeping file order for equal ids. It is called
+// once after a bulk load so that loading stays O(n log n).
+func (e *EtcPasswdCache) sortUidIndex() {
+	sort.SliceStable(e.uidindex, func(i, j int) bool {
+		return e.uidindex[i].uid < e.uidindex[j].uid
+	})
+}
+
+// settleLastUidIndexEntry moves the most recently appended entry of the uid index into its
+// sorted position. The caller must hold the write lock.
+func (e *EtcPasswdCache) settleLastUidIndexEntry() {
+	last := len(e.uidindex) - 1
+	entry := e.uidindex[last]
+	pos := sort.Search(last, func(i int) bool {
+		return e.uidindex[i].uid > entry.uid
+	})
+	copy(e.uidindex[pos+1:], e.uidindex[pos:last])
+	e.uidindex[pos] = entry
+}
+
+// EntriesInUidRange returns the entries with a user id between min and max inclusive, ordered
+// by user id. A sorted index is kept so the lookup does not scan every entry.
+func (e *EtcPasswdCache) EntriesInUidRange(min, max int) []*EtcPasswdEntry {
+	e.refresh()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if max < min {
+		return make([]*EtcPasswdEntry, 0)
+	}
+	start := sort.Search(len(e.uidindex), func(i int) bool {
+		return e.uidindex[i].uid >= min
+	})
+	end := sort.Search(len(e.uidindex), func(i int) bool {
+		return e.uidindex[i].uid > max
+	})
+	results := make([]*EtcPasswdEntry, end-start)
+	copy(results, e.uidindex[start:end])
+	return results
+}
+
+// NextFreeUid returns the lowest user id between min and max inclusive that is not used by any
+// entry. An error is returned if every id in the range is taken.
+func (e *EtcPasswdCache) NextFreeUid(min, max int) (int, error) {
+	return e.NextFreeUidRange(min, max, 1)
+}
+
+// NextFreeUidRange returns the lowest user id between min and max inclusive that starts a block
+// of count consecutive unused ids that also fits within the range. This is useful when
+// allocating several accounts that should have adjacent ids.
+func (e *EtcPasswdCache) NextFreeUidRange(min, max, count int) (int, error) {
+	e.refresh()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.nextFreeUidRange(min, max, count)
+}
+
+// nextFreeUidRange implements NextFreeUidRange. The caller must hold the lock.
+func (e *EtcPasswdCache) nextFreeUidRange(min, max, count int) (int, error) {
+	if count < 1 {
+		return 0, fmt.Errorf("Uid range count must be positive, got %d", count)
+	}
+	candidate := min
+	start := sort.Search(len(e.uidindex), func(i int) bool {
+		return e.uidindex[i].uid >= min
+	})
+	for _, entry := range e.uidindex[start:] {
+		if entry.uid > max || entry.uid-candidate >= count {
+			break
+		}
+		if entry.uid >= candidate {
+			candidate = entry.uid + 1
+		}
+	}
+	if max-candidate+1 < count {
+		return 0, fmt.Errorf("No %d free uids between %d and %d", count, min, max)
+	}
+	return candidate, nil
+}
+
+// NextFreeRegularUid returns the lowest unused user id for a regular account according to the
+// given ranges, such as the ones returned by LoginDefs.AccountRanges.
+func (e *EtcPasswdCache) NextFreeRegularUid(r AccountRanges) (int, error) {
+	return e.NextFreeUid(r.UidMin, r.UidMax)
+}
@@ -0,0 +1,93 @@
+package etcpwdparse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEntriesInUidRange(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	cache.AddEntry(EtcPasswdEntry{username: "alice", uid: 1000})
+	cache.AddEntry(EtcPasswdEntry{username: "ten", uid: 10})
+	cache.AddEntry(EtcPasswdEntry{username: "zero", uid: 0})
+
+	names := func(entries []*EtcPasswdEntry) string {
+		results := make([]string, len(entries))
+		for i, entry := range entries {
+			results[i] = entry.Username()
+		}
+		return strings.Join(results, ",")
+	}
+
+	if r := names(cache.EntriesInUidRange(5, 12)); r != "sync,shutdown,halt,mail,ten,operator,games" {
+		t.Fatalf("unexpected range %s", r)
+	}
+	if r := names(cache.EntriesInUidRange(0, 0)); r != "root,zero" {
+		t.Fatalf("unexpected range %s", r)
+	}
+	if r := names(cache.EntriesInUidRange(100, 60000)); r != "alice" {
+		t.Fatalf("unexpected range %s", r)
+	}
+	if len(cache.EntriesInUidRange(10, 5)) != 0 {
+		t.Fatalf("an inverted range should be empty")
+	}
+}
+
+func TestNextFreeUid(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	cache.AddEntry(EtcPasswdEntry{username: "alice", uid: 1000})
+	cache.AddEntry(EtcPasswdEntry{username: "bob", uid: 1001})
+	cache.AddEntry(EtcPasswdEntry{username: "carol", uid: 1003})
+	cache.AddEntry(EtcPasswdEntry{username: "dave", uid: 1005})
+
+	if uid, err := cache.NextFreeUid(1000, 2000); err != nil || uid != 1002 {
+		t.Fatalf("%d != 1002 (%v)", uid, err)
+	}
+	if uid, err := cache.NextFreeUidRange(1000, 2000, 2); err != nil || uid != 1006 {
+		t.Fatalf("%d != 1006 (%v)", uid, err)
+	}
+	if uid, err := cache.NextFreeUid(0, 20); err != nil || uid != 9 {
+		t.Fatalf("%d != 9 (%v)", uid, err)
+	}
+	if uid, err := cache.NextFreeRegularUid(DefaultAccountRanges()); err != nil || uid != 1002 {
+		t.Fatalf("%d != 1002 (%v)", uid, err)
+	}
+	if uid, err := cache.NextFreeUid(1000, 1001); err == nil {
+		t.Fatalf("Should have failed but returned %d", uid)
+	}
+	if uid, err := cache.NextFreeUidRange(1002, 1005, 2); err == nil {
+		t.Fatalf("Should have failed but returned %d", uid)
+	}
+	if uid, err := cache.NextFreeUidRange(1004, 1004, 1); err != nil || uid != 1004 {
+		t.Fatalf("%d != 1004 (%v)", uid, err)
+	}
+}
+
+func TestNextFreeUidRangeRefreshes(t *testing.T) {
+	pwFile := filepath.Join(t.TempDir(), "passwd")
+	os.WriteFile(pwFile, []byte("root:x:0:0:root:/root:/bin/bash\n"), 0644)
+
+	cache := NewEtcPasswdCache(false, WithStatCheck())
+	if err := cache.LoadFromPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	// another writer takes 1000 behind the cache's back
+	other := NewEtcPasswdCache(false, WithoutLocking())
+	other.LoadFromPath(pwFile)
+	other.AddEntry(EtcPasswdEntry{username: "alice", uid: 1000, gid: 1000, homedir: "/home/alice", shell: "/bin/sh"})
+	if err := other.WriteToPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if uid, err := cache.NextFreeUidRange(1000, 2000, 1); err != nil || uid != 1001 {
+		t.Fatalf("%d != 1001 (%v)", uid, err)
+	}
+}
@@ -0,0 +1,98 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := make([]string, 0)
+	for _, c := range "abcdefghijklmnop" {
+		lines = append(lines, string(c))
+	}
+	old := strings.Join(lines, "\n") + "\n"
+	changed := append([]string(nil), lines...)
+	changed[1] = "B"
+	changed = append(changed[:12], changed[13:]...)
+	changed = append(changed, "q")
+	next := strings.Join(changed, "\n") + "\n"
+
+	expected := `--- a
++++ b
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,7 +10,7 @@
+ j
+ k
+ l
+-m
+ n
+ o
+ p
++q
+`
+	if diff := unifiedDiff("a", "b", old, next); diff != expected {
+		t.Fatalf("%q != %q", diff, expected)
+	}
+	if diff := unifiedDiff("a", "b", old, old); diff != "" {
+		t.Fatalf("expected no diff but got %q", diff)
+	}
+}
+
+func TestUnifiedDiffEmptySide(t *testing.T) {
+	expected := "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if diff := unifiedDiff("a", "b", "", "x\ny\n"); diff != expected {
+		t.Fatalf("%q != %q", diff, expected)
+	}
+	expected = "--- a\n+++ b\n@@ -1 +0,0 @@\n-x\n"
+	if diff := unifiedDiff("a", "b", "x\n", ""); diff != expected {
+		t.Fatalf("%q != %q", diff, expected)
+	}
+}
+
+func TestDiffLinesLargeContent(t *testing.T) {
+	old := make([]string, 20000)
+	next := make([]string, 0, len(old))
+	for i := range old {
+		old[i] = fmt.Sprintf("user%d:x:%d:%d::/home/user%d:/bin/sh", i, i, i, i)
+		if i%10 != 3 {
+			next = append(next, old[i])
+		}
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	ops := diffLines(old, next)
+	runtime.ReadMemStats(&after)
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 128<<20 {
+		t.Fatalf("diffing used %d MiB", alloc>>20)
+	}
+
+	kept, removed := 0, 0
+	a, b := make([]string, 0), make([]string, 0)
+	for _, op := range ops {
+		switch op.kind {
+		case ' ':
+			kept++
+			a, b = append(a, op.text), append(b, op.text)
+		case '-':
+			removed++
+			a = append(a, op.text)
+		case '+':
+			b = append(b, op.text)
+		}
+	}
+	if kept != len(next) || removed != len(old)-len(next) {
+		t.Fatalf("unexpected script with %d kept and %d removed", kept, removed)
+	}
+	if strings.Join(a, "\n") != strings.Join(old, "\n") || strings.Join(b, "\n") != strings.Join(next, "\n") {
+		t.Fatalf("the script does not turn the old content into the new content")
+	}
+}
@@ -0,0 +1,165 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+)
+
+const (
+	// DefaultHomeBase is the directory under which AddUser creates home directory paths
+	DefaultHomeBase = "/home"
+	// DefaultShell is the login shell given by AddUser when the spec does not name one
+	DefaultShell = "/bin/sh"
+	// DefaultSysUidMin is the lowest user id AddUser allocates for system accounts
+	DefaultSysUidMin = 101
+)
+
+// UserSpec describes a new account for AddUser. Only Username is required, every other field
+// has a useradd-like default.
+type UserSpec struct {
+	Username string
+	// Password defaults to "x" which refers to the shadow file
+	Password string
+	// Uid is allocated from Ranges when zero, so uid 0 cannot be requested through AddUser
+	Uid int
+	// Gid defaults to the user id when nil, matching the user private group scheme
+	Gid  *int
+	Info string
+	// Homedir defaults to HomeBase joined with the username
+	Homedir string
+	// HomeBase defaults to DefaultHomeBase
+	HomeBase string
+	// Shell defaults to DefaultShell
+	Shell string
+	// System allocates the uid between DefaultSysUidMin and Ranges.UidMin-1 instead of the
+	// regular account range
+	System bool
+	// Ranges defaults to DefaultAccountRanges, use LoginDefs.AccountRanges to follow login.defs
+	Ranges AccountRanges
+	// WriteBack saves the cache to the file it was loaded from after the entry is added. The
+	// file stays locked from the uid allocation until it is written
+	WriteBack bool
+}
+
+// AddUser validates the spec, fills in the defaults, allocates a uid if needed, and appends the
+// new entry to the cache. The username must be valid and not already in use, and an explicit
+// uid must not be taken.
+//
+// If WriteBack is set the file the cache was loaded from is locked with LockFile, unless
+// WithoutLocking was given, before the uid is allocated and until the cache has been written
+// to it, so that other processes cannot allocate the same uid in between. If the file changed
+// since it was loaded it is reloaded first, which loses changes to the cache that have not been
+// saved. The entry is removed from the cache again if writing fails.
+func (e *EtcPasswdCache) AddUser(spec UserSpec) (EtcPasswdEntry, error) {
+	if err := ValidateUsername(spec.Username); err != nil {
+		return EtcPasswdEntry{}, err
+	}
+	if spec.Password == "" {
+		spec.Password = "x"
+	}
+	if spec.HomeBase == "" {
+		spec.HomeBase = DefaultHomeBase
+	}
+	if spec.Homedir == "" {
+		spec.Homedir = path.Join(spec.HomeBase, spec.Username)
+	}
+	if spec.Shell == "" {
+		spec.Shell = DefaultShell
+	}
+	if spec.Ranges == (AccountRanges{}) {
+		spec.Ranges = DefaultAccountRanges()
+	}
+
+	if !spec.WriteBack {
+		return e.addUser(spec)
+	}
+	return e.addUserWriteBack(spec)
+}
+
+// addUserWriteBack implements AddUser with WriteBack, doing the uid allocation and the write
+// while holding the file lock.
+func (e *EtcPasswdCache) addUserWriteBack(spec UserSpec) (EtcPasswdEntry, error) {
+	e.mu.RLock()
+	path, loadedInfo := e.path, e.loadedInfo
+	e.mu.RUnlock()
+	if path == "" {
+		return EtcPasswdEntry{}, fmt.Errorf("Cache was not loaded from a path and cannot be saved")
+	}
+	if e.opts.dryRun {
+		return e.addUser(spec)
+	}
+	if !e.opts.noLocking {
+		lock, err := LockFile(path, e.opts.lockTimeout)
+		if err != nil {
+			return EtcPasswdEntry{}, err
+		}
+		defer lock.Unlock()
+	}
+	if info, err := os.Stat(path); err == nil && (loadedInfo == nil || fileChanged(loadedInfo, info)) {
+		if err := e.LoadFromPath(path); err != nil {
+			return EtcPasswdEntry{}, err
+		}
+	}
+
+	entry, err := e.addUser(spec)
+	if err != nil {
+		return EtcPasswdEntry{}, err
+	}
+	buf := new(bytes.Buffer)
+	if _, err = e.WriteTo(buf); err == nil {
+		err = writeFileAtomic(path, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		e.RemoveEntry(entry.username)
+		return EtcPasswdEntry{}, err
+	}
+	// the file now holds our content, so the next write back does not need to reload it
+	if info, err := os.Stat(path); err == nil {
+		e.mu.Lock()
+		e.loadedInfo = info
+		e.mu.Unlock()
+	}
+	return entry, nil
+}
+
+// addUser allocates the uid and adds the entry while holding the write lock so that concurrent
+// calls cannot be given the same uid.
+func (e *EtcPasswdCache) addUser(spec UserSpec) (EtcPasswdEntry, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.namemap == nil {
+		e.reset()
+	}
+	if _, ok := e.namemap[e.opts.nameKey(spec.Username)]; ok {
+		return EtcPasswdEntry{}, sentinelf(ErrDuplicateUser, "User with username '%s' already exists", spec.Username)
+	}
+	uid := spec.Uid
+	if uid == 0 {
+		min, max := spec.Ranges.UidMin, spec.Ranges.UidMax
+		if spec.System {
+			min, max = DefaultSysUidMin, spec.Ranges.UidMin-1
+		}
+		var err error
+		if uid, err = e.nextFreeUidRange(min, max, 1); err != nil {
+			return EtcPasswdEntry{}, err
+		}
+	} else if _, ok := e.idmap[uid]; ok {
+		return EtcPasswdEntry{}, sentinelf(ErrDuplicateUser, "User with uid %d already exists", uid)
+	}
+	gid := uid
+	if spec.Gid != nil {
+		gid = *spec.Gid
+	}
+
+	entry, err := NewEtcPasswdEntry(spec.Username, spec.Password, uid, gid, spec.Info, spec.Homedir, spec.Shell)
+	if err != nil {
+		return EtcPasswdEntry{}, err
+	}
+	if err := e.addUnparsedWithPolicy(entry); err != nil {
+		return EtcPasswdEntry{}, err
+	}
+	e.settleLastUidIndexEntry()
+	return entry, nil
+}
@@ -0,0 +1,121 @@
+package etcpwdparse
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestAddUser(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	alice, err := cache.AddUser(UserSpec{Username: "alice", Info: "Alice"})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if alice.String() != "alice:x:1000:1000:Alice:/home/alice:/bin/sh" {
+		t.Fatalf("unexpected entry %s", alice.String())
+	}
+	users := 100
+	bob, err := cache.AddUser(UserSpec{Username: "bob", Gid: &users, HomeBase: "/srv", Shell: "/bin/bash"})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if bob.String() != "bob:x:1001:100::/srv/bob:/bin/bash" {
+		t.Fatalf("unexpected entry %s", bob.String())
+	}
+	svc, err := cache.AddUser(UserSpec{Username: "svc", System: true, Shell: "/sbin/nologin"})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if svc.Uid() != DefaultSysUidMin {
+		t.Fatalf("%d != %d", svc.Uid(), DefaultSysUidMin)
+	}
+	if entry, ok := cache.LookupUserByUid(1001); !ok || entry.Username() != "bob" {
+		t.Fatalf("bob was not indexed")
+	}
+
+	if _, err := cache.AddUser(UserSpec{Username: "alice"}); err == nil {
+		t.Fatalf("Should have failed on duplicate username")
+	}
+	if _, err := cache.AddUser(UserSpec{Username: "carol", Uid: 1000}); err == nil {
+		t.Fatalf("Should have failed on duplicate uid")
+	}
+	if _, err := cache.AddUser(UserSpec{Username: "bad:name"}); err == nil {
+		t.Fatalf("Should have failed on invalid username")
+	}
+	rootGroup := 0
+	if wheel, err := cache.AddUser(UserSpec{Username: "wheel", Gid: &rootGroup}); err != nil || wheel.Gid() != 0 {
+		t.Fatalf("expected an explicit gid 0 to be kept: %d %v", wheel.Gid(), err)
+	}
+	if _, err := cache.AddUser(UserSpec{Username: "dave", Ranges: AccountRanges{UidMin: 1000, UidMax: 1001}}); err == nil {
+		t.Fatalf("Should have failed on exhausted range")
+	}
+}
+
+func TestAddUserWriteBack(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	ioutil.WriteFile(pwFile, []byte("# users\nroot:x:0:0:root:/root:/bin/bash\n"), 0644)
+
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, err := cache.AddUser(UserSpec{Username: "alice", WriteBack: true}); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	content, _ := ioutil.ReadFile(pwFile)
+	if string(content) != "# users\nroot:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/sh\n" {
+		t.Fatalf("unexpected content %q", string(content))
+	}
+
+	unsaved := NewEtcPasswdCache(false)
+	if _, err := unsaved.AddUser(UserSpec{Username: "alice", WriteBack: true}); err == nil {
+		t.Fatalf("Should have failed to save a cache without a path")
+	}
+	if _, ok := unsaved.LookupUserByName("alice"); ok {
+		t.Fatalf("alice should not have been kept after the failed save")
+	}
+
+	// a second cache loaded before the first write back reloads the file and picks a free uid
+	stale := NewEtcPasswdCache(false)
+	if err := stale.LoadFromPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, err := cache.AddUser(UserSpec{Username: "bob", WriteBack: true}); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	carol, err := stale.AddUser(UserSpec{Username: "carol", WriteBack: true})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if carol.Uid() != 1002 {
+		t.Fatalf("expected carol to get a uid not used by bob: %d", carol.Uid())
+	}
+	content, _ = ioutil.ReadFile(pwFile)
+	if !strings.Contains(string(content), "\nbob:x:1001:") || !strings.Contains(string(content), "\ncarol:x:1002:") {
+		t.Fatalf("unexpected content %q", string(content))
+	}
+
+	// the entry is removed again when the write fails
+	goneDir, _ := ioutil.TempDir("", "etc")
+	gone := NewEtcPasswdCache(false, WithoutLocking())
+	ioutil.WriteFile(path.Join(goneDir, "passwd"), []byte("root:x:0:0:root:/root:/bin/bash\n"), 0644)
+	if err := gone.LoadFromPath(path.Join(goneDir, "passwd")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	os.RemoveAll(goneDir)
+	if _, err := gone.AddUser(UserSpec{Username: "dave", WriteBack: true}); err == nil {
+		t.Fatalf("Should have failed to write to a missing directory")
+	}
+	if _, ok := gone.LookupUserByName("dave"); ok {
+		t.Fatalf("dave should not have been kept after the failed save")
+	}
+}
@@ -0,0 +1,135 @@
+package etcpwdparse
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net"
+	"path/filepath"
+)
+
+// DefaultUserdbSocket is the systemd-userdbd socket that merges the answers of every userdb
+// service on the host, including DynamicUser= units and systemd-homed.
+const DefaultUserdbSocket = "/run/systemd/userdb/io.systemd.Multiplexer"
+
+// userdbNoRecord is the Varlink error returned when no user matches the query.
+const userdbNoRecord = "io.systemd.UserDatabase.NoRecordFound"
+
+// UserdbSource is a UserSource that queries the io.systemd.UserDatabase Varlink interface, so
+// users that never appear in /etc/passwd can be resolved without cgo. Socket defaults to
+// DefaultUserdbSocket when empty. To merge these users with the file based entries, chain it
+// behind the cache with ChainSource{cache, &UserdbSource{}}.
+type UserdbSource struct {
+	Socket string
+}
+
+// call sends a GetUserRecord request with the given parameters and passes each returned record
+// to fn until it returns false. When more is set the service streams every matching record.
+func (s *UserdbSource) call(ctx context.Context, parameters map[string]interface{}, more bool, fn func(*EtcPasswdEntry) bool) error {
+	socket := s.Socket
+	if len(socket) == 0 {
+		socket = DefaultUserdbSocket
+	}
+	parameters["service"] = filepath.Base(socket)
+	request, err := json.Marshal(map[string]interface{}{
+		"method":     "io.systemd.UserDatabase.GetUserRecord",
+		"parameters": parameters,
+		"more":       more,
+	})
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// unblock the reads below when the context is done
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if _, err := conn.Write(append(request, 0)); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(conn)
+	for {
+		message, err := reader.ReadBytes(0)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		var reply struct {
+			Parameters struct {
+				Record UserRecord `json:"record"`
+			} `json:"parameters"`
+			Continues bool   `json:"continues"`
+			Error     string `json:"error"`
+		}
+		if err := json.Unmarshal(message[:len(message)-1], &reply); err != nil {
+			return fmt.Errorf("Userdb reply was badly formatted: %w", err)
+		}
+		if reply.Error == userdbNoRecord {
+			return nil
+		}
+		if len(reply.Error) > 0 {
+			return fmt.Errorf("Userdb query failed with %s", reply.Error)
+		}
+		// records that cannot be written as a passwd line are skipped
+		if entry, err := reply.Parameters.Record.Entry(); err == nil && !fn(&entry) {
+			return nil
+		}
+		if !reply.Continues {
+			return nil
+		}
+	}
+}
+
+// lookup returns the single record matching the parameters.
+func (s *UserdbSource) lookup(ctx context.Context, parameters map[string]interface{}) (*EtcPasswdEntry, bool, error) {
+	var result *EtcPasswdEntry
+	err := s.call(ctx, parameters, false, func(entry *EtcPasswdEntry) bool {
+		result = entry
+		return false
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result, result != nil, nil
+}
+
+// LookupByName returns the entry for the given username
+func (s *UserdbSource) LookupByName(name string) (*EtcPasswdEntry, bool) {
+	entry, ok, _ := s.LookupByNameContext(context.Background(), name)
+	return entry, ok
+}
+
+// LookupByNameContext is like LookupByName but gives up when the context is done. Errors
+// talking to the socket, such as on hosts without systemd-userdbd, are returned.
+func (s *UserdbSource) LookupByNameContext(ctx context.Context, name string) (*EtcPasswdEntry, bool, error) {
+	return s.lookup(ctx, map[string]interface{}{"userName": name})
+}
+
+// LookupByUid returns the entry for the given user id
+func (s *UserdbSource) LookupByUid(uid int) (*EtcPasswdEntry, bool) {
+	entry, ok, _ := s.LookupByUidContext(context.Background(), uid)
+	return entry, ok
+}
+
+// LookupByUidContext is like LookupByUid but gives up when the context is done.
+func (s *UserdbSource) LookupByUidContext(ctx context.Context, uid int) (*EtcPasswdEntry, bool, error) {
+	return s.lookup(ctx, map[string]interface{}{"uid": uid})
+}
+
+// All enumerates every user known to the userdb services. Nothing is returned if the socket
+// cannot be reached.
+func (s *UserdbSource) All() iter.Seq[*EtcPasswdEntry] {
+	return func(yield func(*EtcPasswdEntry) bool) {
+		s.call(context.Background(), map[string]interface{}{}, true, yield)
+	}
+}
@@ -0,0 +1,160 @@
+package etcpwdparse
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var fakeUserdbRecords = []string{
+	`{"userName":"alice","uid":60001,"gid":60001,"realName":"Alice","homeDirectory":"/home/alice","shell":"/bin/bash","disposition":"regular"}`,
+	`{"userName":"dynamic","uid":61234,"disposition":"dynamic"}`,
+}
+
+// serveFakeUserdb answers GetUserRecord queries on a unix socket from fakeUserdbRecords.
+func serveFakeUserdb(t *testing.T, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			message, err := bufio.NewReader(conn).ReadBytes(0)
+			if err != nil {
+				return
+			}
+			var request struct {
+				Method     string                 `json:"method"`
+				Parameters map[string]interface{} `json:"parameters"`
+				More       bool                   `json:"more"`
+			}
+			if err := json.Unmarshal(message[:len(message)-1], &request); err != nil {
+				t.Errorf("Should not have failed: %s", err)
+				return
+			}
+			if request.Method != "io.systemd.UserDatabase.GetUserRecord" || request.Parameters["service"] != "io.systemd.Multiplexer" {
+				conn.Write([]byte(`{"error":"org.varlink.service.MethodNotFound"}` + "\x00"))
+				return
+			}
+			matches := make([]string, 0)
+			for _, record := range fakeUserdbRecords {
+				var fields map[string]interface{}
+				json.Unmarshal([]byte(record), &fields)
+				if name, ok := request.Parameters["userName"]; ok && fields["userName"] != name {
+					continue
+				}
+				if uid, ok := request.Parameters["uid"]; ok && fields["uid"] != uid {
+					continue
+				}
+				matches = append(matches, record)
+			}
+			if len(matches) == 0 {
+				conn.Write([]byte(`{"error":"io.systemd.UserDatabase.NoRecordFound"}` + "\x00"))
+				return
+			}
+			for i, record := range matches {
+				continues := request.More && i < len(matches)-1
+				reply, _ := json.Marshal(map[string]interface{}{
+					"parameters": map[string]interface{}{"record": json.RawMessage(record), "incomplete": false},
+					"continues":  continues,
+				})
+				conn.Write(append(reply, 0))
+				if !continues {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func TestUserdbSource(t *testing.T) {
+	tempDir, _ := os.MkdirTemp("", "userdb")
+	defer os.RemoveAll(tempDir)
+	socket := filepath.Join(tempDir, "io.systemd.Multiplexer")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not available: %s", err)
+	}
+	defer listener.Close()
+	go serveFakeUserdb(t, listener)
+
+	source := &UserdbSource{Socket: socket}
+	alice, ok := source.LookupByName("alice")
+	if !ok {
+		t.Fatalf("alice should have been found")
+	}
+	if alice.Uid() != 60001 || alice.Info() != "Alice" || alice.Homedir() != "/home/alice" || alice.Shell() != "/bin/bash" || alice.Password() != "x" {
+		t.Fatalf("unexpected entry %+v", alice)
+	}
+	dynamic, ok := source.LookupByUid(61234)
+	if !ok {
+		t.Fatalf("dynamic should have been found")
+	}
+	if dynamic.Username() != "dynamic" || dynamic.Gid() != 61234 || dynamic.Homedir() != "/" {
+		t.Fatalf("unexpected entry %+v", dynamic)
+	}
+	if _, ok := source.LookupByName("nosuchuser"); ok {
+		t.Fatalf("nosuchuser should not have been found")
+	}
+
+	names := make([]string, 0)
+	for entry := range source.All() {
+		names = append(names, entry.Username())
+	}
+	if strings.Join(names, ",") != "alice,dynamic" {
+		t.Fatalf("%s != alice,dynamic", strings.Join(names, ","))
+	}
+
+	// merged with the file entries
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	chain := ChainSource{cache, source}
+	if _, ok := chain.LookupByName("root"); !ok {
+		t.Fatalf("root should have been found")
+	}
+	if _, ok := chain.LookupByName("dynamic"); !ok {
+		t.Fatalf("dynamic should have been found")
+	}
+
+	missing := &UserdbSource{Socket: filepath.Join(tempDir, "missing")}
+	if _, _, err := missing.LookupByNameContext(context.Background(), "alice"); err == nil {
+		t.Fatalf("Should have failed without a socket")
+	}
+}
+
+func TestUserdbSourceContext(t *testing.T) {
+	tempDir, _ := os.MkdirTemp("", "userdb")
+	defer os.RemoveAll(tempDir)
+	socket := filepath.Join(tempDir, "io.systemd.Multiplexer")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not available: %s", err)
+	}
+	defer listener.Close()
+	// accept connections but never answer
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err = (&UserdbSource{Socket: socket}).LookupByNameContext(ctx, "alice")
+	if err != context.DeadlineExceeded {
+		t.Fatalf("%v != %v", err, context.DeadlineExceeded)
+	}
+}
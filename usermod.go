@@ -0,0 +1,101 @@
+package etcpwdparse
+
+// DeleteUser removes every entry with the given username from the cache along with the lines
+// they were loaded from, like userdel. Call Save to persist the change to disk.
+func (e *EtcPasswdCache) DeleteUser(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.namemap[e.opts.nameKey(name)]; !ok {
+		return sentinelf(ErrUserNotFound, "No such user with username '%s'", name)
+	}
+	e.rebuildEntries(e.entries, func(entry *EtcPasswdEntry) bool {
+		return e.opts.nameKey(entry.username) == e.opts.nameKey(name)
+	}, false)
+	return nil
+}
+
+// RemoveEntry removes the entry that LookupUserByName returns for the given username, along with
+// the line it was loaded from, and returns it. Unlike DeleteUser any other entries with the same
+// username are kept, and the lookup indexes then return the next one in line. Call Save to
+// persist the change to disk.
+func (e *EtcPasswdCache) RemoveEntry(name string) (EtcPasswdEntry, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	target, ok := e.namemap[e.opts.nameKey(name)]
+	if !ok {
+		return EtcPasswdEntry{}, sentinelf(ErrUserNotFound, "No such user with username '%s'", name)
+	}
+	e.removeEntry(target)
+	return *target, nil
+}
+
+// RemoveByUid removes the entry that LookupUserByUid returns for the given user id, along with
+// the line it was loaded from, and returns it. Call Save to persist the change to disk.
+func (e *EtcPasswdCache) RemoveByUid(uid int) (EtcPasswdEntry, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	target, ok := e.idmap[uid]
+	if !ok {
+		return EtcPasswdEntry{}, sentinelf(ErrUserNotFound, "No such user with uid %d", uid)
+	}
+	e.removeEntry(target)
+	return *target, nil
+}
+
+// removeEntry drops a single entry and rebuilds the indexes. The caller must hold the write lock.
+func (e *EtcPasswdCache) removeEntry(target *EtcPasswdEntry) {
+	e.rebuildEntries(e.entries, func(entry *EtcPasswdEntry) bool {
+		return entry == target
+	}, false)
+}
+
+// ModifyUser applies changes to the entry returned by LookupUserByName for the given username,
+// like usermod. The modify function receives a builder holding the current fields, and the
+// result is validated before it replaces the entry in place. Renaming to a username or changing
+// to a uid that another entry already uses is rejected. Call Save to persist the change to disk.
+// The modify function runs without any cache lock held so it may use the lookup methods. If the
+// entry is changed by another goroutine in the meantime, modify is called again with the new
+// fields.
+func (e *EtcPasswdCache) ModifyUser(name string, modify func(b *EtcPasswdEntryBuilder)) (EtcPasswdEntry, error) {
+	for {
+		e.mu.RLock()
+		current, ok := e.namemap[e.opts.nameKey(name)]
+		e.mu.RUnlock()
+		if !ok {
+			return EtcPasswdEntry{}, sentinelf(ErrUserNotFound, "No such user with username '%s'", name)
+		}
+
+		builder := NewEtcPasswdEntryBuilderFrom(*current)
+		modify(builder)
+		updated, err := builder.Build()
+		if err != nil {
+			return EtcPasswdEntry{}, err
+		}
+
+		applied, err := e.applyModification(name, current, updated)
+		if err != nil {
+			return EtcPasswdEntry{}, err
+		} else if applied {
+			return updated, nil
+		}
+	}
+}
+
+// applyModification replaces current with updated unless the entry for the username is no longer
+// current, in which case false is returned so that the caller can start again. Entries are never
+// modified in place so comparing the pointers is enough.
+func (e *EtcPasswdCache) applyModification(name string, current *EtcPasswdEntry, updated EtcPasswdEntry) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.namemap[e.opts.nameKey(name)] != current {
+		return false, nil
+	}
+	if _, ok := e.namemap[e.opts.nameKey(updated.username)]; ok && e.opts.nameKey(updated.username) != e.opts.nameKey(current.username) {
+		return false, sentinelf(ErrDuplicateUser, "User with username '%s' already exists", updated.username)
+	}
+	if _, ok := e.idmap[updated.uid]; ok && updated.uid != current.uid {
+		return false, sentinelf(ErrDuplicateUser, "User with uid %d already exists", updated.uid)
+	}
+	e.replaceEntry(current, &updated)
+	return true, nil
+}
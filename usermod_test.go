@@ -0,0 +1,168 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeleteUser(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader("# users\nroot:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\nbob:x:1001:1001::/home/bob:/bin/sh\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := cache.DeleteUser("alice"); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, ok := cache.LookupUserByName("alice"); ok {
+		t.Fatalf("alice should have been deleted")
+	}
+	if _, ok := cache.LookupUserByUid(1000); ok {
+		t.Fatalf("uid 1000 should have been deleted")
+	}
+	if entry, ok := cache.LookupUserByUid(1001); !ok || entry.Username() != "bob" {
+		t.Fatalf("bob should still exist")
+	}
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	if buf.String() != "# users\nroot:x:0:0:root:/root:/bin/bash\nbob:x:1001:1001::/home/bob:/bin/sh\n" {
+		t.Fatalf("unexpected content %q", buf.String())
+	}
+	if err := cache.DeleteUser("alice"); err == nil {
+		t.Fatalf("Should have failed on a missing user")
+	}
+}
+
+func TestRemoveEntry(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\nbob:x:1001:1001::/home/bob:/bin/sh\nalice:x:1005:1005::/home/alice2:/bin/zsh\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	removed, err := cache.RemoveEntry("alice")
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if removed.Uid() != 1005 {
+		t.Fatalf("the last alice should have been removed, not %v", removed)
+	}
+	if entry, ok := cache.LookupUserByName("alice"); !ok || entry.Uid() != 1000 {
+		t.Fatalf("the first alice should now be returned: %v", entry)
+	}
+	if _, ok := cache.LookupUserByUid(1005); ok {
+		t.Fatalf("uid 1005 should have been removed")
+	}
+	if entries := cache.LookupUsersByGid(1005); len(entries) != 0 {
+		t.Fatalf("gid 1005 should have no entries: %v", entries)
+	}
+
+	removed, err = cache.RemoveByUid(1001)
+	if err != nil || removed.Username() != "bob" {
+		t.Fatalf("Should have removed bob: %v %v", removed, err)
+	}
+	if _, ok := cache.LookupUserByName("bob"); ok {
+		t.Fatalf("bob should have been removed")
+	}
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	expected := "root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\n"
+	if buf.String() != expected {
+		t.Fatalf("%q != %q", buf.String(), expected)
+	}
+
+	if _, err := cache.RemoveEntry("bob"); err == nil {
+		t.Fatalf("Should have failed to remove a missing user")
+	}
+	if _, err := cache.RemoveByUid(1001); err == nil {
+		t.Fatalf("Should have failed to remove a missing uid")
+	}
+}
+
+func TestModifyUser(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\nbob:x:1001:1001::/home/bob:/bin/sh\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	before := cache.snapshotEntries()
+
+	updated, err := cache.ModifyUser("alice", func(b *EtcPasswdEntryBuilder) {
+		b.Username("alicia").Uid(1002).Shell("/bin/zsh")
+	})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if updated.String() != "alicia:x:1002:1000::/home/alice:/bin/zsh" {
+		t.Fatalf("unexpected entry %s", updated.String())
+	}
+	if _, ok := cache.LookupUserByName("alice"); ok {
+		t.Fatalf("alice should have been renamed")
+	}
+	if entry, ok := cache.LookupUserByUid(1002); !ok || entry.Username() != "alicia" {
+		t.Fatalf("alicia should be indexed by uid")
+	}
+	if before[1].Username() != "alice" {
+		t.Fatalf("the previous snapshot should not have been modified")
+	}
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	if buf.String() != "root:x:0:0:root:/root:/bin/bash\nalicia:x:1002:1000::/home/alice:/bin/zsh\nbob:x:1001:1001::/home/bob:/bin/sh\n" {
+		t.Fatalf("unexpected content %q", buf.String())
+	}
+
+	if _, err := cache.ModifyUser("alicia", func(b *EtcPasswdEntryBuilder) { b.Username("bob") }); err == nil {
+		t.Fatalf("Should have failed on a duplicate username")
+	}
+	if _, err := cache.ModifyUser("alicia", func(b *EtcPasswdEntryBuilder) { b.Uid(1001) }); err == nil {
+		t.Fatalf("Should have failed on a duplicate uid")
+	}
+	if _, err := cache.ModifyUser("alicia", func(b *EtcPasswdEntryBuilder) { b.Shell("/bin/sh\n") }); err == nil {
+		t.Fatalf("Should have failed on an invalid field")
+	}
+	if _, err := cache.ModifyUser("nobody", func(b *EtcPasswdEntryBuilder) {}); err == nil {
+		t.Fatalf("Should have failed on a missing user")
+	}
+}
+
+func TestModifyUserCallbackCanUseLookups(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.ModifyUser("alice", func(b *EtcPasswdEntryBuilder) {
+			uid, _ := cache.NextFreeUid(1000, 2000)
+			if root, ok := cache.LookupUserByName("root"); ok {
+				b.Uid(uid).Shell(root.Shell())
+			}
+		})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("ModifyUser deadlocked when the callback used a lookup")
+	}
+	if entry, ok := cache.LookupUserByName("alice"); !ok || entry.Uid() != 1001 || entry.Shell() != "/bin/bash" {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+
+	// a change made while the callback runs is not overwritten, the callback sees it instead
+	calls := 0
+	updated, err := cache.ModifyUser("alice", func(b *EtcPasswdEntryBuilder) {
+		calls++
+		if calls == 1 {
+			cache.ModifyUser("alice", func(b *EtcPasswdEntryBuilder) { b.Homedir("/srv/alice") })
+		}
+		b.Shell("/bin/zsh")
+	})
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if calls != 2 || updated.Homedir() != "/srv/alice" || updated.Shell() != "/bin/zsh" {
+		t.Fatalf("unexpected entry %s after %d calls", updated.String(), calls)
+	}
+}
@@ -0,0 +1,66 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxUsernameLength is the longest username accepted by ValidateUsername, the limit used by
+// useradd and the utmp format.
+const MaxUsernameLength = 32
+
+// validUsernameRegex is the rule applied by shadow-utils is_valid_user_name: the POSIX
+// portable filename character set, not starting with a '-', with an optional trailing '$' for
+// Samba machine accounts.
+var validUsernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.][a-zA-Z0-9_.-]*\$?$`)
+
+// ValidateUsername returns an error if the name would be rejected by useradd. The name must be
+// 1 to MaxUsernameLength characters from the portable filename character set, must not start
+// with '-', and must not be entirely numeric or "." or ".." since those are ambiguous with
+// user ids and paths.
+func ValidateUsername(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("Username must not be empty")
+	}
+	if len(name) > MaxUsernameLength {
+		return fmt.Errorf("Username '%s' is longer than %d characters", name, MaxUsernameLength)
+	}
+	if !validUsernameRegex.MatchString(name) {
+		return fmt.Errorf("Username '%s' contains invalid characters", name)
+	}
+	if name == "." || name == ".." {
+		return fmt.Errorf("Username '%s' is not allowed", name)
+	}
+	if strings.Trim(name, "0123456789") == "" {
+		return fmt.Errorf("Username '%s' must not be entirely numeric", name)
+	}
+	return nil
+}
+
+// WithStrictUsernames makes loading reject entries whose username fails ValidateUsername. The
+// lines are treated like any other bad line, so they are skipped when bad lines are ignored.
+func WithStrictUsernames() Option {
+	return func(o *options) {
+		o.strictUsernames = true
+	}
+}
+
+// WithCaseInsensitiveNames makes the passwd and shadow caches match usernames regardless of case,
+// so that LookupUserByName("Alice") finds "alice", as some directory synced and legacy systems
+// expect. Entries keep the username as written in the file. Usernames that only differ by case
+// are then duplicates and handled as set by WithDuplicatePolicy.
+func WithCaseInsensitiveNames() Option {
+	return func(o *options) {
+		o.caseInsensitiveNames = true
+	}
+}
+
+// nameKey returns the key the username is indexed by.
+func (o *options) nameKey(name string) string {
+	name = NormalizeUsername(name, o.normalization)
+	if o.caseInsensitiveNames {
+		return strings.ToLower(name)
+	}
+	return name
+}
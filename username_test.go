@@ -0,0 +1,80 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateUsername(t *testing.T) {
+	for _, name := range []string{"root", "alice", "a.b", "_apt", "user-1", "Admin", "1password", "host$"} {
+		if err := ValidateUsername(name); err != nil {
+			t.Fatalf("%s should be valid: %s", name, err)
+		}
+	}
+	for _, name := range []string{"", "-bob", "a b", "a:b", "bob$x", "ünïcode", ".", "..", "1234", strings.Repeat("a", 33)} {
+		if err := ValidateUsername(name); err == nil {
+			t.Fatalf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestStrictUsernames(t *testing.T) {
+	content := "root:x:0:0:root:/root:/bin/bash\n1000:x:1000:1000::/home/x:/bin/sh\n"
+	if err := NewEtcPasswdCache(false).LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	err := NewEtcPasswdCache(false, WithStrictUsernames()).LoadFromReader(strings.NewReader(content))
+	pe, ok := err.(*ParseError)
+	if !ok || pe.LineNumber != 2 || pe.Field != "username" {
+		t.Fatalf("expected a username error on line 2, got %v", err)
+	}
+
+	cache := NewEtcPasswdCache(true, WithStrictUsernames())
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(cache.ListEntries()) != 1 {
+		t.Fatalf("%d != 1", len(cache.ListEntries()))
+	}
+}
+
+func TestCaseInsensitiveNames(t *testing.T) {
+	cache := NewEtcPasswdCache(false, WithCaseInsensitiveNames())
+	if err := cache.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\nAlice:x:1000:1000::/home/alice:/bin/bash\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	for _, name := range []string{"alice", "ALICE", "Alice"} {
+		entry, ok := cache.LookupUserByName(name)
+		if !ok || entry.Username() != "Alice" {
+			t.Fatalf("%s should have matched Alice", name)
+		}
+	}
+	if _, err := cache.AddUser(UserSpec{Username: "alice"}); err == nil {
+		t.Fatalf("Should have failed to add a user differing only by case")
+	}
+	if _, err := cache.ModifyUser("ALICE", func(b *EtcPasswdEntryBuilder) { b.Shell("/bin/zsh") }); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := cache.DeleteUser("aLiCe"); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, ok := cache.LookupUserByName("Alice"); ok {
+		t.Fatalf("Alice should have been deleted")
+	}
+
+	// without the option the case must match
+	exact := NewEtcPasswdCache(false)
+	exact.LoadFromReader(strings.NewReader("Alice:x:1000:1000::/home/alice:/bin/bash\n"))
+	if _, ok := exact.LookupUserByName("alice"); ok {
+		t.Fatalf("alice should not have matched Alice")
+	}
+
+	shadow := NewEtcShadowCache(false, WithCaseInsensitiveNames())
+	if err := shadow.LoadFromReader(strings.NewReader("Alice:$6$salt$hash:19000:0:99999:7:::\n")); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if _, ok := shadow.LookupUserByName("alice"); !ok {
+		t.Fatalf("alice should have matched the shadow entry of Alice")
+	}
+}
@@ -0,0 +1,125 @@
+package etcpwdparse
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// nobodyUid is the overflow user id that systemd treats as intrinsic like root.
+const nobodyUid = 65534
+
+// UserRecordPrivileged is the "privileged" section of a user record which holds the fields
+// normally only visible to root.
+type UserRecordPrivileged struct {
+	HashedPassword []string `json:"hashedPassword,omitempty"`
+}
+
+// UserRecord is a systemd JSON user record as used by systemd-homed and the userdb interface.
+// Only the fields that map onto a passwd entry are decoded; every other field is kept in Extra
+// so that a record survives a decode and encode round trip unchanged.
+type UserRecord struct {
+	UserName      string                `json:"userName"`
+	Uid           *int                  `json:"uid,omitempty"`
+	Gid           *int                  `json:"gid,omitempty"`
+	RealName      string                `json:"realName,omitempty"`
+	HomeDirectory string                `json:"homeDirectory,omitempty"`
+	Shell         string                `json:"shell,omitempty"`
+	Disposition   string                `json:"disposition,omitempty"`
+	Privileged    *UserRecordPrivileged `json:"privileged,omitempty"`
+	// Extra holds the fields of the record not listed above
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// userRecordFields is UserRecord without the custom JSON methods.
+type userRecordFields UserRecord
+
+// ParseUserRecord decodes a JSON user record. The userName field is required.
+func ParseUserRecord(data []byte) (UserRecord, error) {
+	var result UserRecord
+	if err := json.Unmarshal(data, &result); err != nil {
+		return UserRecord{}, err
+	}
+	if len(result.UserName) == 0 {
+		return UserRecord{}, fmt.Errorf("User record must have a userName")
+	}
+	return result, nil
+}
+
+// UnmarshalJSON decodes the known fields of the record and keeps the rest in Extra.
+func (r *UserRecord) UnmarshalJSON(data []byte) error {
+	var fields userRecordFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var extra map[string]json.RawMessage
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return err
+	}
+	for _, key := range []string{"userName", "uid", "gid", "realName", "homeDirectory", "shell", "disposition", "privileged"} {
+		delete(extra, key)
+	}
+	if len(extra) > 0 {
+		fields.Extra = extra
+	}
+	*r = UserRecord(fields)
+	return nil
+}
+
+// MarshalJSON encodes the record, including the fields kept in Extra.
+func (r UserRecord) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(userRecordFields(r))
+	if err != nil || len(r.Extra) == 0 {
+		return data, err
+	}
+	merged := make(map[string]json.RawMessage)
+	for key, value := range r.Extra {
+		merged[key] = value
+	}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merged)
+}
+
+// Entry converts the record into a passwd entry the way nss-systemd does: the password is "x",
+// the gid defaults to the uid, and the home directory defaults to "/". The record must have a
+// uid and the fields must be valid for a passwd line.
+func (r *UserRecord) Entry() (EtcPasswdEntry, error) {
+	if r.Uid == nil {
+		return EtcPasswdEntry{}, fmt.Errorf("User record for '%s' has no uid", r.UserName)
+	}
+	gid := *r.Uid
+	if r.Gid != nil {
+		gid = *r.Gid
+	}
+	homedir := r.HomeDirectory
+	if len(homedir) == 0 {
+		homedir = "/"
+	}
+	return NewEtcPasswdEntry(r.UserName, "x", *r.Uid, gid, r.RealName, homedir, r.Shell)
+}
+
+// UserRecord converts the entry into a systemd JSON user record. The disposition is derived
+// from the uid using the default account ranges, and a password hash held in the entry itself
+// is moved to the privileged section.
+func (e *EtcPasswdEntry) UserRecord() UserRecord {
+	uid, gid := e.uid, e.gid
+	result := UserRecord{
+		UserName:      e.username,
+		Uid:           &uid,
+		Gid:           &gid,
+		RealName:      e.info,
+		HomeDirectory: e.homedir,
+		Shell:         e.shell,
+		Disposition:   "regular",
+	}
+	if e.uid == 0 || e.uid == nobodyUid {
+		result.Disposition = "intrinsic"
+	} else if e.IsSystemAccount() {
+		result.Disposition = "system"
+	}
+	if ClassifyPassword(e.password) == PasswordHash {
+		result.Privileged = &UserRecordPrivileged{HashedPassword: []string{e.password}}
+	}
+	return result
+}
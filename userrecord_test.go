@@ -0,0 +1,102 @@
+package etcpwdparse
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const fakeUserRecord = `{
+	"userName": "alice",
+	"uid": 60001,
+	"gid": 60001,
+	"realName": "Alice Smith",
+	"homeDirectory": "/home/alice",
+	"shell": "/bin/zsh",
+	"disposition": "regular",
+	"storage": "luks",
+	"memberOf": ["wheel"],
+	"privileged": {"hashedPassword": ["$6$salt$hash"]}
+}`
+
+func TestUserRecord(t *testing.T) {
+	record, err := ParseUserRecord([]byte(fakeUserRecord))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if record.UserName != "alice" || *record.Uid != 60001 || record.Shell != "/bin/zsh" || record.Privileged.HashedPassword[0] != "$6$salt$hash" {
+		t.Fatalf("unexpected record %+v", record)
+	}
+	if len(record.Extra) != 2 || string(record.Extra["storage"]) != `"luks"` {
+		t.Fatalf("unexpected extra fields %v", record.Extra)
+	}
+
+	entry, err := record.Entry()
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry.Username() != "alice" || entry.Password() != "x" || entry.Gid() != 60001 || entry.Info() != "Alice Smith" || entry.Homedir() != "/home/alice" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	// the record survives a round trip
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	var original, encoded map[string]interface{}
+	json.Unmarshal([]byte(fakeUserRecord), &original)
+	json.Unmarshal(data, &encoded)
+	originalData, _ := json.Marshal(original)
+	encodedData, _ := json.Marshal(encoded)
+	if string(originalData) != string(encodedData) {
+		t.Fatalf("%s != %s", encodedData, originalData)
+	}
+
+	minimal, err := ParseUserRecord([]byte(`{"userName":"dynamic","uid":61234}`))
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	entry, _ = minimal.Entry()
+	if entry.Gid() != 61234 || entry.Homedir() != "/" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	if _, err := ParseUserRecord([]byte(`{"uid":1}`)); err == nil {
+		t.Fatalf("Should have failed without a userName")
+	}
+	noUid, _ := ParseUserRecord([]byte(`{"userName":"bob"}`))
+	if _, err := noUid.Entry(); err == nil {
+		t.Fatalf("Should have failed without a uid")
+	}
+}
+
+func TestEntryUserRecord(t *testing.T) {
+	cases := map[string]string{
+		"root:x:0:0:root:/root:/bin/bash":                          "intrinsic",
+		"nobody:x:65534:65534:Nobody:/:/sbin/nologin":              "intrinsic",
+		"daemon:x:2:2:daemon:/sbin:/sbin/nologin":                  "system",
+		"alice:$6$salt$hash:1000:1000:Alice:/home/alice:/bin/bash": "regular",
+	}
+	for line, disposition := range cases {
+		entry, _ := ParsePasswdLine(line)
+		record := entry.UserRecord()
+		if record.Disposition != disposition {
+			t.Fatalf("%s != %s for %s", record.Disposition, disposition, line)
+		}
+		back, err := record.Entry()
+		if err != nil {
+			t.Fatalf("Should not have failed: %s", err)
+		}
+		if back.Username() != entry.Username() || back.Uid() != entry.Uid() || back.Homedir() != entry.Homedir() || back.Shell() != entry.Shell() {
+			t.Fatalf("unexpected entry %+v", back)
+		}
+	}
+	entry, _ := ParsePasswdLine("alice:$6$salt$hash:1000:1000:Alice:/home/alice:/bin/bash")
+	if record := entry.UserRecord(); record.Privileged == nil || record.Privileged.HashedPassword[0] != "$6$salt$hash" {
+		t.Fatalf("the password hash should have been kept")
+	}
+	entry, _ = ParsePasswdLine("bob:x:1001:1001:Bob:/home/bob:/bin/bash")
+	if record := entry.UserRecord(); record.Privileged != nil {
+		t.Fatalf("a shadowed password should not be kept")
+	}
+}
@@ -0,0 +1,73 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseSpecID parses a numeric user or group from a user spec.
+func parseSpecID(value string) (int, bool, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, nil
+	}
+	if id < 0 || id > MaxID {
+		return 0, false, fmt.Errorf("Id %s in user spec is out of range", value)
+	}
+	return int(id), true, nil
+}
+
+// ResolveUserSpec resolves a Docker style user spec, as used by USER and runAsUser, of the form
+// "user", "user:group", "uid", or "uid:gid" into ids, the same way container runtimes do.
+//
+// The user is matched by name or by uid against the passwd cache. A number that is not in the
+// file is used as it is, while an unknown name is an error. When no group is given the primary
+// group of the user is used, or 0 for a user that is not in the file. The group is matched by
+// name or gid against the group cache in the same way. An empty spec means root.
+func (a *OsUserAdapter) ResolveUserSpec(spec string) (int, int, error) {
+	userPart, groupPart, hasGroup := strings.Cut(spec, ":")
+	if len(userPart) == 0 {
+		userPart = "0"
+	}
+
+	uid, gid := 0, 0
+	numeric, isNumber, err := parseSpecID(userPart)
+	if err != nil {
+		return 0, 0, err
+	}
+	var found *EtcPasswdEntry
+	for _, entry := range a.Passwd.snapshotEntries() {
+		if entry.username == userPart || (isNumber && entry.uid == numeric) {
+			found = entry
+			break
+		}
+	}
+	switch {
+	case found != nil:
+		uid, gid = found.uid, found.gid
+	case isNumber:
+		uid = numeric
+	default:
+		return 0, 0, sentinelf(ErrUserNotFound, "No such user with username '%s'", userPart)
+	}
+
+	if !hasGroup || len(groupPart) == 0 {
+		return uid, gid, nil
+	}
+	numeric, isNumber, err = parseSpecID(groupPart)
+	if err != nil {
+		return 0, 0, err
+	}
+	if a.Groups != nil {
+		for _, group := range a.Groups.entries {
+			if group.name == groupPart || (isNumber && group.gid == numeric) {
+				return uid, group.gid, nil
+			}
+		}
+	}
+	if !isNumber {
+		return 0, 0, sentinelf(ErrGroupNotFound, "No such group with name '%s'", groupPart)
+	}
+	return uid, numeric, nil
+}
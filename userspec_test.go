@@ -0,0 +1,60 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveUserSpec(t *testing.T) {
+	passwd := NewEtcPasswdCache(false)
+	if err := passwd.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	groups := NewEtcGroupCache(false)
+	if err := groups.LoadFromReader(strings.NewReader(fakeGroupContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	adapter := NewOsUserAdapter(passwd, groups)
+
+	cases := []struct {
+		spec string
+		uid  int
+		gid  int
+	}{
+		{"", 0, 0},
+		{"ftp", 14, 50},
+		{"14", 14, 50},
+		{"ftp:wheel", 14, 10},
+		{"ftp:10", 14, 10},
+		{"ftp:4242", 14, 4242},
+		{"4242", 4242, 0},
+		{"4242:4343", 4242, 4343},
+		{"4242:users", 4242, 100},
+		{":daemon", 0, 2},
+		{"mail:", 8, 12},
+	}
+	for _, c := range cases {
+		uid, gid, err := adapter.ResolveUserSpec(c.spec)
+		if err != nil {
+			t.Fatalf("Should not have failed for %q: %s", c.spec, err)
+		}
+		if uid != c.uid || gid != c.gid {
+			t.Fatalf("%q gave %d:%d not %d:%d", c.spec, uid, gid, c.uid, c.gid)
+		}
+	}
+
+	for _, spec := range []string{"nosuchuser", "ftp:nosuchgroup", "-1", "ftp:-5", "99999999999"} {
+		if _, _, err := adapter.ResolveUserSpec(spec); err == nil {
+			t.Fatalf("Should have failed for %q", spec)
+		}
+	}
+
+	// without a group cache only numeric groups resolve
+	noGroups := NewOsUserAdapter(passwd, nil)
+	if _, gid, err := noGroups.ResolveUserSpec("ftp:77"); err != nil || gid != 77 {
+		t.Fatalf("ftp:77 should have resolved: %v", err)
+	}
+	if _, _, err := noGroups.ResolveUserSpec("ftp:wheel"); err == nil {
+		t.Fatalf("Should have failed without a group cache")
+	}
+}
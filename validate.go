@@ -0,0 +1,122 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FindingKind identifies the type of problem reported by Validate.
+type FindingKind string
+
+const (
+	// FindingDuplicateUsername is reported for every entry after the first with a given username
+	FindingDuplicateUsername FindingKind = "duplicate-username"
+	// FindingDuplicateUid is reported for every entry after the first with a given user id
+	FindingDuplicateUid FindingKind = "duplicate-uid"
+	// FindingEmptyField is reported when a required field is empty
+	FindingEmptyField FindingKind = "empty-field"
+	// FindingRelativeHomedir is reported when the home directory is not an absolute path
+	FindingRelativeHomedir FindingKind = "relative-homedir"
+	// FindingMissingShell is reported when the shell is not an absolute path. An empty shell is
+	// not reported since login uses /bin/sh for it
+	FindingMissingShell FindingKind = "missing-shell"
+	// FindingBadUsername is reported when the username contains characters that tools reject
+	FindingBadUsername FindingKind = "bad-username"
+	// FindingNormalizedUsername is reported for every entry after the first whose username is
+	// encoded differently from an earlier one but is the same after NFKC normalization
+	FindingNormalizedUsername FindingKind = "normalized-username"
+	// FindingFieldWhitespace is reported when a field starts or ends with whitespace, which can
+	// only be loaded with WithVerbatimFields
+	FindingFieldWhitespace FindingKind = "field-whitespace"
+)
+
+// Finding is a single problem reported by Validate.
+type Finding struct {
+	Kind FindingKind
+	// LineNumber is the 1-based line the entry was loaded from, or 0 for an entry added since
+	LineNumber int
+	Username   string
+	Message    string
+}
+
+// String formats the finding in the style of pwck output
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: user '%s': %s", f.LineNumber, f.Username, f.Message)
+}
+
+// Validate runs pwck-style consistency checks over the entries and returns the problems found
+// in file order. An empty result means the content is consistent.
+func (e *EtcPasswdCache) Validate() []Finding {
+	e.refresh()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	findings := make([]Finding, 0)
+	seenNames := make(map[string]int)
+	seenUids := make(map[int]int)
+	seenNormalized := make(map[string]*EtcPasswdEntry)
+	normalizedLines := make(map[string]int)
+	for _, l := range e.lines {
+		if l.entry < 0 {
+			continue
+		}
+		entry := e.entries[l.entry]
+		lineNumber := l.number
+		add := func(kind FindingKind, format string, args ...interface{}) {
+			findings = append(findings, Finding{
+				Kind:       kind,
+				LineNumber: lineNumber,
+				Username:   entry.username,
+				Message:    fmt.Sprintf(format, args...),
+			})
+		}
+
+		if len(entry.username) == 0 {
+			add(FindingEmptyField, "empty username")
+		} else if err := ValidateUsername(entry.username); err != nil {
+			add(FindingBadUsername, "invalid user name: %s", err)
+		}
+		if first, ok := seenNames[entry.username]; ok && len(entry.username) > 0 {
+			add(FindingDuplicateUsername, "duplicate username, first seen on line %d", first)
+		} else {
+			seenNames[entry.username] = lineNumber
+		}
+		key := e.opts.normalizedCollisionKey(entry.username)
+		if first, ok := seenNormalized[key]; ok && first.username != entry.username {
+			add(FindingNormalizedUsername, "username normalizes to the same name as '%s' on line %d", first.username, normalizedLines[key])
+		} else if !ok {
+			seenNormalized[key], normalizedLines[key] = entry, lineNumber
+		}
+		if first, ok := seenUids[entry.uid]; ok {
+			add(FindingDuplicateUid, "duplicate uid %d, first seen on line %d", entry.uid, first)
+		} else {
+			seenUids[entry.uid] = lineNumber
+		}
+
+		fields := []struct {
+			name  string
+			value string
+		}{
+			{"username", entry.username},
+			{"password", entry.password},
+			{"info", entry.info},
+			{"home directory", entry.homedir},
+			{"login shell", entry.shell},
+		}
+		for _, f := range fields {
+			if strings.TrimSpace(f.value) != f.value {
+				add(FindingFieldWhitespace, "%s has leading or trailing whitespace", f.name)
+			}
+		}
+
+		if len(entry.homedir) == 0 {
+			add(FindingEmptyField, "empty home directory")
+		} else if !strings.HasPrefix(entry.homedir, "/") {
+			add(FindingRelativeHomedir, "home directory '%s' is not an absolute path", entry.homedir)
+		}
+		// an empty shell means /bin/sh to login and pwck
+		if len(entry.shell) > 0 && !strings.HasPrefix(entry.shell, "/") {
+			add(FindingMissingShell, "login shell '%s' is not an absolute path", entry.shell)
+		}
+	}
+	return findings
+}
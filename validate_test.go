@@ -0,0 +1,80 @@
+package etcpwdparse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if findings := cache.Validate(); len(findings) != 0 {
+		t.Fatalf("Should have had no findings: %v", findings)
+	}
+
+	content := `# header
+root:x:0:0:root:/root:/bin/bash
+root:x:1:1:root:/root:/bin/bash
+toor:x:0:0::/root:/bin/bash
+bad user:x:2:2::relative:/bin/sh
+nohome:x:3:3:::
+`
+	if err := cache.LoadFromReader(strings.NewReader(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	findings := cache.Validate()
+	expected := []struct {
+		kind FindingKind
+		line int
+	}{
+		{FindingDuplicateUsername, 3},
+		{FindingDuplicateUid, 4},
+		{FindingBadUsername, 5},
+		{FindingRelativeHomedir, 5},
+		{FindingEmptyField, 6},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("%d != %d: %v", len(findings), len(expected), findings)
+	}
+	for i, f := range findings {
+		if f.Kind != expected[i].kind || f.LineNumber != expected[i].line {
+			t.Fatalf("finding %d was %s on line %d", i, f.Kind, f.LineNumber)
+		}
+	}
+	if findings[1].String() != "line 4: user 'toor': duplicate uid 0, first seen on line 2" {
+		t.Fatalf("unexpected message %s", findings[1].String())
+	}
+}
+
+func TestValidateLineNumbersAndRefresh(t *testing.T) {
+	pwFile := filepath.Join(t.TempDir(), "passwd")
+	os.WriteFile(pwFile, []byte("# header\nroot:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:bin/sh\n"), 0644)
+
+	cache := NewEtcPasswdCache(false, WithStatCheck())
+	if err := cache.LoadFromPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	cache.DeleteUser("root")
+	cache.AddEntry(EtcPasswdEntry{username: "bob", uid: 1001, gid: 1001, homedir: "bob"})
+	findings := cache.Validate()
+	if len(findings) != 2 || findings[0].LineNumber != 3 || findings[0].Kind != FindingMissingShell {
+		t.Fatalf("alice should be reported on the line she was loaded from: %v", findings)
+	}
+	if findings[1].LineNumber != 0 || findings[1].Kind != FindingRelativeHomedir {
+		t.Fatalf("bob was not loaded from a line: %v", findings)
+	}
+
+	// a stat checked cache validates the current file
+	other := NewEtcPasswdCache(false, WithoutLocking())
+	other.LoadFromReader(strings.NewReader("root:x:0:0:root:/root:/bin/bash\n"))
+	if err := other.WriteToPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if findings := cache.Validate(); len(findings) != 0 {
+		t.Fatalf("the changed file should have been validated: %v", findings)
+	}
+}
@@ -0,0 +1,32 @@
+package etcpwdparse
+
+import (
+	"strings"
+)
+
+// WithVerbatimFields stops the passwd parser from trimming the whitespace around each field and
+// line, so that values such as a GECOS field with trailing spaces or a shell padded with blanks
+// are kept exactly as found and written back unchanged. Only the "\r" of a CRLF line ending is
+// removed. Validate reports fields with leading or trailing whitespace as FindingFieldWhitespace,
+// since most tools would not match them the way they look.
+func WithVerbatimFields() Option {
+	return func(o *options) {
+		o.verbatim = true
+	}
+}
+
+// trimLine prepares a line for splitting into fields.
+func (o *options) trimLine(line string) string {
+	if o.verbatim {
+		return strings.TrimSuffix(line, "\r")
+	}
+	return strings.TrimSpace(line)
+}
+
+// trimField returns the value of a text field as it is stored in the entry.
+func (o *options) trimField(value string) string {
+	if o.verbatim {
+		return value
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,59 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const fakeVerbatimContent = "root:x:0:0:root:/root:/bin/bash\r\nalice:x:1000:1000:Alice Smith  :/home/alice: /bin/bash \n"
+
+func TestVerbatimFields(t *testing.T) {
+	cache := NewEtcPasswdCache(false, WithVerbatimFields())
+	if err := cache.LoadFromReader(strings.NewReader(fakeVerbatimContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	entry, _ := cache.LookupUserByName("alice")
+	if entry.Info() != "Alice Smith  " || entry.Shell() != " /bin/bash " {
+		t.Fatalf("fields should be kept as found: %q %q", entry.Info(), entry.Shell())
+	}
+	if root, _ := cache.LookupUserByName("root"); root.Shell() != "/bin/bash" {
+		t.Fatalf("the CRLF line ending should be removed: %q", root.Shell())
+	}
+
+	findings := make([]string, 0)
+	for _, f := range cache.Validate() {
+		if f.Kind == FindingFieldWhitespace {
+			findings = append(findings, f.String())
+		}
+	}
+	expected := []string{
+		"line 2: user 'alice': info has leading or trailing whitespace",
+		"line 2: user 'alice': login shell has leading or trailing whitespace",
+	}
+	if strings.Join(findings, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("%v != %v", findings, expected)
+	}
+
+	// changed entries are written with their fields untouched
+	cache.ModifyUser("alice", func(b *EtcPasswdEntryBuilder) { b.Homedir("/home/asmith") })
+	buf := new(bytes.Buffer)
+	cache.WriteTo(buf)
+	if !strings.Contains(buf.String(), "alice:x:1000:1000:Alice Smith  :/home/asmith: /bin/bash \n") {
+		t.Fatalf("unexpected content %q", buf.String())
+	}
+
+	// by default the fields are trimmed and nothing is reported
+	trimmed := NewEtcPasswdCache(false)
+	if err := trimmed.LoadFromReader(strings.NewReader(fakeVerbatimContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if entry, _ := trimmed.LookupUserByName("alice"); entry.Shell() != "/bin/bash" {
+		t.Fatalf("%q != /bin/bash", entry.Shell())
+	}
+	for _, f := range trimmed.Validate() {
+		if f.Kind == FindingFieldWhitespace {
+			t.Fatalf("unexpected finding %s", f)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package etcpwdparse
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultWatchInterval is the interval used by StartWatching when no positive interval is given.
+const DefaultWatchInterval = 2 * time.Second
+
+// fileChanged returns true if the file described by current is different to the one described
+// by previous. Comparing the file identity as well as the size and modification time catches
+// both in-place edits and the atomic rename that useradd and friends perform.
+func fileChanged(previous, current os.FileInfo) bool {
+	return !os.SameFile(previous, current) ||
+		!previous.ModTime().Equal(current.ModTime()) ||
+		previous.Size() != current.Size()
+}
+
+// StartWatching starts a background goroutine that checks the file the cache was loaded from
+// every interval and reloads the cache when the file changes. The file is checked with stat
+// rather than inotify so that it behaves the same on every operating system. If a reload fails
+// the existing content is kept and the reload is retried at the next interval.
+func (e *EtcPasswdCache) StartWatching(interval time.Duration) error {
+	e.watchMu.Lock()
+	defer e.watchMu.Unlock()
+	if e.watchStop != nil {
+		return fmt.Errorf("Cache is already being watched")
+	}
+
+	e.mu.RLock()
+	path := e.path
+	e.mu.RUnlock()
+	if path == "" {
+		return fmt.Errorf("Cache was not loaded from a path and cannot be watched")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	e.watchStop = make(chan struct{})
+	e.watchDone = make(chan struct{})
+	go e.watchLoop(path, info, interval, e.watchStop, e.watchDone)
+	return nil
+}
+
+// StopWatching stops the background goroutine started by StartWatching and waits for it to
+// exit. It does nothing if the cache is not being watched.
+func (e *EtcPasswdCache) StopWatching() {
+	e.watchMu.Lock()
+	defer e.watchMu.Unlock()
+	if e.watchStop == nil {
+		return
+	}
+	close(e.watchStop)
+	<-e.watchDone
+	e.watchStop = nil
+	e.watchDone = nil
+}
+
+// watchLoop polls the file at path until stop is closed.
+func (e *EtcPasswdCache) watchLoop(path string, last os.FileInfo, interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil || !fileChanged(last, info) {
+				continue
+			}
+			if err := e.LoadFromPath(path); err == nil {
+				last = info
+			}
+		}
+	}
+}
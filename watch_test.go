@@ -0,0 +1,61 @@
+package etcpwdparse
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatching(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", "etc")
+	defer os.RemoveAll(tempDir)
+	pwFile := path.Join(tempDir, "passwd")
+	if err := ioutil.WriteFile(pwFile, []byte(fakePwdContent), 0644); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+
+	cache := NewEtcPasswdCache(false)
+	if err := cache.StartWatching(time.Millisecond); err == nil {
+		t.Fatalf("Should have failed to watch an unloaded cache")
+	}
+	if err := cache.LoadFromPath(pwFile); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if err := cache.StartWatching(5 * time.Millisecond); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	defer cache.StopWatching()
+	if err := cache.StartWatching(5 * time.Millisecond); err == nil {
+		t.Fatalf("Should have failed to watch twice")
+	}
+
+	// a broken file should not replace the existing content
+	if err := ioutil.WriteFile(pwFile, []byte("broken\n"), 0644); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := cache.LookupUserByName("root"); !ok {
+		t.Fatalf("root should still be found after a failed reload")
+	}
+
+	content := strings.Replace(fakePwdContent, "root:x:0:0", "admin:x:0:0", 1)
+	if err := ioutil.WriteFile(pwFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := cache.LookupUserByName("admin"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cache was not reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cache.StopWatching()
+	cache.StopWatching()
+}
@@ -0,0 +1,162 @@
+package etcpwdparse
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The YAML support here deliberately covers only the shape of document that ToYAML produces: a
+// block sequence of mappings with one scalar value per key. Plain, single-quoted, and
+// double-quoted scalars and comments are understood so that hand-written files work too, but
+// anchors, flow collections, and multi-line scalars are not. This keeps the package free of
+// third party dependencies.
+
+// ToYAML encodes all the entries in the cache as a YAML sequence of mappings in file order.
+func (e *EtcPasswdCache) ToYAML() ([]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	buf := new(bytes.Buffer)
+	if len(e.entries) == 0 {
+		buf.WriteString("[]\n")
+		return buf.Bytes(), nil
+	}
+	for _, entry := range e.entries {
+		values := []string{
+			strconv.Quote(entry.username),
+			strconv.Quote(entry.password),
+			strconv.Itoa(entry.uid),
+			strconv.Itoa(entry.gid),
+			strconv.Quote(entry.info),
+			strconv.Quote(entry.homedir),
+			strconv.Quote(entry.shell),
+		}
+		for j, key := range entryFieldNames {
+			prefix := "  "
+			if j == 0 {
+				prefix = "- "
+			}
+			fmt.Fprintf(buf, "%s%s: %s\n", prefix, key, values[j])
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// FromYAML replaces the cached content with the entries decoded from a YAML document as produced
+// by ToYAML. Each entry is validated in the same way as NewEtcPasswdEntry. The existing content
+// is left untouched if decoding fails.
+func (e *EtcPasswdCache) FromYAML(data []byte) error {
+	records, err := parseYAMLRecords(string(data))
+	if err != nil {
+		return err
+	}
+	next := e.newLoadTarget("")
+	for _, record := range records {
+		entry, err := entryFromYAMLRecord(record)
+		if err != nil {
+			return err
+		}
+		if err := next.addWithPolicy(entry, ""); err != nil {
+			return err
+		}
+	}
+	e.replaceContent(next)
+	return nil
+}
+
+// yamlRecord is a single decoded mapping along with the line it started on.
+type yamlRecord struct {
+	line   int
+	values map[string]string
+}
+
+// parseYAMLRecords parses a block sequence of flat mappings.
+func parseYAMLRecords(content string) ([]yamlRecord, error) {
+	records := make([]yamlRecord, 0)
+	var current *yamlRecord
+	for i, raw := range strings.Split(content, "\n") {
+		lineNumber := i + 1
+		line := strings.TrimRight(raw, " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") || trimmed == "---" || trimmed == "[]" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			records = append(records, yamlRecord{line: lineNumber, values: make(map[string]string)})
+			current = &records[len(records)-1]
+			trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+			if len(trimmed) == 0 {
+				continue
+			}
+		} else if current == nil || !strings.HasPrefix(line, " ") {
+			return nil, fmt.Errorf("YAML line %d was not part of a sequence item", lineNumber)
+		}
+		parts := strings.SplitN(trimmed, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("YAML line %d was not a key: value pair", lineNumber)
+		}
+		key := strings.TrimSpace(parts[0])
+		value, err := parseYAMLScalar(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("YAML line %d had a bad value: %w", lineNumber, err)
+		}
+		if _, ok := current.values[key]; ok {
+			return nil, fmt.Errorf("YAML line %d repeated key %s", lineNumber, key)
+		}
+		current.values[key] = value
+	}
+	return records, nil
+}
+
+// parseYAMLScalar decodes a plain, single-quoted, or double-quoted scalar.
+func parseYAMLScalar(value string) (string, error) {
+	if strings.HasPrefix(value, "\"") {
+		return strconv.Unquote(value)
+	}
+	if strings.HasPrefix(value, "'") {
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("unterminated single quoted string %s", value)
+		}
+		return strings.Replace(value[1:len(value)-1], "''", "'", -1), nil
+	}
+	// plain scalars may be followed by a comment
+	if idx := strings.Index(value, " #"); idx >= 0 {
+		value = strings.TrimSpace(value[:idx])
+	}
+	if value == "~" || value == "null" {
+		return "", nil
+	}
+	return value, nil
+}
+
+// entryFromYAMLRecord converts a decoded mapping into a validated entry.
+func entryFromYAMLRecord(record yamlRecord) (EtcPasswdEntry, error) {
+	for key := range record.values {
+		known := false
+		for _, k := range entryFieldNames {
+			known = known || k == key
+		}
+		if !known {
+			return EtcPasswdEntry{}, fmt.Errorf("YAML item at line %d had unknown key %s", record.line, key)
+		}
+	}
+	ids := make([]int, 2)
+	for i, key := range []string{"uid", "gid"} {
+		value, ok := record.values[key]
+		if !ok {
+			return EtcPasswdEntry{}, fmt.Errorf("YAML item at line %d was missing %s", record.line, key)
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return EtcPasswdEntry{}, fmt.Errorf("YAML item at line %d had badly formatted %s %s", record.line, key, value)
+		}
+		ids[i] = id
+	}
+	v := record.values
+	entry, err := NewEtcPasswdEntry(v["username"], v["password"], ids[0], ids[1], v["info"], v["homedir"], v["shell"])
+	if err != nil {
+		return EtcPasswdEntry{}, fmt.Errorf("YAML item at line %d was invalid: %w", record.line, err)
+	}
+	return entry, nil
+}
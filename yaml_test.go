@@ -0,0 +1,73 @@
+package etcpwdparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCacheYAML(t *testing.T) {
+	cache := NewEtcPasswdCache(false)
+	if err := cache.LoadFromReader(strings.NewReader(fakePwdContent)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	data, err := cache.ToYAML()
+	if err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if !strings.HasPrefix(string(data), "- username: \"root\"\n  password: \"x\"\n  uid: 0\n") {
+		t.Fatalf("unexpected yaml %s", string(data))
+	}
+
+	other := NewEtcPasswdCache(false)
+	if err := other.FromYAML(data); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	if len(other.ListEntries()) != 13 {
+		t.Fatalf("%d != 13", len(other.ListEntries()))
+	}
+	ftp, _ := other.LookupUserByName("ftp")
+	if ftp.Info() != "FTP User" || ftp.Gid() != 50 {
+		t.Fatalf("ftp was decoded incorrectly: %+v", ftp)
+	}
+}
+
+func TestFromYAMLHandWritten(t *testing.T) {
+	content := `
+# users for the web tier
+- username: web
+  password: x
+  uid: 1500   # fixed uid
+  gid: 1500
+  info: 'Web ''Server'''
+  homedir: /srv/web
+  shell: /usr/sbin/nologin
+-
+  username: deploy
+  uid: 1501
+  gid: 1500
+`
+	cache := NewEtcPasswdCache(false)
+	if err := cache.FromYAML([]byte(content)); err != nil {
+		t.Fatalf("Should not have failed: %s", err)
+	}
+	web, _ := cache.LookupUserByName("web")
+	if web.Uid() != 1500 || web.Info() != "Web 'Server'" {
+		t.Fatalf("web was decoded incorrectly: %+v", web)
+	}
+	deploy, _ := cache.LookupUserByName("deploy")
+	if deploy.Shell() != "" || deploy.Gid() != 1500 {
+		t.Fatalf("deploy was decoded incorrectly: %+v", deploy)
+	}
+
+	bad := []string{
+		"username: web\n",
+		"- username: web\n  uid: x\n  gid: 1\n",
+		"- username: web\n  gid: 1\n",
+		"- username: web\n  uid: 1\n  gid: 1\n  colour: red\n",
+	}
+	for _, b := range bad {
+		if err := cache.FromYAML([]byte(b)); err == nil {
+			t.Fatalf("Should have failed on %q", b)
+		}
+	}
+}
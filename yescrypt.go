@@ -0,0 +1,501 @@
+package etcpwdparse
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// The yescrypt flags and pwxform settings below are those of the "j" flavour, the only one
+// produced by libxcrypt and the only one the reference implementation accepts for "$y$" hashes.
+const (
+	yescryptRW       = 0x002
+	yescryptDefaults = 0x0b6
+	yescryptPrehash  = 0x10000000
+	yescryptHashLen  = 32
+	yescryptMaxSalt  = 64
+	// yescryptMaxMemory bounds the memory a hash can ask for, far above any system default
+	yescryptMaxMemory = 1 << 30
+
+	pwxSimple = 2
+	pwxGather = 4
+	pwxRounds = 6
+	sWidth    = 8
+	pwxBytes  = pwxGather * pwxSimple * 8
+	pwxWords  = pwxBytes / 4
+	sBytes    = 3 * (1 << sWidth) * pwxSimple * 8
+	sMask     = ((1 << sWidth) - 1) * pwxSimple * 8
+)
+
+// yescryptParams are the cost parameters encoded in a "$y$" setting.
+type yescryptParams struct {
+	flags uint32
+	n     uint64
+	r     uint32
+	p     uint32
+	t     uint32
+	g     uint32
+	nrom  uint64
+}
+
+// yescryptCrypt computes the yescrypt hash of the plaintext using the parameters and salt of the
+// setting, following the reference implementation by Solar Designer.
+func yescryptCrypt(setting, plaintext string) (string, error) {
+	params, rest, err := decodeYescryptParams(strings.TrimPrefix(setting, "$y$"))
+	if err != nil {
+		return "", err
+	}
+	if params.flags != yescryptDefaults || params.g != 0 || params.nrom != 0 {
+		return "", ErrUnsupportedHash
+	}
+	saltText, _, _ := strings.Cut(rest, "$")
+	salt, err := yescryptDecode(saltText)
+	if err != nil {
+		return "", err
+	}
+	if 128*uint64(params.r)*params.n > yescryptMaxMemory || uint64(params.r)*uint64(params.p) > yescryptMaxMemory/128 {
+		return "", fmt.Errorf("Password hash asked for more than %d bytes of memory", yescryptMaxMemory)
+	}
+
+	passwd := []byte(plaintext)
+	if params.p >= 1 && params.n/uint64(params.p) >= 0x100 && params.n/uint64(params.p)*uint64(params.r) >= 0x20000 {
+		passwd = yescryptKDF(passwd, salt, params.flags|yescryptPrehash, params.n>>6, params.r, params.p, 0)
+	}
+	hashed := yescryptKDF(passwd, salt, params.flags, params.n, params.r, params.p, params.t)
+	prefix := setting[:len(setting)-len(rest)]
+	return prefix + saltText + "$" + yescryptEncode(hashed), nil
+}
+
+// decodeYescryptParams decodes the flavour and costs at the start of a "$y$" setting, returning
+// the rest of the setting after the '$' that ends them.
+func decodeYescryptParams(setting string) (yescryptParams, string, error) {
+	params := yescryptParams{p: 1}
+	bad := fmt.Errorf("Password hash had badly formatted yescrypt parameters")
+	flavor, setting, ok := decodeYescryptUint32(setting, 0)
+	if !ok {
+		return params, "", bad
+	}
+	if flavor < yescryptRW {
+		params.flags = flavor
+	} else if flavor <= yescryptRW+(0x3fc>>2) {
+		params.flags = yescryptRW + (flavor-yescryptRW)<<2
+	} else {
+		return params, "", bad
+	}
+	nLog2, setting, ok := decodeYescryptUint32(setting, 1)
+	if !ok || nLog2 > 63 {
+		return params, "", bad
+	}
+	params.n = 1 << nLog2
+	if params.r, setting, ok = decodeYescryptUint32(setting, 1); !ok {
+		return params, "", bad
+	}
+	if !strings.HasPrefix(setting, "$") {
+		var have uint32
+		if have, setting, ok = decodeYescryptUint32(setting, 1); !ok {
+			return params, "", bad
+		}
+		if have&1 != 0 {
+			if params.p, setting, ok = decodeYescryptUint32(setting, 2); !ok {
+				return params, "", bad
+			}
+		}
+		if have&2 != 0 {
+			if params.t, setting, ok = decodeYescryptUint32(setting, 1); !ok {
+				return params, "", bad
+			}
+		}
+		if have&4 != 0 {
+			if params.g, setting, ok = decodeYescryptUint32(setting, 1); !ok {
+				return params, "", bad
+			}
+		}
+		if have&8 != 0 {
+			var nromLog2 uint32
+			if nromLog2, setting, ok = decodeYescryptUint32(setting, 1); !ok || nromLog2 > 63 {
+				return params, "", bad
+			}
+			params.nrom = 1 << nromLog2
+		}
+	}
+	if !strings.HasPrefix(setting, "$") {
+		return params, "", bad
+	}
+	return params, setting[1:], nil
+}
+
+// decodeYescryptUint32 decodes the variable length number at the start of the text, returning
+// the remaining text.
+func decodeYescryptUint32(text string, minimum uint32) (uint32, string, bool) {
+	start, end, chars, shift := uint32(0), uint32(47), 1, uint32(0)
+	if len(text) == 0 {
+		return 0, text, false
+	}
+	c := strings.IndexByte(cryptAlphabet, text[0])
+	if c < 0 {
+		return 0, text, false
+	}
+	value := minimum
+	for uint32(c) > end {
+		value += (end + 1 - start) << shift
+		start = end + 1
+		end = start + (62-end)/2
+		chars++
+		shift += 6
+	}
+	value += (uint32(c) - start) << shift
+	for i := 1; i < chars; i++ {
+		if i >= len(text) {
+			return 0, text, false
+		}
+		c := strings.IndexByte(cryptAlphabet, text[i])
+		if c < 0 {
+			return 0, text, false
+		}
+		shift -= 6
+		value += uint32(c) << shift
+	}
+	return value, text[chars:], true
+}
+
+// yescryptDecode decodes the little endian base64 used by yescrypt for salts.
+func yescryptDecode(text string) ([]byte, error) {
+	result := make([]byte, 0, len(text)*6/8)
+	for i := 0; i < len(text); i += 4 {
+		var value, n uint32
+		for j := i; j < len(text) && j < i+4; j++ {
+			c := strings.IndexByte(cryptAlphabet, text[j])
+			if c < 0 {
+				return nil, fmt.Errorf("Password hash had badly formatted salt %s", text)
+			}
+			value |= uint32(c) << n
+			n += 6
+		}
+		if n < 12 {
+			return nil, fmt.Errorf("Password hash had badly formatted salt %s", text)
+		}
+		for ; n >= 8; n -= 8 {
+			result = append(result, byte(value))
+			value >>= 8
+		}
+		if value != 0 {
+			return nil, fmt.Errorf("Password hash had badly formatted salt %s", text)
+		}
+	}
+	if len(result) > yescryptMaxSalt {
+		return nil, fmt.Errorf("Password hash had a salt longer than %d bytes", yescryptMaxSalt)
+	}
+	return result, nil
+}
+
+// yescryptEncode encodes the bytes in the little endian base64 used by yescrypt.
+func yescryptEncode(data []byte) string {
+	result := make([]byte, 0, (len(data)*8+5)/6)
+	for i := 0; i < len(data); i += 3 {
+		var value, n uint32
+		for j := i; j < len(data) && j < i+3; j++ {
+			value |= uint32(data[j]) << n
+			n += 8
+		}
+		for ; n > 0; n -= min(n, 6) {
+			result = append(result, cryptAlphabet[value&0x3f])
+			value >>= 6
+		}
+	}
+	return string(result)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the message with the key.
+func hmacSHA256(key, message []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// pbkdf2SHA256 derives length bytes with a single iteration of PBKDF2-HMAC-SHA256.
+func pbkdf2SHA256(passwd, salt []byte, length int) []byte {
+	result := make([]byte, 0, length+sha256.Size)
+	mac := hmac.New(sha256.New, passwd)
+	for block := uint32(1); len(result) < length; block++ {
+		mac.Reset()
+		mac.Write(salt)
+		mac.Write(binary.BigEndian.AppendUint32(nil, block))
+		result = mac.Sum(result)
+	}
+	return result[:length]
+}
+
+// yescryptKDF derives the 32 byte hash of the password.
+func yescryptKDF(passwd, salt []byte, flags uint32, n uint64, r, p, t uint32) []byte {
+	key := "yescrypt-prehash"
+	if flags&yescryptPrehash == 0 {
+		key = key[:8]
+	}
+	passwd = hmacSHA256([]byte(key), passwd)
+	b := pbkdf2SHA256(passwd, salt, 128*int(r)*int(p))
+	// the first bytes of B become the password that is mixed into the final step
+	copy(passwd, b)
+	yescryptSmix(b, int(r), n, p, t, flags, passwd)
+	hashed := pbkdf2SHA256(passwd, b, yescryptHashLen)
+	if flags&yescryptPrehash != 0 {
+		return hashed
+	}
+	// the final steps match SCRAM, giving the StoredKey of the ClientKey
+	stored := sha256.Sum256(hmacSHA256(hashed, []byte("Client Key")))
+	return stored[:]
+}
+
+// pwxformCtx holds the S-boxes used by pwxform, as word offsets into s, and the write position.
+type pwxformCtx struct {
+	s          []uint32
+	s0, s1, s2 int
+	w          int
+}
+
+// transform applies pwxform to the 64 byte block.
+func (c *pwxformCtx) transform(b []uint32) {
+	s := c.s
+	for i := 0; i < pwxRounds; i++ {
+		for j := 0; j < pwxGather; j++ {
+			x := b[j*pwxSimple*2:]
+			p0 := s[c.s0+int(x[0]&sMask)/4:]
+			p1 := s[c.s1+int(x[1]&sMask)/4:]
+			for k := 0; k < pwxSimple; k++ {
+				v := uint64(x[2*k+1]) * uint64(x[2*k])
+				v += uint64(p0[2*k+1])<<32 + uint64(p0[2*k])
+				v ^= uint64(p1[2*k+1])<<32 + uint64(p1[2*k])
+				x[2*k], x[2*k+1] = uint32(v), uint32(v>>32)
+			}
+			if i != 0 && i != pwxRounds-1 {
+				for k := 0; k < pwxSimple; k++ {
+					s[c.s2+2*c.w], s[c.s2+2*c.w+1] = x[2*k], x[2*k+1]
+					c.w++
+				}
+			}
+		}
+	}
+	c.s0, c.s1, c.s2 = c.s2, c.s0, c.s1
+	c.w &= (1<<sWidth)*pwxSimple - 1
+}
+
+// salsa20 applies the given number of Salsa20 rounds to the block, which is held in the
+// shuffled word order of the reference implementation.
+func salsa20(b []uint32, rounds int) {
+	var x [16]uint32
+	for i := 0; i < 16; i++ {
+		x[i*5%16] = b[i]
+	}
+	for i := 0; i < rounds; i += 2 {
+		x[4] ^= bits.RotateLeft32(x[0]+x[12], 7)
+		x[8] ^= bits.RotateLeft32(x[4]+x[0], 9)
+		x[12] ^= bits.RotateLeft32(x[8]+x[4], 13)
+		x[0] ^= bits.RotateLeft32(x[12]+x[8], 18)
+		x[9] ^= bits.RotateLeft32(x[5]+x[1], 7)
+		x[13] ^= bits.RotateLeft32(x[9]+x[5], 9)
+		x[1] ^= bits.RotateLeft32(x[13]+x[9], 13)
+		x[5] ^= bits.RotateLeft32(x[1]+x[13], 18)
+		x[14] ^= bits.RotateLeft32(x[10]+x[6], 7)
+		x[2] ^= bits.RotateLeft32(x[14]+x[10], 9)
+		x[6] ^= bits.RotateLeft32(x[2]+x[14], 13)
+		x[10] ^= bits.RotateLeft32(x[6]+x[2], 18)
+		x[3] ^= bits.RotateLeft32(x[15]+x[11], 7)
+		x[7] ^= bits.RotateLeft32(x[3]+x[15], 9)
+		x[11] ^= bits.RotateLeft32(x[7]+x[3], 13)
+		x[15] ^= bits.RotateLeft32(x[11]+x[7], 18)
+
+		x[1] ^= bits.RotateLeft32(x[0]+x[3], 7)
+		x[2] ^= bits.RotateLeft32(x[1]+x[0], 9)
+		x[3] ^= bits.RotateLeft32(x[2]+x[1], 13)
+		x[0] ^= bits.RotateLeft32(x[3]+x[2], 18)
+		x[6] ^= bits.RotateLeft32(x[5]+x[4], 7)
+		x[7] ^= bits.RotateLeft32(x[6]+x[5], 9)
+		x[4] ^= bits.RotateLeft32(x[7]+x[6], 13)
+		x[5] ^= bits.RotateLeft32(x[4]+x[7], 18)
+		x[11] ^= bits.RotateLeft32(x[10]+x[9], 7)
+		x[8] ^= bits.RotateLeft32(x[11]+x[10], 9)
+		x[9] ^= bits.RotateLeft32(x[8]+x[11], 13)
+		x[10] ^= bits.RotateLeft32(x[9]+x[8], 18)
+		x[12] ^= bits.RotateLeft32(x[15]+x[14], 7)
+		x[13] ^= bits.RotateLeft32(x[12]+x[15], 9)
+		x[14] ^= bits.RotateLeft32(x[13]+x[12], 13)
+		x[15] ^= bits.RotateLeft32(x[14]+x[13], 18)
+	}
+	for i := 0; i < 16; i++ {
+		b[i] += x[i*5%16]
+	}
+}
+
+// blockmixSalsa8 is the scrypt BlockMix using Salsa20/8, with y as scratch space.
+func blockmixSalsa8(b, y []uint32, r int) {
+	var x [16]uint32
+	copy(x[:], b[(2*r-1)*16:])
+	for i := 0; i < 2*r; i++ {
+		for k := range x {
+			x[k] ^= b[i*16+k]
+		}
+		salsa20(x[:], 8)
+		copy(y[i*16:], x[:])
+	}
+	for i := 0; i < r; i++ {
+		copy(b[i*16:(i+1)*16], y[i*2*16:])
+		copy(b[(i+r)*16:(i+r+1)*16], y[(i*2+1)*16:])
+	}
+}
+
+// blockmixPwxform is the yescrypt BlockMix using pwxform.
+func blockmixPwxform(b []uint32, ctx *pwxformCtx, r int) {
+	var x [pwxWords]uint32
+	r1 := 128 * r / pwxBytes
+	copy(x[:], b[(r1-1)*pwxWords:])
+	for i := 0; i < r1; i++ {
+		if r1 > 1 {
+			for k := range x {
+				x[k] ^= b[i*pwxWords+k]
+			}
+		}
+		ctx.transform(x[:])
+		copy(b[i*pwxWords:], x[:])
+	}
+	i := (r1 - 1) * pwxBytes / 64
+	salsa20(b[i*16:], 2)
+	for i++; i < 2*r; i++ {
+		for k := 0; k < 16; k++ {
+			b[i*16+k] ^= b[(i-1)*16+k]
+		}
+		salsa20(b[i*16:], 2)
+	}
+}
+
+// integerify returns the 64 bit number at the start of the last 64 byte block.
+func integerify(x []uint32, r int) uint64 {
+	last := x[(2*r-1)*16:]
+	return uint64(last[13])<<32 + uint64(last[0])
+}
+
+// p2floor returns the largest power of two not greater than x.
+func p2floor(x uint64) uint64 {
+	for y := x & (x - 1); y != 0; y = x & (x - 1) {
+		x = y
+	}
+	return x
+}
+
+// wrap maps x into the range of blocks already written when writing block i.
+func wrap(x, i uint64) uint64 {
+	n := p2floor(i)
+	return (x & (n - 1)) + (i - n)
+}
+
+// loadBlocks and storeBlocks convert between bytes and words in the shuffled word order.
+func loadBlocks(x []uint32, b []byte, r int) {
+	for k := 0; k < 2*r; k++ {
+		for i := 0; i < 16; i++ {
+			x[k*16+i] = binary.LittleEndian.Uint32(b[(k*16+i*5%16)*4:])
+		}
+	}
+}
+
+func storeBlocks(b []byte, x []uint32, r int) {
+	for k := 0; k < 2*r; k++ {
+		for i := 0; i < 16; i++ {
+			binary.LittleEndian.PutUint32(b[(k*16+i*5%16)*4:], x[k*16+i])
+		}
+	}
+}
+
+// blockmix applies pwxform when there is a context and Salsa20/8 otherwise.
+func blockmix(x, y []uint32, r int, ctx *pwxformCtx) {
+	if ctx != nil {
+		blockmixPwxform(x, ctx, r)
+	} else {
+		blockmixSalsa8(x, y, r)
+	}
+}
+
+// yescryptSmix1 fills v with n blocks derived from b, leaving the last in b.
+func yescryptSmix1(b []byte, r int, n uint64, flags uint32, v, xy []uint32, ctx *pwxformCtx) {
+	s := 32 * r
+	x, y := xy[:s], xy[s:]
+	loadBlocks(x, b, r)
+	for i := uint64(0); i < n; i++ {
+		copy(v[i*uint64(s):], x)
+		if flags&yescryptRW != 0 && i > 1 {
+			j := wrap(integerify(x, r), i)
+			for k, w := range v[j*uint64(s) : (j+1)*uint64(s)] {
+				x[k] ^= w
+			}
+		}
+		blockmix(x, y, r, ctx)
+	}
+	storeBlocks(b, x, r)
+}
+
+// yescryptSmix2 mixes nloop pseudo-randomly chosen blocks of v into b, writing them back to v
+// in read-write mode.
+func yescryptSmix2(b []byte, r int, n, nloop uint64, flags uint32, v, xy []uint32, ctx *pwxformCtx) {
+	s := 32 * r
+	x, y := xy[:s], xy[s:]
+	loadBlocks(x, b, r)
+	for i := uint64(0); i < nloop; i++ {
+		j := integerify(x, r) & (n - 1)
+		vj := v[j*uint64(s) : (j+1)*uint64(s)]
+		for k, w := range vj {
+			x[k] ^= w
+		}
+		if flags&yescryptRW != 0 {
+			copy(vj, x)
+		}
+		blockmix(x, y, r, ctx)
+	}
+	storeBlocks(b, x, r)
+}
+
+// yescryptSmix runs the memory-hard part of yescrypt over the p blocks of b, updating the
+// password with the S-box state of the first block.
+func yescryptSmix(b []byte, r int, n uint64, p, t, flags uint32, passwd []byte) {
+	s := 32 * r
+	v := make([]uint32, uint64(s)*n)
+	xy := make([]uint32, 2*s)
+	nchunk := n / uint64(p)
+	nloopAll := nchunk
+	if t <= 1 {
+		if t != 0 {
+			nloopAll *= 2
+		}
+		nloopAll = (nloopAll + 2) / 3
+	} else {
+		nloopAll *= uint64(t) - 1
+	}
+	nloopRW := nloopAll / uint64(p)
+	nchunk &^= 1
+	nloopAll = (nloopAll + 1) &^ 1
+	nloopRW = (nloopRW + 1) &^ 1
+
+	ctxs := make([]*pwxformCtx, p)
+	for i := uint64(0); i < uint64(p); i++ {
+		vchunk := i * nchunk
+		np := nchunk
+		if i == uint64(p)-1 {
+			np = n - vchunk
+		}
+		bp := b[128*r*int(i) : 128*r*int(i+1)]
+		vp := v[vchunk*uint64(s):]
+
+		sbox := make([]uint32, sBytes/4)
+		yescryptSmix1(bp, 1, sBytes/128, 0, sbox, xy, nil)
+		ctx := &pwxformCtx{s: sbox, s2: 0, s1: sBytes / 3 / 4, s0: 2 * sBytes / 3 / 4}
+		ctxs[i] = ctx
+		if i == 0 {
+			copy(passwd, hmacSHA256(bp[128*r-64:], passwd))
+		}
+		yescryptSmix1(bp, r, np, flags, vp, xy, ctx)
+		yescryptSmix2(bp, r, p2floor(np), nloopRW, flags, vp, xy, ctx)
+	}
+	for i := 0; i < int(p); i++ {
+		bp := b[128*r*i : 128*r*(i+1)]
+		yescryptSmix2(bp, r, n, nloopAll-nloopRW, flags&^yescryptRW, v, xy, ctxs[i])
+	}
+}